@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	"github.com/spf13/cobra"
+)
+
+var gamepadHoldFor time.Duration
+
+var gamepadCmd = &cobra.Command{
+	Use:   "gamepad",
+	Short: "Control the virtual gamepad",
+	Long: `Press buttons and move sticks, triggers and the D-pad of the daemon's
+virtual gamepad. The gamepad is created on the first gamepad command.
+
+Buttons: south (a), east (b), north, west, tl (lb), tr (rb), select (back),
+         start, mode (guide), thumbl, thumbr
+Axes:    left_x, left_y, right_x, right_y  (-32768 to 32767)
+         left_trigger, right_trigger       (0 to 255)
+         dpad_x, dpad_y                    (-1 to 1)`,
+}
+
+var gamepadTapCmd = &cobra.Command{
+	Use:   "tap BUTTON",
+	Short: "Press and release a gamepad button",
+	Long: `Press and release a gamepad button.
+
+Example:
+  uinput-client gamepad tap south`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGamepadTap,
+}
+
+var gamepadHoldCmd = &cobra.Command{
+	Use:   "hold BUTTON",
+	Short: "Hold a gamepad button until interrupted or a duration elapses",
+	Long: `Hold a gamepad button until Ctrl+C, SIGTERM, or the --for duration
+elapses. The daemon releases the button when this command exits.
+
+Example:
+  uinput-client gamepad hold start --for 3s`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGamepadHold,
+}
+
+var gamepadAxisCmd = &cobra.Command{
+	Use:   "axis AXIS=VALUE...",
+	Short: "Set stick, trigger or D-pad values while the command runs",
+	Long: `Set one or more axes in a single report and keep them there until
+Ctrl+C, SIGTERM, or the --for duration elapses. The daemon returns the
+axes to rest when this command exits.
+
+Examples:
+  uinput-client gamepad axis left_x=32767 --for 2s   # steer right
+  uinput-client gamepad axis right_trigger=255 left_y=-32768
+  uinput-client gamepad axis dpad_y=-1 --for 100ms    # D-pad up`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runGamepadAxis,
+}
+
+func init() {
+	rootCmd.AddCommand(gamepadCmd)
+	gamepadCmd.AddCommand(gamepadTapCmd)
+	gamepadCmd.AddCommand(gamepadHoldCmd)
+	gamepadCmd.AddCommand(gamepadAxisCmd)
+
+	gamepadHoldCmd.Flags().DurationVar(&gamepadHoldFor, "for", 0, "release after this duration (0=until interrupted)")
+	gamepadAxisCmd.Flags().DurationVar(&gamepadHoldFor, "for", 0, "reset after this duration (0=until interrupted)")
+}
+
+func runGamepadTap(cmd *cobra.Command, args []string) error {
+	if _, ok := uinput.LookupGamepadButton(args[0]); !ok {
+		return fmt.Errorf("unknown gamepad button: %s", args[0])
+	}
+
+	return sendCommand(protocol.CommandType_GamepadButton, protocol.GamepadButtonPayload{
+		Button: args[0],
+	})
+}
+
+func runGamepadHold(cmd *cobra.Command, args []string) error {
+	if _, ok := uinput.LookupGamepadButton(args[0]); !ok {
+		return fmt.Errorf("unknown gamepad button: %s", args[0])
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.GamepadPress(ctx, args[0]); err != nil {
+		return err
+	}
+
+	waitHold(ctx, gamepadHoldFor)
+
+	// Release with a fresh context: ctx may already be cancelled
+	return c.GamepadRelease(context.Background(), args[0])
+}
+
+func runGamepadAxis(cmd *cobra.Command, args []string) error {
+	axes := make(map[string]int32, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid axis %q (expected AXIS=VALUE)", arg)
+		}
+		if _, ok := uinput.LookupGamepadAxis(name); !ok {
+			return fmt.Errorf("unknown gamepad axis: %s", name)
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid value for axis %s: %q", name, value)
+		}
+		axes[name] = int32(n)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close() // The daemon returns the axes to rest on disconnect
+
+	if err := c.GamepadAxes(ctx, axes); err != nil {
+		return err
+	}
+
+	waitHold(ctx, gamepadHoldFor)
+	return nil
+}
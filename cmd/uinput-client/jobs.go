@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/styles"
+	"github.com/bnema/uinputd-go/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var cancelAll bool
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List the typing jobs running on the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runJobs,
+}
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel [ID]",
+	Short: "Abort running typing jobs",
+	Long: `Abort a typing job (type, stream or stream session) of any client, or
+every job with --all. Typing stops before the next character and the keys
+held by the job's client are released.
+
+Examples:
+  uinput-client jobs
+  uinput-client cancel 3
+  uinput-client cancel --all      # e.g. bound to a panic-button hotkey`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCancel,
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause ID",
+	Short: "Pause a running typing job",
+	Long: `Pause a typing job of any client before its next character, e.g. to
+switch windows. The job waits until it is resumed; a paused stream session
+even outlives its client. The daemon cancels jobs paused for longer than
+jobs.pause_timeout_ms.
+
+Examples:
+  uinput-client pause 3
+  uinput-client resume 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPause,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume ID",
+	Short: "Resume a paused typing job where it left off",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPause,
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(cancelCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+
+	cancelCmd.Flags().BoolVar(&cancelAll, "all", false, "cancel every running job")
+}
+
+func runJobs(cmd *cobra.Command, args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	jobs, err := c.Jobs(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println(styles.Dim("no running jobs"))
+		return nil
+	}
+	for _, job := range jobs {
+		printJob(job)
+	}
+	return nil
+}
+
+// printJob prints a job's ID, type, state, progress and client.
+func printJob(job client.Job) {
+	fmt.Printf("  %-4s %-12s %-8s %s %s\n", job.ID, job.Type, job.State, jobProgress(job),
+		styles.Dim("started "+time.Since(job.StartedAt).Round(time.Second).String()+" ago by "+peerName(job.Peer)))
+}
+
+// peerName formats a client's executable, PID and UID.
+func peerName(p *client.Peer) string {
+	if p == nil {
+		return "unknown client"
+	}
+	exe := p.Exe
+	if exe == "" && len(p.Cmdline) > 0 {
+		exe = p.Cmdline[0]
+	}
+	if exe == "" {
+		exe = "?"
+	}
+	return fmt.Sprintf("%s (pid %d, uid %d)", exe, p.PID, p.UID)
+}
+
+// jobProgress formats the characters a job typed so far.
+func jobProgress(job client.Job) string {
+	return fmt.Sprintf("%d/%d (%.0f%%)", job.Offset, job.Total, job.Percent)
+}
+
+func runPause(cmd *cobra.Command, args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	var job *client.Job
+	if cmd.Name() == "resume" {
+		job, err = c.Resume(ctx, args[0])
+	} else {
+		job, err = c.Pause(ctx, args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(styles.Success(fmt.Sprintf("job %s %s at %s", job.ID, job.State, jobProgress(*job))))
+	return nil
+}
+
+func runCancel(cmd *cobra.Command, args []string) error {
+	if cancelAll == (len(args) == 1) {
+		return fmt.Errorf("give either a job ID or --all")
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	if !cancelAll {
+		if err := c.Cancel(ctx, args[0]); err != nil {
+			return err
+		}
+		fmt.Println(styles.Success(fmt.Sprintf("job %s cancelled", args[0])))
+		return nil
+	}
+
+	jobs, err := c.CancelAll(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(styles.Success(fmt.Sprintf("%d jobs cancelled", len(jobs))))
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var (
+	layoutsDir     string
+	xkbDir         string
+	coverageSet    string
+	coverageFile   string
+	coverageMin    float64
+	coverageDetail bool
+)
+
+var layoutCmd = &cobra.Command{
+	Use:     "layouts",
+	Aliases: []string{"layout"},
+	Short:   "Inspect and check keyboard layouts offline",
+	Long: `Inspect, validate and measure keyboard layouts without a running daemon.
+
+Layouts are the built-in ones, the layout files (*.yaml, *.yml, *.json) in
+--layouts-dir (the daemon's layouts_dir) and XKB layouts named
+"xkb:<layout>(<variant>)".`,
+}
+
+var layoutListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available layouts",
+	Args:  cobra.NoArgs,
+	RunE:  runLayoutList,
+}
+
+var layoutDumpCmd = &cobra.Command{
+	Use:   "dump NAME",
+	Short: "Print every character a layout can type with its keystrokes",
+	Long: `Print every character of the Basic Multilingual Plane that a layout can
+type, one per line with its code point and keystrokes.
+
+Example:
+  uinput-client layouts dump fr | grep U+00`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLayoutDump,
+}
+
+var layoutCoverageCmd = &cobra.Command{
+	Use:   "coverage NAME...",
+	Short: "Measure which characters of a charset or text file layouts can type",
+	Long: `Measure the share of a charset, or of the characters of a text file, that
+each layout can type. Exits with an error if a layout is below --min.
+
+Charsets: ` + charsetNames() + `
+
+Examples:
+  uinput-client layouts coverage fr de --charset latin1
+  uinput-client layouts coverage acme --file corpus.txt --min 99.5`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLayoutCoverage,
+}
+
+var layoutValidateCmd = &cobra.Command{
+	Use:   "validate FILE...",
+	Short: "Check layout files without a running daemon",
+	Long: `Parse layout files offline and report every invalid entry with its line.
+
+Example:
+  uinput-client layouts validate /etc/uinputd/layouts/acme.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLayoutValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(layoutCmd)
+	layoutCmd.AddCommand(layoutValidateCmd)
+	layoutCmd.AddCommand(layoutListCmd)
+	layoutCmd.AddCommand(layoutDumpCmd)
+	layoutCmd.AddCommand(layoutCoverageCmd)
+
+	layoutCmd.PersistentFlags().StringVar(&layoutsDir, "layouts-dir", "/etc/uinputd/layouts", "directory of layout files")
+	layoutCmd.PersistentFlags().StringVar(&xkbDir, "xkb-dir", layouts.DefaultXKBDir, "directory of XKB symbols files")
+
+	layoutCoverageCmd.Flags().StringVar(&coverageSet, "charset", "latin1", "charset to measure against")
+	layoutCoverageCmd.Flags().StringVar(&coverageFile, "file", "", "measure against the characters of a text file instead")
+	layoutCoverageCmd.Flags().Float64Var(&coverageMin, "min", 0, "minimum coverage in percent")
+	layoutCoverageCmd.Flags().BoolVar(&coverageDetail, "missing", false, "list the missing characters")
+	layoutCoverageCmd.MarkFlagsMutuallyExclusive("charset", "file")
+}
+
+// newLocalRegistry creates a registry like the daemon's, from the
+// built-in layouts and the layout files in --layouts-dir.
+func newLocalRegistry() *layouts.Registry {
+	registry := layouts.NewRegistry()
+	registry.SetXKBDir(xkbDir)
+
+	if _, err := registry.LoadDir(layoutsDir); err != nil {
+		for _, e := range splitErrors(err) {
+			fmt.Fprintln(os.Stderr, styles.Warning(e.Error()))
+		}
+	}
+	return registry
+}
+
+func runLayoutList(cmd *cobra.Command, args []string) error {
+	registry := newLocalRegistry()
+	names := registry.Available()
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Println(styles.ListItem(name))
+	}
+	fmt.Println(styles.Dim(fmt.Sprintf("  XKB layouts from %s: xkb:<layout>(<variant>)", xkbDir)))
+	return nil
+}
+
+func runLayoutDump(cmd *cobra.Command, args []string) error {
+	layout, err := newLocalRegistry().Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, m := range layouts.Dump(context.Background(), layout) {
+		keys := make([]string, len(m.Keys))
+		for i, key := range m.Keys {
+			keys[i] = key.String()
+		}
+		fmt.Printf("%s  %-6q %s\n", styles.Dim(fmt.Sprintf("U+%04X", m.Char)), m.Char, strings.Join(keys, " "))
+	}
+	return nil
+}
+
+func runLayoutCoverage(cmd *cobra.Command, args []string) error {
+	chars, source, err := coverageChars()
+	if err != nil {
+		return err
+	}
+
+	registry := newLocalRegistry()
+
+	below := 0
+	for _, name := range args {
+		layout, err := registry.Get(name)
+		if err != nil {
+			return err
+		}
+
+		report := layouts.Coverage(context.Background(), layout, chars)
+		msg := fmt.Sprintf("%s covers %d/%d characters of %s (%.1f%%)", name, report.Covered, report.Total, source, report.Percent())
+		switch {
+		case report.Percent() < coverageMin:
+			below++
+			fmt.Println(styles.Error(msg))
+		case len(report.Missing) > 0:
+			fmt.Println(styles.Warning(msg))
+		default:
+			fmt.Println(styles.Success(msg))
+		}
+
+		if coverageDetail && len(report.Missing) > 0 {
+			missing := make([]string, len(report.Missing))
+			for i, char := range report.Missing {
+				missing[i] = fmt.Sprintf("%q U+%04X", char, char)
+			}
+			fmt.Println(styles.Dim("  missing: " + strings.Join(missing, ", ")))
+		}
+	}
+
+	if below > 0 {
+		return fmt.Errorf("%d of %d layouts below %.1f%% coverage", below, len(args), coverageMin)
+	}
+	return nil
+}
+
+// coverageChars returns the characters selected by --file or --charset
+// and a description of them.
+func coverageChars() ([]rune, string, error) {
+	if coverageFile != "" {
+		data, err := os.ReadFile(coverageFile)
+		if err != nil {
+			return nil, "", err
+		}
+		return layouts.TextChars(string(data)), coverageFile, nil
+	}
+
+	cs, err := layouts.LookupCharset(coverageSet)
+	if err != nil {
+		return nil, "", err
+	}
+	return cs.Chars(), cs.Name, nil
+}
+
+// charsetNames lists the known charsets for help texts.
+func charsetNames() string {
+	names := make([]string, len(layouts.Charsets))
+	for i, cs := range layouts.Charsets {
+		names[i] = cs.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func runLayoutValidate(cmd *cobra.Command, args []string) error {
+	invalid := 0
+	for _, path := range args {
+		layout, err := layouts.LoadLayoutFile(path)
+		if err != nil {
+			invalid++
+			for _, e := range splitErrors(err) {
+				fmt.Println(styles.Error(e.Error()))
+			}
+			continue
+		}
+		fmt.Println(styles.Success(fmt.Sprintf("%s: layout %q is valid", path, layout.Name())))
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d layout files are invalid", invalid, len(args))
+	}
+	return nil
+}
+
+// splitErrors returns the errors joined in err, or err itself.
+func splitErrors(err error) []error {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -2,19 +2,26 @@ package main
 
 import (
 	"bufio"
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bnema/uinputd-go/internal/doctor"
 	"github.com/bnema/uinputd-go/internal/installer"
 	"github.com/bnema/uinputd-go/internal/protocol"
 	"github.com/bnema/uinputd-go/internal/styles"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	"github.com/bnema/uinputd-go/pkg/client"
 	"github.com/spf13/cobra"
 )
 
@@ -27,15 +34,31 @@ var embeddedConfig []byte
 //go:embed embedded/uinputd.service
 var embeddedSystemd []byte
 
+//go:embed embedded/uinputd.socket
+var embeddedSocket []byte
+
 var (
 	version   = "dev"
 	commit    = "unknown"
 	buildTime = "unknown"
 
 	socketPath  string
+	deviceName  string
+	priority    int
 	layout      string
+	fallback    string
+	strict      bool
 	charDelayMs int
 	wordDelayMs int
+	streamMode  string
+	holdFor     time.Duration
+)
+
+// Stream modes for the stream command
+const (
+	streamModeLine  = "line"  // Forward each line as soon as it is read
+	streamModeByte  = "byte"  // Forward bytes as soon as they are read
+	streamModeBatch = "batch" // Read all of stdin, then send it at once
 )
 
 func main() {
@@ -59,13 +82,17 @@ Examples:
   echo "Hello from stdin" | uinput-client stream
   cat document.txt | uinput-client stream --layout fr
 
+  # Type LLM tokens as they arrive, newlines included
+  llm_tool | uinput-client stream --mode byte
+
   # SimulStreaming integration (filter timestamps, then stream)
   simulstreaming_output | awk '{$1=$2=""; print substr($0,3)}' | uinput-client stream --layout fr
 
   # Custom delays
   echo "Slow typing" | uinput-client stream --char-delay 100 --word-delay 300
 
-  uinput-client key 28  # Send Enter key (keycode 28)
+  uinput-client key 28              # Send Enter key (keycode 28)
+  uinput-client key ctrl+shift+t    # Send a key chord by name
 
   # Installation
   uinput-client install daemon         # Install daemon binary
@@ -76,6 +103,8 @@ Examples:
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&socketPath, "socket", "s", "/run/uinputd.sock", "socket path")
 	rootCmd.PersistentFlags().StringVarP(&layout, "layout", "l", "", "keyboard layout (us, fr, de, es, uk, it)")
+	rootCmd.PersistentFlags().StringVarP(&deviceName, "device", "d", "", "named device from the daemon's devices.extra config")
+	rootCmd.PersistentFlags().IntVar(&priority, "priority", 0, "queue priority; higher runs before commands already waiting")
 }
 
 var typeCmd = &cobra.Command{
@@ -88,15 +117,40 @@ var typeCmd = &cobra.Command{
 var streamCmd = &cobra.Command{
 	Use:   "stream",
 	Short: "Stream text from stdin with real-time delays",
-	Args:  cobra.MaximumNArgs(0),
-	RunE:  runStream,
+	Long: `Stream text from stdin with real-time delays.
+
+Modes:
+  line   type each line as soon as it is read, joining lines with a space (default)
+  byte   type bytes as soon as they are read, newlines included
+  batch  read all of stdin, then send it as a single command`,
+	Args: cobra.MaximumNArgs(0),
+	RunE: runStream,
 }
 
 var keyCmd = &cobra.Command{
-	Use:   "key KEYCODE",
-	Short: "Send a single key press",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runKey,
+	Use:   "key KEYCODE|CHORD",
+	Short: "Send a key press or key chord",
+	Long: `Send a single key press by numeric keycode, or a key chord by name.
+
+Chords join modifiers and keys with '+', e.g. ctrl+shift+t, super+enter,
+ctrl+alt+delete. Modifiers: shift, ctrl, alt, altgr, super (and their
+l/r variants). Keys: letters, digits, enter, esc, tab, space, backspace,
+delete, arrows (up, down, left, right), home, end, pageup, pagedown, f1-f24...`,
+	Args: cobra.ExactArgs(1),
+	RunE: runKey,
+}
+
+var holdCmd = &cobra.Command{
+	Use:   "hold CHORD",
+	Short: "Hold keys down until interrupted or a duration elapses",
+	Long: `Hold keys down until Ctrl+C, SIGTERM, or the --for duration elapses.
+The daemon releases the keys when this command exits, even if it crashes.
+
+Examples:
+  uinput-client hold shift --for 2s
+  uinput-client hold f13             # push-to-talk, release with Ctrl+C`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHold,
 }
 
 var pingCmd = &cobra.Command{
@@ -122,7 +176,8 @@ Requires root privileges.`,
 var installSystemdCmd = &cobra.Command{
 	Use:   "systemd-service",
 	Short: "Install systemd service unit",
-	Long: `Install the systemd service unit file for uinputd.
+	Long: `Install the systemd service and socket units for uinputd.
+Enabling uinputd.socket starts the daemon on the first connection.
 The daemon must be installed first. Requires root privileges.`,
 	RunE: runInstallSystemd,
 }
@@ -148,6 +203,7 @@ func init() {
 	rootCmd.AddCommand(typeCmd)
 	rootCmd.AddCommand(streamCmd)
 	rootCmd.AddCommand(keyCmd)
+	rootCmd.AddCommand(holdCmd)
 	rootCmd.AddCommand(pingCmd)
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(versionCmd)
@@ -156,23 +212,47 @@ func init() {
 	installCmd.AddCommand(installDaemonCmd)
 	installCmd.AddCommand(installSystemdCmd)
 
+	// Typing command flags
+	for _, c := range []*cobra.Command{typeCmd, streamCmd} {
+		c.Flags().StringVar(&fallback, "fallback", "", "typing of characters missing from the layout (none, unicode_hex; empty=use config default)")
+		c.Flags().BoolVar(&strict, "strict", false, "type nothing if the layout cannot type every character")
+	}
+
 	// Stream command flags
 	streamCmd.Flags().IntVar(&charDelayMs, "char-delay", 0, "delay between characters in ms (0=use config default)")
 	streamCmd.Flags().IntVar(&wordDelayMs, "word-delay", 0, "delay between words in ms (0=use config default)")
+	streamCmd.Flags().StringVar(&streamMode, "mode", streamModeLine, "stream mode (line, byte, batch)")
+
+	// Hold command flags
+	holdCmd.Flags().DurationVar(&holdFor, "for", 0, "release after this duration (0=until interrupted)")
 }
 
 func runType(cmd *cobra.Command, args []string) error {
 	text := args[0]
 
 	payload := protocol.TypePayload{
-		Text:   text,
-		Layout: layout,
+		Text:     text,
+		Layout:   layout,
+		Fallback: fallback,
+		Strict:   strict,
 	}
 
 	return sendCommand(protocol.CommandType_Type, payload)
 }
 
 func runStream(cmd *cobra.Command, args []string) error {
+	switch streamMode {
+	case streamModeBatch:
+		return runStreamBatch()
+	case streamModeLine, streamModeByte:
+		return runStreamSession()
+	default:
+		return fmt.Errorf("unknown stream mode: %s", streamMode)
+	}
+}
+
+// runStreamBatch reads all of stdin and sends it as a single stream command.
+func runStreamBatch() error {
 	// Read from stdin and accumulate lines into continuous text
 	var buffer strings.Builder
 	scanner := bufio.NewScanner(os.Stdin)
@@ -200,6 +280,8 @@ func runStream(cmd *cobra.Command, args []string) error {
 	payload := protocol.StreamPayload{
 		Text:      text,
 		Layout:    layout,
+		Fallback:  fallback,
+		Strict:    strict,
 		DelayMs:   wordDelayMs,
 		CharDelay: charDelayMs,
 	}
@@ -207,17 +289,131 @@ func runStream(cmd *cobra.Command, args []string) error {
 	return sendCommand(protocol.CommandType_Stream, payload)
 }
 
+// runStreamSession forwards stdin to a streaming session as it is read.
+// The session is opened lazily so that empty input sends nothing.
+func runStreamSession() error {
+	ctx := context.Background()
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var w *client.StreamWriter
+	write := func(text string) error {
+		if w == nil {
+			w, err = c.OpenStream(ctx, &client.StreamOptions{
+				Layout:    layout,
+				Fallback:  fallback,
+				DelayMs:   wordDelayMs,
+				CharDelay: charDelayMs,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, text)
+		return err
+	}
+
+	if streamMode == streamModeByte {
+		buf := make([]byte, 4096)
+		for {
+			n, rerr := os.Stdin.Read(buf)
+			if n > 0 {
+				if err := write(string(buf[:n])); err != nil {
+					return err
+				}
+			}
+			if rerr == io.EOF {
+				break
+			}
+			if rerr != nil {
+				return fmt.Errorf("reading stdin: %w", rerr)
+			}
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		sent := false
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			// Join lines with a space, as batch mode does
+			if sent {
+				line = " " + line
+			}
+			if err := write(line); err != nil {
+				return err
+			}
+			sent = true
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	if w == nil {
+		return nil // Empty input, nothing to do
+	}
+	return w.Close()
+}
+
 func runKey(cmd *cobra.Command, args []string) error {
-	var keycode uint16
-	if _, err := fmt.Sscanf(args[0], "%d", &keycode); err != nil {
-		return fmt.Errorf("invalid keycode: %w", err)
+	// A plain number is a raw keycode, anything else is a chord
+	if keycode, err := strconv.ParseUint(args[0], 10, 16); err == nil {
+		return sendCommand(protocol.CommandType_Key, protocol.KeyPayload{
+			Keycode: uint16(keycode),
+		})
 	}
 
-	payload := protocol.KeyPayload{
-		Keycode: keycode,
+	// Validate locally for a friendlier error than the daemon's
+	if _, err := uinput.ParseChord(args[0]); err != nil {
+		return err
 	}
 
-	return sendCommand(protocol.CommandType_Key, payload)
+	return sendCommand(protocol.CommandType_Key, protocol.KeyPayload{
+		Chord: args[0],
+	})
+}
+
+func runHold(cmd *cobra.Command, args []string) error {
+	if _, err := uinput.ParseChord(args[0]); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.KeyDown(ctx, args[0]); err != nil {
+		return err
+	}
+
+	waitHold(ctx, holdFor)
+
+	// Release with a fresh context: ctx may already be cancelled
+	return c.KeyUp(context.Background(), args[0])
+}
+
+// waitHold blocks until ctx is cancelled or, if d > 0, d has elapsed.
+func waitHold(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		<-ctx.Done()
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
 }
 
 func runPing(cmd *cobra.Command, args []string) error {
@@ -230,6 +426,22 @@ func runPing(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// newClient creates a client targeting the --device, if any, with the
+// --priority.
+func newClient() (*client.Client, error) {
+	c, err := client.New(socketPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if deviceName != "" {
+		c = c.Device(deviceName)
+	}
+	if priority != 0 {
+		c = c.WithPriority(priority)
+	}
+	return c, nil
+}
+
 func sendCommand(cmdType protocol.CommandType, payload interface{}) error {
 	// Connect to daemon
 	conn, err := net.Dial("unix", socketPath)
@@ -246,8 +458,10 @@ func sendCommand(cmdType protocol.CommandType, payload interface{}) error {
 
 	// Create command
 	cmd := protocol.Command{
-		Type:    cmdType,
-		Payload: payloadBytes,
+		Device:   deviceName,
+		Priority: priority,
+		Type:     cmdType,
+		Payload:  payloadBytes,
 	}
 
 	// Send command
@@ -263,16 +477,43 @@ func sendCommand(cmdType protocol.CommandType, payload interface{}) error {
 
 	// Handle response
 	if !resp.Success {
+		if resp.Code == protocol.ErrorCode_UnsupportedChars && resp.Result != nil {
+			return fmt.Errorf("daemon error: %s: %s", resp.Error, joinChars(resp.Result.Dropped))
+		}
 		return fmt.Errorf("daemon error: %s", resp.Error)
 	}
 
 	if resp.Message != "" {
-		fmt.Println(styles.Success(resp.Message))
+		message := resp.Message
+		if resp.Result != nil {
+			message += fmt.Sprintf(" (layout: %s, %d characters in %dms)", resp.Layout, resp.Result.Typed, resp.Result.ElapsedMs)
+		} else if resp.Layout != "" {
+			message += fmt.Sprintf(" (layout: %s)", resp.Layout)
+		}
+		fmt.Println(styles.Success(message))
+	}
+
+	if resp.Result != nil {
+		if len(resp.Result.Fallback) > 0 {
+			fmt.Println(styles.Info(fmt.Sprintf("typed with fallback: %s", joinChars(resp.Result.Fallback))))
+		}
+		if len(resp.Result.Dropped) > 0 {
+			fmt.Println(styles.Warning(fmt.Sprintf("dropped: %s", joinChars(resp.Result.Dropped))))
+		}
 	}
 
 	return nil
 }
 
+// joinChars lists reported characters with their positions, e.g. `"→" (3), "é" (5)`.
+func joinChars(chars []protocol.CharReport) string {
+	parts := make([]string, len(chars))
+	for i, c := range chars {
+		parts[i] = fmt.Sprintf("%q (%d)", c.Char, c.Position)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // ensureRoot checks if running as root, and if not, re-executes with sudo
 func ensureRoot() error {
 	if os.Geteuid() == 0 {
@@ -341,18 +582,20 @@ func runInstallSystemd(cmd *cobra.Command, args []string) error {
 	fmt.Println(styles.Info("Installing systemd service..."))
 
 	// Use installer package for installation logic
-	if err := installer.InstallSystemdService(embeddedSystemd); err != nil {
+	if err := installer.InstallSystemdService(embeddedSystemd, embeddedSocket); err != nil {
 		return err
 	}
 
 	fmt.Println(styles.Success("Service installed: /etc/systemd/system/uinputd.service"))
+	fmt.Println(styles.Success("Socket installed:  /etc/systemd/system/uinputd.socket"))
 	fmt.Println(styles.Success("Systemd reloaded"))
 
 	fmt.Println(styles.Section("Systemd service installed!"))
 	fmt.Println(styles.Bold("Next steps:"))
-	fmt.Println(styles.ListItem("Enable and start: sudo systemctl enable --now uinputd"))
-	fmt.Println(styles.ListItem("Check status:     systemctl status uinputd"))
-	fmt.Println(styles.ListItem("Verify setup:     uinput-client doctor"))
+	fmt.Println(styles.ListItem("Start on first use: sudo systemctl enable --now uinputd.socket"))
+	fmt.Println(styles.ListItem("Or always running:  sudo systemctl enable --now uinputd"))
+	fmt.Println(styles.ListItem("Check status:       systemctl status uinputd"))
+	fmt.Println(styles.ListItem("Verify setup:       uinput-client doctor"))
 
 	return nil
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	"github.com/bnema/uinputd-go/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mouseAbsolute bool
+	mouseCount    int
+	mouseHiRes    bool
+	mouseHoldFor  time.Duration
+
+	tapNormalized bool
+	tapMoveOnly   bool
+	tapButton     string
+)
+
+var mouseCmd = &cobra.Command{
+	Use:   "mouse",
+	Short: "Control the virtual mouse",
+	Long: `Move the pointer, click, hold buttons and scroll with the daemon's
+virtual mouse. Use -- before negative numbers so they are not read as flags.`,
+}
+
+var mouseMoveCmd = &cobra.Command{
+	Use:   "move DX DY",
+	Short: "Move the pointer relatively, or to X Y with --absolute",
+	Long: `Move the pointer by DX, DY (positive is right and down).
+
+With --absolute, the pointer is first pushed to the top-left corner and then
+moved to X, Y; this is only pixel-accurate with a flat acceleration profile.
+
+Examples:
+  uinput-client mouse move 100 0
+  uinput-client mouse move -- -50 -50
+  uinput-client mouse move --absolute 960 540`,
+	Args: cobra.ExactArgs(2),
+	RunE: runMouseMove,
+}
+
+var mouseClickCmd = &cobra.Command{
+	Use:   "click [BUTTON]",
+	Short: "Click a mouse button (default: left)",
+	Long: `Click a mouse button: left, right, middle, side, extra, forward, back.
+
+Examples:
+  uinput-client mouse click
+  uinput-client mouse click right
+  uinput-client mouse click --count 2   # double click`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMouseClick,
+}
+
+var mouseHoldCmd = &cobra.Command{
+	Use:   "hold [BUTTON]",
+	Short: "Hold a mouse button until interrupted or a duration elapses",
+	Long: `Hold a mouse button (default: left) until Ctrl+C, SIGTERM, or the --for
+duration elapses. The daemon releases the button when this command exits.
+
+Example:
+  uinput-client mouse hold --for 500ms`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMouseHold,
+}
+
+var mouseScrollCmd = &cobra.Command{
+	Use:   "scroll DY [DX]",
+	Short: "Scroll the wheels (positive is up and right)",
+	Long: `Scroll by DY wheel detents vertically and DX horizontally.
+With --hi-res, amounts are in 1/120 of a detent for smooth scrolling.
+
+Examples:
+  uinput-client mouse scroll -- -3       # three notches down
+  uinput-client mouse scroll 0 2         # two notches right
+  uinput-client mouse scroll --hi-res 30`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runMouseScroll,
+}
+
+var tapCmd = &cobra.Command{
+	Use:   "tap X Y",
+	Short: "Tap or click exact screen coordinates with the absolute pointer",
+	Long: `Tap (touchscreen) or click (tablet) at X, Y using the daemon's absolute
+pointer device (devices.absolute in the daemon config). Coordinates are
+pixels of the configured width and height, or fractions with --normalized.
+
+Examples:
+  uinput-client tap 960 540
+  uinput-client tap --normalized 0.5 0.5
+  uinput-client tap --button right 100 200
+  uinput-client tap --move 100 200       # tablet only: move without clicking`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTap,
+}
+
+func init() {
+	rootCmd.AddCommand(mouseCmd)
+	rootCmd.AddCommand(tapCmd)
+	mouseCmd.AddCommand(mouseMoveCmd)
+	mouseCmd.AddCommand(mouseClickCmd)
+	mouseCmd.AddCommand(mouseHoldCmd)
+	mouseCmd.AddCommand(mouseScrollCmd)
+
+	mouseMoveCmd.Flags().BoolVar(&mouseAbsolute, "absolute", false, "move to screen coordinates instead of by an offset")
+	mouseClickCmd.Flags().IntVar(&mouseCount, "count", 1, "number of clicks")
+	mouseHoldCmd.Flags().DurationVar(&mouseHoldFor, "for", 0, "release after this duration (0=until interrupted)")
+	mouseScrollCmd.Flags().BoolVar(&mouseHiRes, "hi-res", false, "amounts are in 1/120 of a detent")
+
+	tapCmd.Flags().BoolVar(&tapNormalized, "normalized", false, "coordinates are fractions (0-1) of the screen")
+	tapCmd.Flags().BoolVar(&tapMoveOnly, "move", false, "move the pointer without clicking (tablet only)")
+	tapCmd.Flags().StringVar(&tapButton, "button", "", "tablet button to click (left, right, middle)")
+}
+
+func runMouseMove(cmd *cobra.Command, args []string) error {
+	x, err := parseAmount(args[0])
+	if err != nil {
+		return err
+	}
+	y, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	return sendCommand(protocol.CommandType_MouseMove, protocol.MouseMovePayload{
+		X:        x,
+		Y:        y,
+		Absolute: mouseAbsolute,
+	})
+}
+
+func runMouseClick(cmd *cobra.Command, args []string) error {
+	button, err := mouseButtonArg(args)
+	if err != nil {
+		return err
+	}
+
+	return sendCommand(protocol.CommandType_MouseClick, protocol.MouseButtonPayload{
+		Button: button,
+		Count:  mouseCount,
+	})
+}
+
+func runMouseHold(cmd *cobra.Command, args []string) error {
+	button, err := mouseButtonArg(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.MouseDown(ctx, client.MouseButton(button)); err != nil {
+		return err
+	}
+
+	waitHold(ctx, mouseHoldFor)
+
+	// Release with a fresh context: ctx may already be cancelled
+	return c.MouseUp(context.Background(), client.MouseButton(button))
+}
+
+func runMouseScroll(cmd *cobra.Command, args []string) error {
+	vertical, err := parseAmount(args[0])
+	if err != nil {
+		return err
+	}
+
+	var horizontal int32
+	if len(args) > 1 {
+		if horizontal, err = parseAmount(args[1]); err != nil {
+			return err
+		}
+	}
+
+	return sendCommand(protocol.CommandType_MouseScroll, protocol.MouseScrollPayload{
+		Vertical:   vertical,
+		Horizontal: horizontal,
+		HiRes:      mouseHiRes,
+	})
+}
+
+func runTap(cmd *cobra.Command, args []string) error {
+	x, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return fmt.Errorf("invalid coordinate %q", args[0])
+	}
+	y, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid coordinate %q", args[1])
+	}
+
+	action := "tap"
+	if tapMoveOnly {
+		action = "move"
+	}
+
+	return sendCommand(protocol.CommandType_AbsPointer, protocol.AbsPointerPayload{
+		X:          x,
+		Y:          y,
+		Normalized: tapNormalized,
+		Action:     action,
+		Button:     tapButton,
+	})
+}
+
+// mouseButtonArg returns the optional button argument, validated locally.
+func mouseButtonArg(args []string) (string, error) {
+	if len(args) == 0 {
+		return "left", nil
+	}
+	if _, ok := uinput.LookupButton(args[0]); !ok {
+		return "", fmt.Errorf("unknown mouse button: %s", args[0])
+	}
+	return args[0], nil
+}
+
+// parseAmount parses a signed pointer or wheel amount.
+func parseAmount(s string) (int32, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return int32(n), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bnema/uinputd-go/internal/styles"
+	"github.com/bnema/uinputd-go/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var planJSON bool
+
+var planCmd = &cobra.Command{
+	Use:   "plan TEXT",
+	Short: "Show the keystrokes the daemon would send, without typing",
+	Long: `Ask the daemon which keystrokes it would send to type TEXT, including
+dead keys, modifiers and fallback input, without touching any device.
+
+Examples:
+  uinput-client plan "Ôk" --layout fr
+  uinput-client plan "→" --fallback unicode_hex --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlan,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVar(&fallback, "fallback", "", "typing of characters missing from the layout (none, unicode_hex; empty=use config default)")
+	planCmd.Flags().BoolVar(&planJSON, "json", false, "print the plan as JSON")
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	plan, err := c.Plan(context.Background(), args[0], &client.TypeOptions{
+		Layout:   layout,
+		Fallback: fallback,
+	})
+	if err != nil {
+		return err
+	}
+
+	if planJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+
+	printPlan(plan)
+	return nil
+}
+
+// printPlan prints one line per character with its keystrokes.
+func printPlan(plan *client.Plan) {
+	fmt.Println(styles.Section(fmt.Sprintf("Plan (layout: %s)", plan.Layout)))
+
+	keystrokes, dropped := 0, 0
+	for _, step := range plan.Steps {
+		chords := make([]string, len(step.Keys))
+		for i, key := range step.Keys {
+			chords[i] = key.Chord
+		}
+		keystrokes += len(step.Keys)
+
+		line := fmt.Sprintf("  %s  %-8q", styles.Dim(fmt.Sprintf("%3d", step.Position)), step.Char)
+		switch step.Method {
+		case client.MethodFallback:
+			line += strings.Join(chords, " ") + "  " + styles.InfoStyle.Render("(fallback)")
+		case client.MethodDropped:
+			dropped++
+			line += styles.ErrorStyle.Render("dropped") + "  " + styles.Dim(step.Error)
+		default:
+			line += strings.Join(chords, " ")
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d characters, %d keystrokes", len(plan.Steps), keystrokes)
+	if dropped > 0 {
+		fmt.Println(styles.Warning(fmt.Sprintf("%s, %d dropped", summary, dropped)))
+		return
+	}
+	fmt.Println(styles.Success(summary))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/styles"
+	"github.com/bnema/uinputd-go/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the daemon's status and capabilities",
+	Long: `Show the daemon's version, uptime, device health, layouts and limits.
+Exits with an error if a device of the daemon stopped working.
+
+Examples:
+  uinput-client status
+  uinput-client status --json | jq .capabilities.commands`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "print the status and capabilities as JSON")
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	if statusJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Status       *client.Status       `json:"status"`
+			Capabilities *client.Capabilities `json:"capabilities"`
+		}{status, caps}); err != nil {
+			return err
+		}
+	} else {
+		printStatus(status, caps)
+	}
+
+	if !status.Healthy {
+		return fmt.Errorf("daemon devices unhealthy")
+	}
+	return nil
+}
+
+// printStatus prints the daemon's state, devices and capabilities.
+func printStatus(status *client.Status, caps *client.Capabilities) {
+	fmt.Println(styles.Section(fmt.Sprintf("uinputd %s", status.Version)))
+	fmt.Printf("  %-16s %s %s\n", "Uptime:", status.Uptime.Round(time.Second), styles.Dim("(since "+status.StartedAt.Format(time.DateTime)+")"))
+	fmt.Printf("  %-16s %d\n", "Commands served:", status.CommandsServed)
+	fmt.Printf("  %-16s %d\n", "Connections:", status.Connections)
+
+	if len(status.Clients) > 0 {
+		fmt.Println(styles.Section("Clients"))
+		for _, cl := range status.Clients {
+			fmt.Println(styles.ListItem(fmt.Sprintf("%s %d commands %s", peerName(cl.Peer), cl.Commands,
+				styles.Dim("connected "+time.Since(cl.ConnectedAt).Round(time.Second).String()+" ago"))))
+		}
+	}
+
+	if len(status.Jobs) > 0 {
+		fmt.Println(styles.Section("Jobs"))
+		for _, job := range status.Jobs {
+			printJob(job)
+		}
+	}
+
+	if len(status.RateLimits) > 0 {
+		fmt.Println(styles.Section("Rate limits"))
+		for _, rl := range status.RateLimits {
+			printRateLimit(rl)
+		}
+	}
+
+	fmt.Println(styles.Section("Devices"))
+	for _, d := range status.Devices {
+		line := fmt.Sprintf("%s %s", d.Name, styles.Dim("("+d.Profile+")"))
+		switch {
+		case !d.Created:
+			fmt.Println(styles.ListItem(line + " " + styles.Dim("created on first use")))
+		case d.Healthy:
+			fmt.Println(styles.Success(line))
+		default:
+			fmt.Println(styles.Error(line + " " + d.Error))
+		}
+	}
+
+	layout := caps.DefaultLayout
+	if caps.LayoutDetect {
+		layout += " " + styles.Dim("(session layout detected)")
+	}
+	fmt.Println(styles.Section("Capabilities"))
+	fmt.Printf("  %-16s %s\n", "Layout:", layout)
+	fmt.Printf("  %-16s %s\n", "Layouts:", strings.Join(caps.Layouts, ", "))
+	fmt.Printf("  %-16s %s %s\n", "Fallback:", caps.DefaultFallback, styles.Dim("(available: "+strings.Join(caps.Fallbacks, ", ")+")"))
+	fmt.Printf("  %-16s %s\n", "Commands:", strings.Join(caps.Commands, ", "))
+	fmt.Printf("  %-16s %d bytes\n", "Max message:", caps.Limits.MaxMessageSize)
+	fmt.Printf("  %-16s %d\n", "Max concurrent:", caps.Limits.MaxConcurrentCmds)
+}
+
+// printRateLimit prints what a rate limit let through and held back.
+func printRateLimit(rl client.RateLimitStatus) {
+	scope := "global"
+	switch {
+	case rl.Rule != "":
+		scope = "rule " + rl.Rule
+	case !rl.Global:
+		scope = fmt.Sprintf("uid %d", rl.UID)
+	}
+
+	limits := []string{}
+	if rl.CommandsPerSecond > 0 {
+		limits = append(limits, fmt.Sprintf("%g commands/s", rl.CommandsPerSecond))
+	}
+	if rl.KeystrokesPerSecond > 0 {
+		limits = append(limits, fmt.Sprintf("%g keystrokes/s", rl.KeystrokesPerSecond))
+	}
+	if len(limits) == 0 {
+		limits = append(limits, "no limit")
+	}
+
+	line := fmt.Sprintf("%-10s %d commands, %d keystrokes, %d delayed, %d rejected %s",
+		scope, rl.Commands, rl.Keystrokes, rl.Delayed, rl.Rejected, styles.Dim("("+strings.Join(limits, ", ")+")"))
+	if rl.Rejected > 0 {
+		fmt.Println(styles.Warning(line))
+	} else {
+		fmt.Println(styles.ListItem(line))
+	}
+}
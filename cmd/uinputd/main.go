@@ -33,11 +33,11 @@ func main() {
 var rootCmd = &cobra.Command{
 	Use:   "uinputd",
 	Short: "uinputd - Input automation daemon with multi-layout support",
-	Long: `uinputd is a daemon that creates a virtual keyboard device
+	Long: `uinputd is a daemon that creates a virtual keyboard (and mouse) device
 and listens for input automation commands via Unix socket.
 
 Features:
-  - Multi-layout support (US, FR, DE, ES, UK, IT)
+  - Multi-layout support (US, FR, DE, ES, UK, IT, or any XKB layout)
   - Real-time streaming input
   - Low resource usage
   - Group-based socket permissions (root:input)`,
@@ -99,6 +99,70 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		log.Fatal("failed to create server", "error", err)
 	}
 	defer srv.Close()
+	srv.SetVersion(version)
+
+	// Create virtual pointer device (mouse commands fail without it)
+	if cfg.Devices.Pointer {
+		pointer, err := uinput.NewPointer(ctx)
+		if err != nil {
+			log.Warn("failed to create pointer device, mouse commands disabled", "error", err)
+		} else {
+			defer pointer.Close()
+			srv.SetPointer(pointer)
+		}
+	}
+
+	// Create absolute pointer device (tablet or touchscreen)
+	if abs := cfg.Devices.Absolute; abs.Enabled {
+		var absolute *uinput.Device
+		switch profile := uinput.Profile(abs.Mode); profile {
+		case uinput.ProfileTablet, uinput.ProfileTouchscreen:
+			absolute, err = uinput.Create(ctx, uinput.Options{
+				Profile: profile,
+				Width:   abs.Width,
+				Height:  abs.Height,
+			})
+		default:
+			err = fmt.Errorf("unknown absolute mode: %s", abs.Mode)
+		}
+		if err != nil {
+			log.Warn("failed to create absolute pointer device, abs_pointer disabled", "error", err)
+		} else {
+			defer absolute.Close()
+			srv.SetAbsolute(absolute)
+		}
+	}
+
+	// Gamepad is created on the first gamepad command
+	if cfg.Devices.Gamepad {
+		srv.SetGamepadFactory(func(ctx context.Context) (uinput.DeviceInterface, error) {
+			return uinput.NewGamepad(ctx)
+		})
+	}
+
+	// Create additional named devices
+	for _, dc := range cfg.Devices.Extra {
+		opts, err := extraDeviceOptions(cfg, dc)
+		if err != nil {
+			log.Fatal("invalid device config", "name", dc.Name, "error", err)
+		}
+
+		device, err := uinput.Create(ctx, opts)
+		if err != nil {
+			log.Fatal("failed to create device", "name", dc.Name, "error", err)
+		}
+		defer device.Close()
+
+		if err := srv.AddDevice(server.NamedDevice{
+			Name:    dc.Name,
+			Profile: opts.Profile,
+			Device:  device,
+			Width:   opts.Width,
+			Height:  opts.Height,
+		}); err != nil {
+			log.Fatal("failed to register device", "name", dc.Name, "error", err)
+		}
+	}
 
 	// Run server with errgroup for coordinated shutdown
 	g, ctx := errgroup.WithContext(ctx)
@@ -116,3 +180,41 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	log.Info("uinputd shutdown complete")
 	return nil
 }
+
+// extraDeviceOptions converts a devices.extra entry into device options.
+func extraDeviceOptions(cfg *config.Config, dc config.DeviceConfig) (uinput.Options, error) {
+	if dc.Name == "" {
+		return uinput.Options{}, fmt.Errorf("name is required")
+	}
+
+	var bus uint16
+	if dc.Bus != "" {
+		var ok bool
+		if bus, ok = uinput.BusNames[dc.Bus]; !ok {
+			return uinput.Options{}, fmt.Errorf("unknown bus: %s", dc.Bus)
+		}
+	}
+
+	opts := uinput.Options{
+		Profile: uinput.Profile(dc.Type),
+		Identity: uinput.Identity{
+			Name:    dc.Name,
+			Bustype: bus,
+			Vendor:  dc.VendorID,
+			Product: dc.ProductID,
+			Version: dc.Version,
+		},
+		Width:  dc.Width,
+		Height: dc.Height,
+	}
+
+	// Tablets and touchscreens default to the built-in absolute pointer size
+	if opts.Width == 0 {
+		opts.Width = cfg.Devices.Absolute.Width
+	}
+	if opts.Height == 0 {
+		opts.Height = cfg.Devices.Absolute.Height
+	}
+
+	return opts, nil
+}
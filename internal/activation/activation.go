@@ -0,0 +1,75 @@
+// Package activation receives the listening socket systemd passes to the
+// daemon with socket activation, following sd_listen_fds(3).
+package activation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenFDsStart is the first file descriptor systemd passes
+// (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// Listener returns the Unix socket systemd passed to the daemon, or nil if
+// the daemon was not socket activated. The LISTEN_* variables are unset so
+// that processes the daemon starts do not inherit them.
+//
+// Closing the listener leaves the socket file in place for systemd.
+func Listener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	return listener(pid, fds, listenFDsStart)
+}
+
+// listener returns the socket passed as file descriptor first, given the
+// values of LISTEN_PID and LISTEN_FDS.
+func listener(pidEnv, fdsEnv string, first int) (net.Listener, error) {
+	if pidEnv == "" && fdsEnv == "" {
+		return nil, nil
+	}
+
+	pid, err := strconv.Atoi(pidEnv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_PID %q", pidEnv)
+	}
+	if pid != os.Getpid() {
+		// Meant for another process, e.g. the one that started the daemon
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fdsEnv)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fdsEnv)
+	}
+	switch {
+	case n == 0:
+		return nil, nil
+	case n > 1:
+		for fd := first; fd < first+n; fd++ {
+			unix.Close(fd)
+		}
+		return nil, fmt.Errorf("expected 1 socket, got %d (check ListenStream= in uinputd.socket)", n)
+	}
+
+	unix.CloseOnExec(first)
+	f := os.NewFile(uintptr(first), "LISTEN_FD_"+strconv.Itoa(first))
+	defer f.Close()
+
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("file descriptor %d: %w", first, err)
+	}
+	if _, ok := l.(*net.UnixListener); !ok {
+		l.Close()
+		return nil, fmt.Errorf("file descriptor %d is not a Unix socket: %s", first, l.Addr())
+	}
+	return l, nil
+}
@@ -0,0 +1,116 @@
+package activation
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	ul, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ul.Close()
+
+	// The socket as systemd would pass it, at a file descriptor of its own
+	// that listener takes ownership of
+	f, err := ul.File()
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	fd, err := unix.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+
+	l, err := listener(strconv.Itoa(os.Getpid()), "1", fd)
+	if err != nil {
+		t.Fatalf("listener() error = %v", err)
+	}
+	if l == nil {
+		t.Fatal("listener() = nil, want the passed socket")
+	}
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	conn.Close()
+
+	// The socket file stays for systemd
+	l.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("socket file removed on Close: %v", err)
+	}
+}
+
+func TestListenerNotActivated(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+		fds  string
+	}{
+		{"no environment", "", ""},
+		{"other process", strconv.Itoa(os.Getpid() + 1), "1"},
+		{"no socket", strconv.Itoa(os.Getpid()), "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := listener(tt.pid, tt.fds, listenFDsStart)
+			if err != nil || l != nil {
+				t.Errorf("listener() = %v, %v, want nil, nil", l, err)
+			}
+		})
+	}
+}
+
+func TestListenerInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+		fds  string
+	}{
+		{"invalid pid", "systemd", "1"},
+		{"invalid count", strconv.Itoa(os.Getpid()), "many"},
+		{"negative count", strconv.Itoa(os.Getpid()), "-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := listener(tt.pid, tt.fds, listenFDsStart); err == nil {
+				t.Error("listener() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestListenerNotSocket(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	// listener takes ownership of the descriptor
+	fd, err := unix.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+	if _, err := listener(strconv.Itoa(os.Getpid()), "1", fd); err == nil {
+		t.Error("listener() error = nil, want error for a non-socket")
+	}
+}
@@ -0,0 +1,314 @@
+// Package audit keeps an append-only trail of the input clients inject
+// through the daemon, as JSON lines with size-based rotation. Typed text is
+// never written in clear: only its length and a keyed hash or redacted
+// preview.
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// How typed text is recorded.
+const (
+	TextHash     = "hash"     // HMAC-SHA256 of the text, keyed per install
+	TextRedacted = "redacted" // Hash and a preview with letters and digits masked
+	TextNone     = "none"     // Length only
+)
+
+// Options configures an audit log.
+type Options struct {
+	Path         string
+	KeyPath      string // HMAC key, created if missing (default: audit.key next to Path)
+	Text         string // TextHash (default), TextRedacted or TextNone
+	PreviewChars int    // Length of redacted previews (default 32)
+	MaxSize      int64  // Bytes before the file is rotated (0 = never)
+	MaxBackups   int    // Rotated files kept as Path.1 ... Path.N
+}
+
+// Entry is a command recorded in the audit log.
+type Entry struct {
+	Time time.Time `json:"time"`
+
+	// Client identity, from the socket credentials
+	UID *uint32 `json:"uid,omitempty"` // Nil if the client could not be identified
+	GID *uint32 `json:"gid,omitempty"`
+	PID int32   `json:"pid,omitempty"`
+	Exe string  `json:"exe,omitempty"`
+
+	Command string `json:"command"`
+	ID      string `json:"id,omitempty"`
+	Device  string `json:"device,omitempty"`
+	Layout  string `json:"layout,omitempty"`
+	Keys    string `json:"keys,omitempty"` // Chord or keycode of key commands
+
+	// Text is the typed text, recorded as TextLen, TextHMAC and Preview
+	// depending on the log's options
+	Text     string `json:"-"`
+	TextLen  int    `json:"text_len,omitempty"` // In characters
+	TextHMAC string `json:"text_hmac,omitempty"`
+	Preview  string `json:"preview,omitempty"`
+
+	Success bool   `json:"success"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Log is an open audit log, safe for concurrent use.
+type Log struct {
+	opts Options
+	key  []byte // HMAC key of text hashes, nil with TextNone
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the audit log for appending, creating it and its directory
+// if needed. The file is only readable by the daemon's user, as is the
+// HMAC key generated on first use.
+func Open(opts Options) (*Log, error) {
+	switch opts.Text {
+	case "":
+		opts.Text = TextHash
+	case TextHash, TextRedacted, TextNone:
+	default:
+		return nil, fmt.Errorf("unknown audit text mode: %s", opts.Text)
+	}
+	if opts.PreviewChars <= 0 {
+		opts.PreviewChars = 32
+	}
+	if opts.KeyPath == "" {
+		opts.KeyPath = filepath.Join(filepath.Dir(opts.Path), "audit.key")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o750); err != nil {
+		return nil, err
+	}
+
+	l := &Log{opts: opts}
+	if opts.Text != TextNone {
+		key, err := loadKey(opts.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("audit key: %w", err)
+		}
+		l.key = key
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// keySize is the length of HMAC keys, stored hex encoded.
+const keySize = 32
+
+// loadKey reads the HMAC key at path, generating it if the file does not
+// exist. The key must not be accessible to other users, or its hashes
+// would be as easy to brute force as plain ones.
+func loadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return createKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return nil, fmt.Errorf("%s is accessible to other users (mode %04o, want 0600)", path, perm)
+	}
+
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("%s is not a %d-byte hex key", path, keySize)
+	}
+	return key, nil
+}
+
+// createKey writes a new random key to path, unless another process
+// created it first.
+func createKey(path string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return loadKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return key, nil
+}
+
+// open opens the log file and reads its current size.
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Record appends the entry, rotating the file first if it would grow past
+// the maximum size. A zero Time is set to now.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.redact(&e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	if l.opts.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.opts.MaxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// redact replaces the entry's text by what the options allow to record.
+func (l *Log) redact(e *Entry) {
+	if e.Text == "" {
+		return
+	}
+
+	e.TextLen = utf8.RuneCountInString(e.Text)
+	if l.opts.Text != TextNone {
+		mac := hmac.New(sha256.New, l.key)
+		mac.Write([]byte(e.Text))
+		e.TextHMAC = hex.EncodeToString(mac.Sum(nil))
+	}
+	if l.opts.Text == TextRedacted {
+		e.Preview = Redact(e.Text, l.opts.PreviewChars)
+	}
+	e.Text = ""
+}
+
+// Redact returns the first max characters of text with letters masked as
+// "x" or "X" and digits as "0", keeping the shape of the text but not its
+// content. Control characters are escaped; truncated text ends with "…".
+func Redact(text string, max int) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range text {
+		if n == max {
+			b.WriteString("…")
+			break
+		}
+		n++
+
+		switch {
+		case unicode.IsUpper(r):
+			b.WriteByte('X')
+		case unicode.IsLetter(r):
+			b.WriteByte('x')
+		case unicode.IsDigit(r):
+			b.WriteByte('0')
+		case unicode.IsControl(r):
+			b.WriteString(strings.Trim(strconv.QuoteRune(r), "'"))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// rotate renames the log file to Path.1, shifting older files up to
+// Path.MaxBackups and dropping the oldest, then starts a new file. Without
+// backups the file is truncated. The log stays open if renaming fails.
+// The caller must hold l.mu.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	err := l.shift()
+	if oerr := l.open(); oerr != nil {
+		return oerr
+	}
+	return err
+}
+
+// shift renames the log file and its backups one step up.
+func (l *Log) shift() error {
+	if l.opts.MaxBackups <= 0 {
+		return os.Truncate(l.opts.Path, 0)
+	}
+
+	backup := func(n int) string { return l.opts.Path + "." + strconv.Itoa(n) }
+	if err := os.Remove(backup(l.opts.MaxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := l.opts.MaxBackups - 1; n >= 1; n-- {
+		if err := os.Rename(backup(n), backup(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.opts.Path, backup(1))
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,267 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEntries decodes every line of an audit log file.
+func readEntries(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		mode    string
+		hash    bool
+		preview string
+	}{
+		{TextHash, true, ""},
+		{TextRedacted, true, "Xxxxx, xxxxx 00!"},
+		{TextNone, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit", "audit.log")
+			l, err := Open(Options{Path: path, Text: tt.mode})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+
+			uid := uint32(1000)
+			err = l.Record(Entry{
+				Time:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+				UID:     &uid,
+				PID:     42,
+				Exe:     "/usr/bin/dictate",
+				Command: "type",
+				Layout:  "us",
+				Text:    "Hello, world 42!",
+				Success: true,
+			})
+			if err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "Hello") {
+				t.Errorf("Text recorded in clear: %s", data)
+			}
+
+			entries := readEntries(t, path)
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+			e := entries[0]
+			if e["uid"] != 1000.0 || e["pid"] != 42.0 || e["exe"] != "/usr/bin/dictate" || e["command"] != "type" || e["layout"] != "us" || e["success"] != true {
+				t.Errorf("Unexpected entry %v", e)
+			}
+			if e["time"] != "2025-01-02T03:04:05Z" || e["text_len"] != 16.0 {
+				t.Errorf("Unexpected time or length in %v", e)
+			}
+			if hash, _ := e["text_hmac"].(string); (hash != "") != tt.hash || (tt.hash && len(hash) != 64) {
+				t.Errorf("text_hmac = %q, want hash %v", hash, tt.hash)
+			}
+			if preview, _ := e["preview"].(string); preview != tt.preview {
+				t.Errorf("preview = %q, want %q", preview, tt.preview)
+			}
+
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+				t.Errorf("Log file mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+// recordText records a type command with text and returns its text_hmac.
+func recordText(t *testing.T, opts Options, text string) string {
+	t.Helper()
+
+	l, err := Open(opts)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := l.Record(Entry{Command: "type", Text: text}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	l.Close()
+
+	entries := readEntries(t, opts.Path)
+	hash, _ := entries[len(entries)-1]["text_hmac"].(string)
+	return hash
+}
+
+func TestTextHMAC(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Path: filepath.Join(dir, "audit.log")}
+	keyPath := filepath.Join(dir, "audit.key")
+
+	hash := recordText(t, opts, "1234")
+
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		t.Fatalf("Key not created: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The hash is keyed, not a plain SHA-256 a dictionary would find
+	plain := sha256.Sum256([]byte("1234"))
+	if hash == hex.EncodeToString(plain[:]) {
+		t.Error("text_hmac is an unkeyed SHA-256")
+	}
+	data, _ := os.ReadFile(keyPath)
+	key, _ := hex.DecodeString(strings.TrimSpace(string(data)))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("1234"))
+	if want := hex.EncodeToString(mac.Sum(nil)); hash != want {
+		t.Errorf("text_hmac = %q, want HMAC with the key file %q", hash, want)
+	}
+
+	// The key is reused, so the same text keeps the same hash
+	if again := recordText(t, opts, "1234"); again != hash {
+		t.Errorf("text_hmac after reopening = %q, want %q", again, hash)
+	}
+
+	// Another install hashes differently
+	other := Options{Path: filepath.Join(t.TempDir(), "audit.log")}
+	if recordText(t, other, "1234") == hash {
+		t.Error("Two keys gave the same text_hmac")
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		perm os.FileMode
+	}{
+		{"readable by others", strings.Repeat("ab", keySize) + "\n", 0o644},
+		{"not hex", "secret\n", 0o600},
+		{"too short", "abcd\n", 0o600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			keyPath := filepath.Join(dir, "audit.key")
+			if err := os.WriteFile(keyPath, []byte(tt.data), tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			os.Chmod(keyPath, tt.perm)
+
+			if _, err := Open(Options{Path: filepath.Join(dir, "audit.log"), KeyPath: keyPath}); err == nil {
+				t.Error("Open() error = nil, want a key error")
+			}
+		})
+	}
+}
+
+func TestNoKeyWithoutHash(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Options{Path: filepath.Join(dir, "audit.log"), Text: TextNone})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	l.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "audit.key")); !os.IsNotExist(err) {
+		t.Error("Key created with text none")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"Pa55 wörd", 32, "Xx00 xxxx"},
+		{"line\nbreak\t", 32, `xxxx\nxxxxx\t`},
+		{"abcdef", 3, "xxx…"},
+		{"abc", 3, "xxx"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.text, tt.max); got != tt.want {
+			t.Errorf("Redact(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(Options{Path: path, MaxSize: 200, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	// Each entry is over 100 bytes, so that every file holds one
+	for i := 0; i < 5; i++ {
+		if err := l.Record(Entry{Command: "ping", ID: strings.Repeat("x", 80) + string(rune('a'+i))}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	for file, want := range map[string]string{path: "e", path + ".1": "d", path + ".2": "c"} {
+		entries := readEntries(t, file)
+		if len(entries) != 1 || !strings.HasSuffix(entries[0]["id"].(string), want) {
+			t.Errorf("%s = %v, want the entry %s", filepath.Base(file), entries, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected at most 2 backups")
+	}
+}
+
+func TestReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		l, err := Open(Options{Path: path})
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		if err := l.Record(Entry{Command: "ping"}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		l.Close()
+	}
+
+	if entries := readEntries(t, path); len(entries) != 2 {
+		t.Errorf("Expected 2 entries after reopening, got %d", len(entries))
+	}
+}
+
+func TestOpenInvalidMode(t *testing.T) {
+	if _, err := Open(Options{Path: filepath.Join(t.TempDir(), "audit.log"), Text: "clear"}); err == nil {
+		t.Error("Open() with unknown text mode error = nil")
+	}
+}
@@ -17,6 +17,32 @@ type Config struct {
 	// Default keyboard layout
 	Layout string `mapstructure:"layout"`
 
+	// Detection of the session's active layout, used instead of Layout
+	LayoutDetect LayoutDetectConfig `mapstructure:"layout_detect"`
+
+	// How characters missing from the layout are typed: "none" drops them,
+	// "unicode_hex" enters them with Ctrl+Shift+U (GTK/IBus)
+	Fallback string `mapstructure:"fallback"`
+
+	// Directory of user-defined layout files (*.yaml, *.yml, *.json)
+	LayoutsDir string `mapstructure:"layouts_dir"`
+
+	// Layouts parsed from XKB symbols files ("xkb:de(nodeadkeys)")
+	XKB XKBConfig `mapstructure:"xkb"`
+
+	// Virtual devices created next to the keyboard
+	Devices DevicesConfig `mapstructure:"devices"`
+
+	// Typing jobs (type, stream and stream sessions)
+	Jobs JobsConfig `mapstructure:"jobs"`
+
+	// Policy file restricting what each client may do, by UID, GID or
+	// executable (empty = every client may do everything)
+	PolicyFile string `mapstructure:"policy_file"`
+
+	// Audit trail of the commands clients send
+	Audit AuditConfig `mapstructure:"audit"`
+
 	// Performance tuning
 	Performance PerformanceConfig `mapstructure:"performance"`
 
@@ -28,6 +54,79 @@ type Config struct {
 type SocketConfig struct {
 	Path        string `mapstructure:"path"`
 	Permissions uint32 `mapstructure:"permissions"`
+	// Connections idle for longer than ReadTimeoutMs are closed, and those
+	// not reading a response within WriteTimeoutMs too (0 = no timeout)
+	ReadTimeoutMs  int `mapstructure:"read_timeout_ms"`
+	WriteTimeoutMs int `mapstructure:"write_timeout_ms"`
+	// When started by systemd socket activation, the daemon exits after
+	// IdleExitMs without clients or jobs (0 = never); systemd starts it
+	// again on the next connection
+	IdleExitMs int `mapstructure:"idle_exit_ms"`
+	// Connections over MaxConnections are refused with the "busy" error
+	// code (0 = no limit)
+	MaxConnections int `mapstructure:"max_connections"`
+}
+
+// LayoutDetectConfig configures detection of the desktop session's active
+// layout for commands that do not name one. Sources are tried in order:
+// "sway", "hyprland", "env", "localectl" and "keyboard_file".
+type LayoutDetectConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Sources []string `mapstructure:"sources"`
+	CacheMs int      `mapstructure:"cache_ms"` // How long a detected layout is reused
+}
+
+// XKBConfig configures layouts loaded from XKB symbols files.
+// Layouts named "xkb:..." are parsed on first use; Preload parses them at
+// startup so that broken layouts are reported early.
+type XKBConfig struct {
+	SymbolsDir string   `mapstructure:"symbols_dir"`
+	Preload    []string `mapstructure:"preload"` // e.g. ["us(dvorak)", "pl"]
+}
+
+// DevicesConfig selects the optional virtual devices.
+type DevicesConfig struct {
+	Pointer  bool           `mapstructure:"pointer"`  // Virtual mouse for mouse_* commands
+	Absolute AbsoluteConfig `mapstructure:"absolute"` // Absolute pointer for abs_pointer commands and absolute mouse moves
+	Gamepad  bool           `mapstructure:"gamepad"`  // Gamepad for gamepad_* commands, created on first use
+
+	// Time for libinput and the compositor to open a new device before
+	// events are written to it (milliseconds)
+	SettleMs int `mapstructure:"settle_ms"`
+
+	// Additional named devices, targeted with the command "device" field
+	Extra []DeviceConfig `mapstructure:"extra"`
+}
+
+// DeviceConfig declares an additional named virtual device.
+// Zero IDs fall back to uinputd's own IDs for the device type.
+type DeviceConfig struct {
+	Name      string `mapstructure:"name"`       // Used by commands and reported to the system
+	Type      string `mapstructure:"type"`       // "keyboard", "pointer", "tablet", "touchscreen" or "gamepad"
+	Bus       string `mapstructure:"bus"`        // "usb", "bluetooth" or "virtual" (default)
+	VendorID  uint16 `mapstructure:"vendor_id"`  // e.g. 0x046d
+	ProductID uint16 `mapstructure:"product_id"` // e.g. 0xc31c
+	Version   uint16 `mapstructure:"version"`
+	Width     int32  `mapstructure:"width"`  // Tablet/touchscreen coordinate space (default: devices.absolute)
+	Height    int32  `mapstructure:"height"` // Tablet/touchscreen coordinate space (default: devices.absolute)
+}
+
+// AbsoluteConfig configures the absolute pointer (tablet/touchscreen) device.
+// Width and Height define the coordinate space, usually the screen size in
+// pixels, so that pixel coordinates map 1:1 to the output.
+type AbsoluteConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Mode    string `mapstructure:"mode"` // "tablet" or "touchscreen"
+	Width   int32  `mapstructure:"width"`
+	Height  int32  `mapstructure:"height"`
+}
+
+// JobsConfig configures typing jobs, which clients can cancel, pause and
+// resume by ID.
+type JobsConfig struct {
+	// How long a job may stay paused before it is cancelled (0 = forever).
+	// Paused stream sessions outlive their connection until then.
+	PauseTimeoutMs int `mapstructure:"pause_timeout_ms"`
 }
 
 // PerformanceConfig contains performance tuning parameters.
@@ -37,6 +136,57 @@ type PerformanceConfig struct {
 	StreamDelayMs     int `mapstructure:"stream_delay_ms"`
 	CharDelayMs       int `mapstructure:"char_delay_ms"`
 	MaxConcurrentCmds int `mapstructure:"max_concurrent_cmds"`
+
+	// Limits on how fast clients may send commands and keystrokes
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// RateLimitConfig configures the token buckets limiting commands and
+// keystrokes per second, daemon-wide and for each client UID. Commands
+// over a limit are rejected, or delayed by up to MaxDelayMs with the
+// "delay" action. The max_commands_per_second of policy rules are
+// enforced the same way.
+type RateLimitConfig struct {
+	Action     string `mapstructure:"action"` // "reject" or "delay"
+	MaxDelayMs int    `mapstructure:"max_delay_ms"`
+
+	Global RateLimits      `mapstructure:"global"`  // Shared by every client
+	PerUID RateLimits      `mapstructure:"per_uid"` // For each client UID
+	UIDs   []UIDRateLimits `mapstructure:"uids"`    // Replace PerUID for given UIDs
+}
+
+// RateLimits are the rates of a token bucket pair (0 = no limit) and the
+// bursts they allow (0 = one second's worth). A command takes the
+// keystrokes of its whole text at once, so texts longer than the
+// keystroke burst are always over the limit.
+type RateLimits struct {
+	CommandsPerSecond   float64 `mapstructure:"commands_per_second"`
+	KeystrokesPerSecond float64 `mapstructure:"keystrokes_per_second"`
+	CommandsBurst       float64 `mapstructure:"commands_burst"`
+	KeystrokesBurst     float64 `mapstructure:"keystrokes_burst"`
+}
+
+// UIDRateLimits are the rate limits of a given client UID.
+type UIDRateLimits struct {
+	UID        uint32 `mapstructure:"uid"`
+	RateLimits `mapstructure:",squash"`
+}
+
+// AuditConfig configures the audit log, a JSON lines file recording each
+// command with the identity of its client. Typed text is recorded as its
+// length and, with Text "hash" or "redacted", an HMAC-SHA256 keyed with
+// the file at KeyPath; "redacted" adds a preview with letters and digits
+// masked.
+type AuditConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Path         string `mapstructure:"path"`
+	KeyPath      string `mapstructure:"key_path"` // Generated if missing (default: audit.key next to path)
+	Text         string `mapstructure:"text"`     // "hash", "redacted" or "none"
+	PreviewChars int    `mapstructure:"preview_chars"`
+	// The file is rotated once larger than MaxSizeMB (0 = never), keeping
+	// MaxBackups older files
+	MaxSizeMB  int `mapstructure:"max_size_mb"`
+	MaxBackups int `mapstructure:"max_backups"`
 }
 
 // LoggingConfig contains logging settings.
@@ -90,9 +240,43 @@ func setDefaults(v *viper.Viper) {
 	// Socket defaults
 	v.SetDefault("socket.path", getDefaultSocketPath())
 	v.SetDefault("socket.permissions", 0600)
+	v.SetDefault("socket.read_timeout_ms", 0)
+	v.SetDefault("socket.write_timeout_ms", 10000)
+	v.SetDefault("socket.idle_exit_ms", 0)
+	v.SetDefault("socket.max_connections", 256)
 
 	// Layout defaults
 	v.SetDefault("layout", "us")
+	v.SetDefault("layout_detect.enabled", false)
+	v.SetDefault("layout_detect.sources", []string{"sway", "hyprland", "env", "localectl", "keyboard_file"})
+	v.SetDefault("layout_detect.cache_ms", 1000)
+	v.SetDefault("fallback", "none")
+	v.SetDefault("layouts_dir", "/etc/uinputd/layouts")
+	v.SetDefault("xkb.symbols_dir", "/usr/share/X11/xkb/symbols")
+
+	// Device defaults
+	v.SetDefault("devices.pointer", true)
+	v.SetDefault("devices.gamepad", true)
+	v.SetDefault("devices.settle_ms", 200)
+	v.SetDefault("devices.absolute.enabled", false)
+	v.SetDefault("devices.absolute.mode", "tablet")
+	v.SetDefault("devices.absolute.width", 1920)
+	v.SetDefault("devices.absolute.height", 1080)
+
+	// Job defaults
+	v.SetDefault("jobs.pause_timeout_ms", 300000) // 5 minutes
+
+	// Policy defaults
+	v.SetDefault("policy_file", "")
+
+	// Audit defaults
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.path", "/var/log/uinputd/audit.log")
+	v.SetDefault("audit.key_path", "")
+	v.SetDefault("audit.text", "hash")
+	v.SetDefault("audit.preview_chars", 32)
+	v.SetDefault("audit.max_size_mb", 10)
+	v.SetDefault("audit.max_backups", 5)
 
 	// Performance defaults
 	v.SetDefault("performance.buffer_size", 4096)
@@ -100,6 +284,16 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("performance.stream_delay_ms", 50)
 	v.SetDefault("performance.char_delay_ms", 10)
 	v.SetDefault("performance.max_concurrent_cmds", 100)
+	v.SetDefault("performance.rate_limit.action", "reject")
+	v.SetDefault("performance.rate_limit.max_delay_ms", 1000)
+	v.SetDefault("performance.rate_limit.global.commands_per_second", 0)
+	v.SetDefault("performance.rate_limit.global.keystrokes_per_second", 0)
+	v.SetDefault("performance.rate_limit.global.commands_burst", 0)
+	v.SetDefault("performance.rate_limit.global.keystrokes_burst", 0)
+	v.SetDefault("performance.rate_limit.per_uid.commands_per_second", 0)
+	v.SetDefault("performance.rate_limit.per_uid.keystrokes_per_second", 0)
+	v.SetDefault("performance.rate_limit.per_uid.commands_burst", 0)
+	v.SetDefault("performance.rate_limit.per_uid.keystrokes_burst", 0)
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
@@ -0,0 +1,289 @@
+package detect
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rulesPath lists every XKB layout and variant with its description.
+// Compositors report the active layout by description, e.g.
+// "German (no dead keys)".
+const rulesPath = "/usr/share/X11/xkb/rules/evdev.lst"
+
+// ipcTimeout bounds a compositor IPC round trip.
+const ipcTimeout = 500 * time.Millisecond
+
+// errNoSocket is returned when the compositor's IPC socket is not found.
+var errNoSocket = errors.New("no IPC socket found")
+
+// swaySource asks sway for the active layout of its keyboards.
+type swaySource struct {
+	descriptions descriptionTable
+}
+
+func (*swaySource) Name() string { return SourceSway }
+
+// sway IPC message type for GET_INPUTS.
+const swayGetInputs = 100
+
+// swayMagic starts every sway (i3) IPC message.
+const swayMagic = "i3-ipc"
+
+func (s *swaySource) Detect(ctx context.Context) (Keymap, error) {
+	path, err := findSocket(os.Getenv("SWAYSOCK"), "/run/user/*/sway-ipc.*.sock")
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	payload, err := ipcRoundTrip(ctx, path, func(conn net.Conn) error {
+		header := make([]byte, len(swayMagic)+8)
+		copy(header, swayMagic)
+		binary.LittleEndian.PutUint32(header[len(swayMagic):], 0)
+		binary.LittleEndian.PutUint32(header[len(swayMagic)+4:], swayGetInputs)
+		_, err := conn.Write(header)
+		return err
+	}, readSwayReply)
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	desc, err := parseSwayInputs(payload)
+	if err != nil {
+		return Keymap{}, err
+	}
+	return s.descriptions.lookup(desc)
+}
+
+// readSwayReply reads a single sway IPC reply and returns its payload.
+func readSwayReply(r io.Reader) ([]byte, error) {
+	header := make([]byte, len(swayMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(swayMagic)]) != swayMagic {
+		return nil, fmt.Errorf("invalid sway IPC reply")
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header[len(swayMagic):]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// parseSwayInputs returns the active layout description of the first
+// keyboard in a GET_INPUTS reply.
+func parseSwayInputs(payload []byte) (string, error) {
+	var inputs []struct {
+		Type                string `json:"type"`
+		XKBActiveLayoutName string `json:"xkb_active_layout_name"`
+	}
+	if err := json.Unmarshal(payload, &inputs); err != nil {
+		return "", fmt.Errorf("invalid GET_INPUTS reply: %w", err)
+	}
+
+	for _, input := range inputs {
+		if input.Type == "keyboard" && input.XKBActiveLayoutName != "" {
+			return input.XKBActiveLayoutName, nil
+		}
+	}
+	return "", fmt.Errorf("no keyboard reported")
+}
+
+// hyprlandSource asks Hyprland for the active keymap of the main keyboard.
+type hyprlandSource struct {
+	descriptions descriptionTable
+}
+
+func (*hyprlandSource) Name() string { return SourceHyprland }
+
+func (s *hyprlandSource) Detect(ctx context.Context) (Keymap, error) {
+	var envPath string
+	if sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); sig != "" {
+		envPath = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "hypr", sig, ".socket.sock")
+	}
+
+	path, err := findSocket(envPath, "/run/user/*/hypr/*/.socket.sock", "/tmp/hypr/*/.socket.sock")
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	payload, err := ipcRoundTrip(ctx, path, func(conn net.Conn) error {
+		_, err := io.WriteString(conn, "j/devices")
+		return err
+	}, io.ReadAll)
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	desc, err := parseHyprlandDevices(payload)
+	if err != nil {
+		return Keymap{}, err
+	}
+	return s.descriptions.lookup(desc)
+}
+
+// parseHyprlandDevices returns the active keymap description of the main
+// keyboard (or the first one) in a j/devices reply.
+func parseHyprlandDevices(payload []byte) (string, error) {
+	var devices struct {
+		Keyboards []struct {
+			ActiveKeymap string `json:"active_keymap"`
+			Main         bool   `json:"main"`
+		} `json:"keyboards"`
+	}
+	if err := json.Unmarshal(payload, &devices); err != nil {
+		return "", fmt.Errorf("invalid devices reply: %w", err)
+	}
+
+	desc := ""
+	for _, kb := range devices.Keyboards {
+		if kb.ActiveKeymap == "" {
+			continue
+		}
+		if kb.Main {
+			return kb.ActiveKeymap, nil
+		}
+		if desc == "" {
+			desc = kb.ActiveKeymap
+		}
+	}
+	if desc == "" {
+		return "", fmt.Errorf("no keyboard reported")
+	}
+	return desc, nil
+}
+
+// findSocket returns envPath if set, otherwise the most recently created
+// socket matching one of the patterns. The daemon usually runs outside of
+// the user session, so the session's environment is not available.
+func findSocket(envPath string, patterns ...string) (string, error) {
+	if envPath != "" {
+		return envPath, nil
+	}
+
+	var newest string
+	var newestTime time.Time
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.Mode()&os.ModeSocket == 0 {
+				continue
+			}
+			if newest == "" || info.ModTime().After(newestTime) {
+				newest, newestTime = match, info.ModTime()
+			}
+		}
+	}
+
+	if newest == "" {
+		return "", errNoSocket
+	}
+	return newest, nil
+}
+
+// ipcRoundTrip sends a request on a Unix socket and reads the reply.
+func ipcRoundTrip(ctx context.Context, path string, write func(net.Conn) error, read func(io.Reader) ([]byte, error)) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, ipcTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := write(conn); err != nil {
+		return nil, err
+	}
+	return read(conn)
+}
+
+// descriptionTable maps layout descriptions to keymaps, loaded lazily
+// from the XKB rules list.
+type descriptionTable struct {
+	once  sync.Once
+	table map[string]Keymap
+	err   error
+}
+
+// lookup returns the keymap with the given description.
+func (d *descriptionTable) lookup(desc string) (Keymap, error) {
+	d.once.Do(func() {
+		f, err := os.Open(rulesPath)
+		if err != nil {
+			d.err = err
+			return
+		}
+		defer f.Close()
+		d.table, d.err = parseRulesList(f)
+	})
+	if d.err != nil {
+		return Keymap{}, fmt.Errorf("layout descriptions: %w", d.err)
+	}
+
+	km, ok := d.table[desc]
+	if !ok {
+		return Keymap{}, fmt.Errorf("unknown layout %q", desc)
+	}
+	return km, nil
+}
+
+// parseRulesList reads the layout and variant sections of an XKB rules
+// list (evdev.lst):
+//
+//	! layout
+//	  de              German
+//	! variant
+//	  nodeadkeys      de: German (no dead keys)
+func parseRulesList(r io.Reader) (map[string]Keymap, error) {
+	table := make(map[string]Keymap)
+
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "! "); ok {
+			section = strings.TrimSpace(name)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name := fields[0]
+		desc := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), name))
+
+		switch section {
+		case "layout":
+			table[desc] = Keymap{Layout: name}
+		case "variant":
+			layout, variantDesc, ok := strings.Cut(desc, ": ")
+			if !ok {
+				continue
+			}
+			table[variantDesc] = Keymap{Layout: layout, Variant: name}
+		}
+	}
+
+	return table, scanner.Err()
+}
@@ -0,0 +1,161 @@
+// Package detect finds the keyboard layout active in the desktop session,
+// so that typed text matches what the user's keymap expects.
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+)
+
+// Source names, in the default detection order.
+const (
+	SourceSway         = "sway"          // sway IPC (GET_INPUTS)
+	SourceHyprland     = "hyprland"      // Hyprland IPC (j/devices)
+	SourceEnv          = "env"           // XKB_DEFAULT_LAYOUT / XKB_DEFAULT_VARIANT
+	SourceLocalectl    = "localectl"     // localectl status
+	SourceKeyboardFile = "keyboard_file" // /etc/default/keyboard
+)
+
+// DefaultSources is the detection order used when none is configured.
+// Compositors come first since they know about group switches.
+var DefaultSources = []string{SourceSway, SourceHyprland, SourceEnv, SourceLocalectl, SourceKeyboardFile}
+
+// ErrNotDetected is returned when no source reports a layout.
+var ErrNotDetected = errors.New("active layout not detected")
+
+// Keymap is an XKB layout and variant, e.g. {"de", "nodeadkeys"}.
+type Keymap struct {
+	Layout  string
+	Variant string
+}
+
+// builtinNames maps XKB layouts to the built-in layout implementing them.
+var builtinNames = map[string]string{
+	"us": layouts.NameUS,
+	"gb": layouts.NameUK,
+	"fr": layouts.NameFR,
+	"de": layouts.NameDE,
+	"es": layouts.NameES,
+	"it": layouts.NameIT,
+}
+
+// RegistryName returns the name of the layout registry entry for the
+// keymap: a built-in layout for variant-less keymaps it covers, otherwise
+// an XKB layout ("xkb:de(nodeadkeys)").
+func (k Keymap) RegistryName() string {
+	if k.Variant == "" {
+		if name, ok := builtinNames[k.Layout]; ok {
+			return name
+		}
+		return layouts.XKBPrefix + k.Layout
+	}
+	return fmt.Sprintf("%s%s(%s)", layouts.XKBPrefix, k.Layout, k.Variant)
+}
+
+// String returns the keymap in XKB notation, e.g. "de(nodeadkeys)".
+func (k Keymap) String() string {
+	if k.Variant == "" {
+		return k.Layout
+	}
+	return k.Layout + "(" + k.Variant + ")"
+}
+
+// Source reports the keymap active in the desktop session.
+type Source interface {
+	Name() string
+	Detect(ctx context.Context) (Keymap, error)
+}
+
+// Detector tries its sources in order and caches the first result.
+type Detector struct {
+	sources []Source
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	cached   Keymap
+	cachedAt time.Time
+}
+
+// New creates a detector using the named sources in order.
+// Results are reused for ttl so that typing bursts do not query the
+// session for every command.
+func New(names []string, ttl time.Duration) (*Detector, error) {
+	if len(names) == 0 {
+		names = DefaultSources
+	}
+
+	sources := make([]Source, 0, len(names))
+	for _, name := range names {
+		source, err := newSource(name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	return NewWithSources(ttl, sources...), nil
+}
+
+// NewWithSources creates a detector from custom sources.
+func NewWithSources(ttl time.Duration, sources ...Source) *Detector {
+	return &Detector{sources: sources, ttl: ttl}
+}
+
+// newSource creates a built-in source by name.
+func newSource(name string) (Source, error) {
+	switch name {
+	case SourceSway:
+		return &swaySource{}, nil
+	case SourceHyprland:
+		return &hyprlandSource{}, nil
+	case SourceEnv:
+		return envSource{}, nil
+	case SourceLocalectl:
+		return localectlSource{}, nil
+	case SourceKeyboardFile:
+		return keyboardFileSource{path: keyboardFilePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown layout detection source: %s", name)
+	}
+}
+
+// Detect returns the active keymap from the first source that reports one.
+func (d *Detector) Detect(ctx context.Context) (Keymap, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.cachedAt.IsZero() && time.Since(d.cachedAt) < d.ttl {
+		return d.cached, nil
+	}
+
+	var errs []error
+	for _, source := range d.sources {
+		km, err := source.Detect(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		if km.Layout == "" {
+			continue
+		}
+
+		d.cached, d.cachedAt = km, time.Now()
+		return km, nil
+	}
+
+	return Keymap{}, fmt.Errorf("%w: %w", ErrNotDetected, errors.Join(errs...))
+}
+
+// firstKeymap returns the first entry of comma-separated XKB layout and
+// variant lists, as used by XKB_DEFAULT_LAYOUT and /etc/default/keyboard.
+func firstKeymap(layoutList, variantList string) Keymap {
+	layout, _, _ := strings.Cut(layoutList, ",")
+	variant, _, _ := strings.Cut(variantList, ",")
+	return Keymap{Layout: strings.TrimSpace(layout), Variant: strings.TrimSpace(variant)}
+}
@@ -0,0 +1,180 @@
+package detect
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staticSource reports a fixed keymap or error and counts its calls.
+type staticSource struct {
+	km    Keymap
+	err   error
+	calls int
+}
+
+func (s *staticSource) Name() string { return "static" }
+
+func (s *staticSource) Detect(ctx context.Context) (Keymap, error) {
+	s.calls++
+	return s.km, s.err
+}
+
+func TestDetectorOrderAndCache(t *testing.T) {
+	failing := &staticSource{err: errors.New("not running")}
+	working := &staticSource{km: Keymap{Layout: "de", Variant: "nodeadkeys"}}
+	unused := &staticSource{km: Keymap{Layout: "fr"}}
+
+	d := NewWithSources(time.Hour, failing, working, unused)
+
+	for i := 0; i < 2; i++ {
+		km, err := d.Detect(context.Background())
+		if err != nil {
+			t.Fatalf("Detect: %v", err)
+		}
+		if km != working.km {
+			t.Errorf("Detect = %v, want %v", km, working.km)
+		}
+	}
+
+	if working.calls != 1 {
+		t.Errorf("working source called %d times, want 1 (cached)", working.calls)
+	}
+	if unused.calls != 0 {
+		t.Errorf("source after a match called %d times", unused.calls)
+	}
+}
+
+func TestDetectorNotDetected(t *testing.T) {
+	d := NewWithSources(0, &staticSource{err: errors.New("not running")})
+	if _, err := d.Detect(context.Background()); !errors.Is(err, ErrNotDetected) {
+		t.Errorf("expected ErrNotDetected, got %v", err)
+	}
+}
+
+func TestNewUnknownSource(t *testing.T) {
+	if _, err := New([]string{"gnome"}, 0); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestKeymapRegistryName(t *testing.T) {
+	tests := map[Keymap]string{
+		{Layout: "us"}:                        "us",
+		{Layout: "gb"}:                        "uk",
+		{Layout: "pl"}:                        "xkb:pl",
+		{Layout: "us", Variant: "dvorak"}:     "xkb:us(dvorak)",
+		{Layout: "de", Variant: "nodeadkeys"}: "xkb:de(nodeadkeys)",
+	}
+	for km, want := range tests {
+		if got := km.RegistryName(); got != want {
+			t.Errorf("%v.RegistryName() = %q, want %q", km, got, want)
+		}
+	}
+}
+
+func TestParseLocalectl(t *testing.T) {
+	out := `   System Locale: LANG=de_DE.UTF-8
+       VC Keymap: de-latin1-nodeadkeys
+      X11 Layout: de,us
+       X11 Model: pc105
+     X11 Variant: nodeadkeys,
+`
+	km, err := parseLocalectl(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Keymap{Layout: "de", Variant: "nodeadkeys"}); km != want {
+		t.Errorf("got %v, want %v", km, want)
+	}
+
+	if _, err := parseLocalectl("   X11 Layout: n/a\n"); err == nil {
+		t.Error("expected error without X11 layout")
+	}
+}
+
+func TestKeyboardFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyboard")
+	content := "# KEYBOARD CONFIGURATION FILE\nXKBMODEL=\"pc105\"\nXKBLAYOUT=\"fr\"\nXKBVARIANT=\"\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	km, err := keyboardFileSource{path: path}.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Keymap{Layout: "fr"}); km != want {
+		t.Errorf("got %v, want %v", km, want)
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("XKB_DEFAULT_LAYOUT", "us,ru")
+	t.Setenv("XKB_DEFAULT_VARIANT", "colemak,")
+
+	km, err := envSource{}.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Keymap{Layout: "us", Variant: "colemak"}); km != want {
+		t.Errorf("got %v, want %v", km, want)
+	}
+}
+
+func TestParseRulesList(t *testing.T) {
+	list := `! model
+  pc105           Generic 105-key PC
+
+! layout
+  us              English (US)
+  de              German
+
+! variant
+  colemak         us: English (Colemak)
+  nodeadkeys      de: German (no dead keys)
+`
+	table, err := parseRulesList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]Keymap{
+		"English (US)":          {Layout: "us"},
+		"German":                {Layout: "de"},
+		"English (Colemak)":     {Layout: "us", Variant: "colemak"},
+		"German (no dead keys)": {Layout: "de", Variant: "nodeadkeys"},
+	}
+	for desc, want := range tests {
+		if got := table[desc]; got != want {
+			t.Errorf("%q: got %v, want %v", desc, got, want)
+		}
+	}
+	if _, ok := table["Generic 105-key PC"]; ok {
+		t.Error("models must not be listed")
+	}
+}
+
+func TestParseCompositorReplies(t *testing.T) {
+	sway := `[
+		{"identifier": "1:1:mouse", "type": "pointer"},
+		{"identifier": "1:1:kbd", "type": "keyboard", "xkb_active_layout_name": "German (no dead keys)"}
+	]`
+	desc, err := parseSwayInputs([]byte(sway))
+	if err != nil || desc != "German (no dead keys)" {
+		t.Errorf("sway: got %q, %v", desc, err)
+	}
+
+	hyprland := `{"mice": [], "keyboards": [
+		{"name": "power-button", "active_keymap": "English (US)", "main": false},
+		{"name": "at-keyboard", "active_keymap": "German", "main": true}
+	]}`
+	desc, err = parseHyprlandDevices([]byte(hyprland))
+	if err != nil || desc != "German" {
+		t.Errorf("hyprland: got %q, %v", desc, err)
+	}
+}
@@ -0,0 +1,103 @@
+package detect
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keyboardFilePath is the Debian-style keyboard configuration file.
+const keyboardFilePath = "/etc/default/keyboard"
+
+// envSource reads XKB_DEFAULT_LAYOUT and XKB_DEFAULT_VARIANT from the
+// daemon's environment.
+type envSource struct{}
+
+func (envSource) Name() string { return SourceEnv }
+
+func (envSource) Detect(ctx context.Context) (Keymap, error) {
+	layout := os.Getenv("XKB_DEFAULT_LAYOUT")
+	if layout == "" {
+		return Keymap{}, fmt.Errorf("XKB_DEFAULT_LAYOUT not set")
+	}
+	return firstKeymap(layout, os.Getenv("XKB_DEFAULT_VARIANT")), nil
+}
+
+// localectlSource reads the X11 keymap from systemd-localed.
+type localectlSource struct{}
+
+func (localectlSource) Name() string { return SourceLocalectl }
+
+func (localectlSource) Detect(ctx context.Context) (Keymap, error) {
+	out, err := exec.CommandContext(ctx, "localectl", "status").Output()
+	if err != nil {
+		return Keymap{}, err
+	}
+	return parseLocalectl(string(out))
+}
+
+// parseLocalectl extracts the keymap from `localectl status` output:
+//
+//	X11 Layout: de
+//	X11 Variant: nodeadkeys
+func parseLocalectl(out string) (Keymap, error) {
+	var layout, variant string
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "X11 Layout":
+			layout = strings.TrimSpace(value)
+		case "X11 Variant":
+			variant = strings.TrimSpace(value)
+		}
+	}
+
+	if layout == "" || layout == "n/a" {
+		return Keymap{}, fmt.Errorf("no X11 layout configured")
+	}
+	return firstKeymap(layout, variant), nil
+}
+
+// keyboardFileSource reads XKBLAYOUT and XKBVARIANT from a shell-style
+// keyboard configuration file.
+type keyboardFileSource struct {
+	path string
+}
+
+func (keyboardFileSource) Name() string { return SourceKeyboardFile }
+
+func (s keyboardFileSource) Detect(ctx context.Context) (Keymap, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return Keymap{}, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		vars[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return Keymap{}, err
+	}
+
+	if vars["XKBLAYOUT"] == "" {
+		return Keymap{}, fmt.Errorf("%s: XKBLAYOUT not set", s.path)
+	}
+	return firstKeymap(vars["XKBLAYOUT"], vars["XKBVARIANT"]), nil
+}
@@ -118,8 +118,9 @@ func InstallDaemon(daemonBinary, configData []byte) error {
 	return nil
 }
 
-// InstallSystemdService installs the systemd service file
-func InstallSystemdService(serviceData []byte) error {
+// InstallSystemdService installs the systemd service and socket units. The
+// socket unit starts the daemon on the first connection when enabled.
+func InstallSystemdService(serviceData, socketData []byte) error {
 	// Check if daemon is installed
 	daemonPath := "/usr/local/bin/uinputd"
 	if _, err := os.Stat(daemonPath); os.IsNotExist(err) {
@@ -132,6 +133,12 @@ func InstallSystemdService(serviceData []byte) error {
 		return fmt.Errorf("failed to write service file: %w", err)
 	}
 
+	// Write socket unit
+	socketPath := "/etc/systemd/system/uinputd.socket"
+	if err := os.WriteFile(socketPath, socketData, 0644); err != nil {
+		return fmt.Errorf("failed to write socket unit: %w", err)
+	}
+
 	// Reload systemd
 	cmd := exec.Command("systemctl", "daemon-reload")
 	if output, err := cmd.CombinedOutput(); err != nil {
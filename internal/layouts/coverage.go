@@ -0,0 +1,122 @@
+package layouts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Charset is a named range of code points to measure layout coverage
+// against. Only graphic characters of the range are included.
+type Charset struct {
+	Name        string
+	Description string
+	First, Last rune
+}
+
+// Charsets lists the charsets known by name, in code point order.
+var Charsets = []Charset{
+	{Name: "ascii", Description: "Basic Latin (printable ASCII)", First: 0x20, Last: 0x7e},
+	{Name: "latin1", Description: "Latin-1 Supplement", First: 0xa0, Last: 0xff},
+	{Name: "latin-ext-a", Description: "Latin Extended-A", First: 0x100, Last: 0x17f},
+	{Name: "latin-ext-b", Description: "Latin Extended-B", First: 0x180, Last: 0x24f},
+	{Name: "greek", Description: "Greek and Coptic", First: 0x370, Last: 0x3ff},
+	{Name: "cyrillic", Description: "Cyrillic", First: 0x400, Last: 0x4ff},
+	{Name: "punctuation", Description: "General Punctuation", First: 0x2000, Last: 0x206f},
+	{Name: "currency", Description: "Currency Symbols", First: 0x20a0, Last: 0x20cf},
+}
+
+// LookupCharset returns the charset with the given name.
+func LookupCharset(name string) (Charset, error) {
+	for _, cs := range Charsets {
+		if cs.Name == name {
+			return cs, nil
+		}
+	}
+
+	names := make([]string, len(Charsets))
+	for i, cs := range Charsets {
+		names[i] = cs.Name
+	}
+	return Charset{}, fmt.Errorf("unknown charset %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Chars returns the graphic characters of the charset.
+func (cs Charset) Chars() []rune {
+	var chars []rune
+	for char := cs.First; char <= cs.Last; char++ {
+		if unicode.IsGraphic(char) {
+			chars = append(chars, char)
+		}
+	}
+	return chars
+}
+
+// TextChars returns the distinct characters of text in code point order.
+// Control characters other than tab and newline are ignored.
+func TextChars(text string) []rune {
+	seen := make(map[rune]bool)
+	var chars []rune
+	for _, char := range text {
+		if seen[char] || (unicode.IsControl(char) && char != '\t' && char != '\n') {
+			continue
+		}
+		seen[char] = true
+		chars = append(chars, char)
+	}
+
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+	return chars
+}
+
+// Mapping is a character and the keystrokes typing it.
+type Mapping struct {
+	Char rune
+	Keys []KeySequence
+}
+
+// Dump returns every character of the Basic Multilingual Plane that the
+// layout can type, with its keystrokes, in code point order. Layouts only
+// map characters, so the plane is scanned.
+func Dump(ctx context.Context, layout Layout) []Mapping {
+	var mappings []Mapping
+	for char := rune(0); char <= 0xffff; char++ {
+		if char >= 0xd800 && char <= 0xdfff {
+			continue // Surrogates are not characters
+		}
+		if keys, err := layout.CharToKeySequence(ctx, char); err == nil {
+			mappings = append(mappings, Mapping{Char: char, Keys: keys})
+		}
+	}
+	return mappings
+}
+
+// CoverageReport tells how many characters of a set a layout can type.
+type CoverageReport struct {
+	Total   int
+	Covered int
+	Missing []rune // In the order of the set
+}
+
+// Percent returns the share of characters covered, 100 for an empty set.
+func (r CoverageReport) Percent() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return float64(r.Covered) * 100 / float64(r.Total)
+}
+
+// Coverage checks which of chars the layout can type.
+func Coverage(ctx context.Context, layout Layout, chars []rune) CoverageReport {
+	report := CoverageReport{Total: len(chars)}
+	for _, char := range chars {
+		if _, err := layout.CharToKeySequence(ctx, char); err != nil {
+			report.Missing = append(report.Missing, char)
+			continue
+		}
+		report.Covered++
+	}
+	return report
+}
@@ -0,0 +1,68 @@
+package layouts
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+func TestCoverage(t *testing.T) {
+	ctx := context.Background()
+
+	ascii, err := LookupCharset("ascii")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := Coverage(ctx, NewUS(), ascii.Chars()); report.Total != 95 || report.Percent() != 100 {
+		t.Errorf("US ASCII coverage = %d/%d %v, want 95/95", report.Covered, report.Total, report.Missing)
+	}
+
+	latin1, err := LookupCharset("latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	us, fr := Coverage(ctx, NewUS(), latin1.Chars()), Coverage(ctx, NewFR(), latin1.Chars())
+	if fr.Covered <= us.Covered {
+		t.Errorf("FR Latin-1 coverage %d should exceed US coverage %d", fr.Covered, us.Covered)
+	}
+	if us.Covered+len(us.Missing) != us.Total {
+		t.Errorf("covered %d + missing %d != total %d", us.Covered, len(us.Missing), us.Total)
+	}
+
+	report := Coverage(ctx, NewUS(), TextChars("déjà vu\x07"))
+	if want := []rune{'à', 'é'}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("missing = %q, want %q", report.Missing, want)
+	}
+	if report.Total != 7 {
+		t.Errorf("total = %d, want 7 distinct characters", report.Total)
+	}
+
+	if _, err := LookupCharset("klingon"); err == nil {
+		t.Error("expected an error for an unknown charset")
+	}
+}
+
+func TestDump(t *testing.T) {
+	mappings := Dump(context.Background(), NewUS())
+
+	found := false
+	for i, m := range mappings {
+		if i > 0 && m.Char <= mappings[i-1].Char {
+			t.Fatalf("mappings not in code point order at %q", m.Char)
+		}
+		if m.Char == 'é' {
+			t.Errorf("US layout should not map %q", m.Char)
+		}
+		if m.Char == 'A' {
+			found = true
+			if want := []KeySequence{{Keycode: uinput.KeyA, Modifier: ModShift}}; !reflect.DeepEqual(m.Keys, want) {
+				t.Errorf("keys of 'A' = %v, want %v", m.Keys, want)
+			}
+		}
+	}
+	if !found {
+		t.Error("US layout dump is missing 'A'")
+	}
+}
@@ -0,0 +1,358 @@
+package layouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+	"gopkg.in/yaml.v3"
+)
+
+// LayoutFileExtensions lists the file extensions loaded as layout files.
+// JSON is valid YAML, so both are parsed the same way.
+var LayoutFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// FileLayout implements a user-defined layout loaded from a YAML or JSON file.
+//
+// Example:
+//
+//	name: acme
+//	mappings:
+//	  "a": a                  # key name or chord, see uinput.KeyNames
+//	  "A": shift+a
+//	  "@": { key: 2, modifiers: [altgr] }
+//	  "ß": { keycode: 12 }
+//	dead_keys:
+//	  "^": leftbrace
+//	compositions:           # added to CommonDeadKeyCompositions
+//	  - { dead_key: "^", base: "w", result: "ŵ" }
+//
+// Space, tab and newline default to their usual keys.
+type FileLayout struct {
+	name            string
+	baseMappings    map[rune]KeyMapping
+	deadKeyRegistry DeadKeyRegistry
+	deadKeys        map[rune]KeyMapping
+}
+
+// LayoutFileError reports an invalid entry of a layout file.
+type LayoutFileError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *LayoutFileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// LoadLayoutFile reads and parses a layout file.
+func LoadLayoutFile(path string) (*FileLayout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLayoutFile(path, data)
+}
+
+// ParseLayoutFile parses a layout definition. Without a name field, the
+// layout is named after the file. The name must not be that of a built-in
+// layout. Every invalid entry is reported as a *LayoutFileError, joined
+// with errors.Join.
+func ParseLayoutFile(filename string, data []byte) (*FileLayout, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	p := &layoutFileParser{file: filename}
+	l := &FileLayout{
+		name: strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)),
+		baseMappings: map[rune]KeyMapping{
+			' ':  {Keycode: uinput.KeySpace, Modifier: ModNone},
+			'\t': {Keycode: uinput.KeyTab, Modifier: ModNone},
+			'\n': {Keycode: uinput.KeyEnter, Modifier: ModNone},
+		},
+		deadKeyRegistry: BuildDeadKeyRegistry(),
+		deadKeys:        make(map[rune]KeyMapping),
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		p.errorf(&doc, "expected a mapping with name, mappings, dead_keys and compositions")
+		return nil, p.err()
+	}
+
+	var compositions []DeadKeyComposition
+	var deadKeyLines map[rune]int
+	var nameNode *yaml.Node
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+
+		switch key.Value {
+		case "name":
+			if value.Kind != yaml.ScalarNode || value.Value == "" {
+				p.errorf(value, "name must be a non-empty string")
+				continue
+			}
+			if strings.HasPrefix(value.Value, XKBPrefix) {
+				p.errorf(value, "name must not start with %q", XKBPrefix)
+				continue
+			}
+			l.name, nameNode = value.Value, value
+		case "description":
+			// Informational only
+		case "mappings":
+			p.mappings(value, l.baseMappings)
+		case "dead_keys":
+			deadKeyLines = p.mappings(value, l.deadKeys)
+		case "compositions":
+			compositions = p.compositions(value)
+		default:
+			p.errorf(key, "unknown field %q", key.Value)
+		}
+	}
+
+	if slices.Contains(builtinNames, l.name) {
+		if nameNode != nil {
+			p.errorf(nameNode, "name %q is a built-in layout", l.name)
+		} else {
+			p.errorf(root, "file name %q is a built-in layout, set a different name", l.name)
+		}
+	}
+
+	for _, comp := range compositions {
+		l.deadKeyRegistry[comp.Result] = comp
+	}
+
+	// Every dead key must take part in at least one composition
+	for dead, line := range deadKeyLines {
+		used := false
+		for _, comp := range l.deadKeyRegistry {
+			if comp.DeadKey == dead {
+				used = true
+				break
+			}
+		}
+		if !used {
+			p.errorf(&yaml.Node{Line: line}, "dead key %q has no compositions", dead)
+		}
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Name returns the layout name.
+func (l *FileLayout) Name() string {
+	return l.name
+}
+
+// CharToKeySequence converts a Unicode character to a sequence of keystrokes.
+func (l *FileLayout) CharToKeySequence(ctx context.Context, char rune) ([]KeySequence, error) {
+	// First, check if it's a direct mapping
+	if mapping, ok := l.baseMappings[char]; ok {
+		return []KeySequence{{Keycode: mapping.Keycode, Modifier: mapping.Modifier}}, nil
+	}
+
+	// Check if it needs a dead key combination
+	if comp, ok := l.deadKeyRegistry[char]; ok {
+		deadKeyMapping, hasDead := l.deadKeys[comp.DeadKey]
+		if !hasDead {
+			return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+		}
+
+		baseMapping, hasBase := l.baseMappings[comp.BaseChar]
+		if !hasBase {
+			return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+		}
+
+		return []KeySequence{
+			{Keycode: deadKeyMapping.Keycode, Modifier: deadKeyMapping.Modifier},
+			{Keycode: baseMapping.Keycode, Modifier: baseMapping.Modifier},
+		}, nil
+	}
+
+	return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+}
+
+// layoutFileModifiers maps the modifier names of layout files to modifiers.
+// Only modifiers the daemon can type with are accepted.
+var layoutFileModifiers = map[string]Modifier{
+	"shift": ModShift,
+	"altgr": ModAltGr,
+}
+
+// layoutFileKey is the long form of a mapping entry.
+type layoutFileKey struct {
+	Key       string   `yaml:"key"`
+	Keycode   uint16   `yaml:"keycode"`
+	Modifiers []string `yaml:"modifiers"`
+}
+
+// layoutFileComposition is an entry of the compositions list.
+type layoutFileComposition struct {
+	DeadKey string `yaml:"dead_key"`
+	Base    string `yaml:"base"`
+	Result  string `yaml:"result"`
+}
+
+// layoutFileParser collects the errors of a layout file with their lines.
+type layoutFileParser struct {
+	file string
+	errs []error
+}
+
+// errorf records an error at the line of node.
+func (p *layoutFileParser) errorf(node *yaml.Node, format string, args ...any) {
+	line := node.Line
+	if line == 0 {
+		line = 1
+	}
+	p.errs = append(p.errs, &LayoutFileError{File: p.file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+// err returns the recorded errors in line order, if any.
+func (p *layoutFileParser) err() error {
+	slices.SortStableFunc(p.errs, func(a, b error) int {
+		return a.(*LayoutFileError).Line - b.(*LayoutFileError).Line
+	})
+	return errors.Join(p.errs...)
+}
+
+// mappings parses a character-to-key mapping section into dst and returns
+// the line of each character. Entries replace the defaults of dst, but a
+// character may only be listed once.
+func (p *layoutFileParser) mappings(node *yaml.Node, dst map[rune]KeyMapping) map[rune]int {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "expected a mapping of characters to keys")
+		return nil
+	}
+
+	seen := make(map[rune]int)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		char, ok := p.char(key)
+		if !ok {
+			continue
+		}
+		if line, dup := seen[char]; dup {
+			p.errorf(key, "character %q already mapped at line %d", char, line)
+			continue
+		}
+		seen[char] = key.Line
+
+		if mapping, ok := p.keyMapping(value); ok {
+			dst[char] = mapping
+		}
+	}
+	return seen
+}
+
+// compositions parses the list of additional dead key compositions.
+func (p *layoutFileParser) compositions(node *yaml.Node) []DeadKeyComposition {
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "expected a list of compositions")
+		return nil
+	}
+
+	comps := make([]DeadKeyComposition, 0, len(node.Content))
+	for _, item := range node.Content {
+		var c layoutFileComposition
+		if err := item.Decode(&c); err != nil {
+			p.errorf(item, "invalid composition: %v", err)
+			continue
+		}
+
+		dead, okDead := singleRune(c.DeadKey)
+		base, okBase := singleRune(c.Base)
+		result, okResult := singleRune(c.Result)
+		if !okDead || !okBase || !okResult {
+			p.errorf(item, "dead_key, base and result must each be a single character")
+			continue
+		}
+		comps = append(comps, DeadKeyComposition{DeadKey: dead, BaseChar: base, Result: result})
+	}
+	return comps
+}
+
+// char parses a mapping key holding a single character.
+func (p *layoutFileParser) char(node *yaml.Node) (rune, bool) {
+	char, ok := singleRune(node.Value)
+	if node.Kind != yaml.ScalarNode || !ok {
+		p.errorf(node, "%q is not a single character", node.Value)
+		return 0, false
+	}
+	return char, true
+}
+
+// keyMapping parses a key, written either as a chord ("shift+a") or as
+// {key|keycode, modifiers}.
+func (p *layoutFileParser) keyMapping(node *yaml.Node) (KeyMapping, bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(node.Value, "+")
+		return p.resolveKey(node, parts[len(parts)-1], 0, parts[:len(parts)-1])
+	case yaml.MappingNode:
+		var k layoutFileKey
+		if err := node.Decode(&k); err != nil {
+			p.errorf(node, "invalid key: %v", err)
+			return KeyMapping{}, false
+		}
+		if k.Key != "" && k.Keycode != 0 {
+			p.errorf(node, "key and keycode are mutually exclusive")
+			return KeyMapping{}, false
+		}
+		return p.resolveKey(node, k.Key, k.Keycode, k.Modifiers)
+	default:
+		p.errorf(node, "expected a key name, a chord or a mapping")
+		return KeyMapping{}, false
+	}
+}
+
+// resolveKey builds a mapping from a key name or keycode and modifier names.
+func (p *layoutFileParser) resolveKey(node *yaml.Node, name string, keycode uint16, modifiers []string) (KeyMapping, bool) {
+	mapping := KeyMapping{Keycode: keycode}
+
+	if name != "" {
+		code, ok := uinput.LookupKey(name)
+		if !ok {
+			p.errorf(node, "unknown key %q", name)
+			return KeyMapping{}, false
+		}
+		mapping.Keycode = code
+	}
+	if mapping.Keycode == 0 {
+		p.errorf(node, "key or keycode is required")
+		return KeyMapping{}, false
+	}
+
+	for _, name := range modifiers {
+		mod, ok := layoutFileModifiers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			p.errorf(node, "unsupported modifier %q (use shift or altgr)", name)
+			return KeyMapping{}, false
+		}
+		mapping.Modifier |= mod
+	}
+
+	return mapping, true
+}
+
+// singleRune returns the only character of s.
+func singleRune(s string) (rune, bool) {
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, r != utf8.RuneError
+}
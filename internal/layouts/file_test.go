@@ -0,0 +1,200 @@
+package layouts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+const testLayoutFile = `name: acme
+description: Team layout
+mappings:
+  "a": a
+  "A": shift+a
+  "@": { key: 2, modifiers: [altgr] }
+  "ß": { keycode: 12 }
+  "w": w
+  "o": o
+dead_keys:
+  "^": leftbrace
+compositions:
+  - { dead_key: "^", base: "w", result: "ŵ" }
+`
+
+func TestFileLayout(t *testing.T) {
+	layout, err := ParseLayoutFile("acme.yaml", []byte(testLayoutFile))
+	if err != nil {
+		t.Fatalf("ParseLayoutFile: %v", err)
+	}
+	if layout.Name() != "acme" {
+		t.Errorf("Name() = %q, want acme", layout.Name())
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		char     rune
+		expected []KeySequence
+		desc     string
+	}{
+		{'a', []KeySequence{{Keycode: uinput.KeyA, Modifier: ModNone}}, "key name"},
+		{'A', []KeySequence{{Keycode: uinput.KeyA, Modifier: ModShift}}, "chord"},
+		{'@', []KeySequence{{Keycode: uinput.Key2, Modifier: ModAltGr}}, "long form"},
+		{'ß', []KeySequence{{Keycode: uinput.KeyMinus, Modifier: ModNone}}, "keycode"},
+		{' ', []KeySequence{{Keycode: uinput.KeySpace, Modifier: ModNone}}, "default space"},
+		{
+			'ŵ',
+			[]KeySequence{
+				{Keycode: uinput.KeyLeftBrace, Modifier: ModNone},
+				{Keycode: uinput.KeyW, Modifier: ModNone},
+			},
+			"custom composition",
+		},
+		{
+			'ô',
+			[]KeySequence{
+				{Keycode: uinput.KeyLeftBrace, Modifier: ModNone},
+				{Keycode: uinput.KeyO, Modifier: ModNone},
+			},
+			"common composition",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			seq, err := layout.CharToKeySequence(ctx, tt.char)
+			if err != nil {
+				t.Fatalf("char %q not supported: %v", tt.char, err)
+			}
+			if len(seq) != len(tt.expected) {
+				t.Fatalf("char %q: got %d keystrokes, want %d", tt.char, len(seq), len(tt.expected))
+			}
+			for i := range seq {
+				if seq[i] != tt.expected[i] {
+					t.Errorf("char %q keystroke[%d]: got %+v, want %+v", tt.char, i, seq[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFileLayoutJSON(t *testing.T) {
+	layout, err := ParseLayoutFile("team.json", []byte(`{"mappings": {"x": "x", "X": "shift+x"}}`))
+	if err != nil {
+		t.Fatalf("ParseLayoutFile: %v", err)
+	}
+	if layout.Name() != "team" {
+		t.Errorf("Name() = %q, want name from file", layout.Name())
+	}
+	if _, err := layout.CharToKeySequence(context.Background(), 'X'); err != nil {
+		t.Errorf("X not supported: %v", err)
+	}
+}
+
+func TestFileLayoutErrors(t *testing.T) {
+	src := `name: broken
+mappings:
+  "ab": a
+  "b": nokey
+  "c": ctrl+c
+  "d": { key: d, keycode: 32 }
+  "b": b
+dead_keys:
+  "%": leftbrace
+colour: blue
+`
+	_, err := ParseLayoutFile("broken.yaml", []byte(src))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+
+	wantLines := []int{3, 4, 5, 6, 7, 9, 10}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	errs := joined.Unwrap()
+	if len(errs) != len(wantLines) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(wantLines), err)
+	}
+
+	for i, e := range errs {
+		var fe *LayoutFileError
+		if !errors.As(e, &fe) {
+			t.Fatalf("error %d: not a LayoutFileError: %v", i, e)
+		}
+		if fe.Line != wantLines[i] {
+			t.Errorf("error %d: line %d, want %d (%v)", i, fe.Line, wantLines[i], fe)
+		}
+	}
+}
+
+func TestFileLayoutBuiltinName(t *testing.T) {
+	tests := []struct {
+		file string
+		src  string
+		line int
+	}{
+		{"acme.yaml", "mappings:\n  \"a\": a\nname: fr\n", 3},
+		{"us.yaml", "mappings:\n  \"a\": a\n", 1},
+	}
+	for _, tt := range tests {
+		_, err := ParseLayoutFile(tt.file, []byte(tt.src))
+
+		var fe *LayoutFileError
+		if !errors.As(err, &fe) {
+			t.Errorf("%s: expected a LayoutFileError, got %v", tt.file, err)
+			continue
+		}
+		if fe.Line != tt.line || !strings.Contains(fe.Msg, "built-in") {
+			t.Errorf("%s: got %v, want a built-in name error on line %d", tt.file, fe, tt.line)
+		}
+	}
+}
+
+func TestRegistryLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"acme.yaml":  testLayoutFile,
+		"team.json":  `{"name": "team", "mappings": {"x": "x"}}`,
+		"bad.yml":    "mappings: [",
+		"us.yaml":    `{"mappings": {"x": "y"}}`,
+		"README.txt": "not a layout",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRegistry()
+	names, err := r.LoadDir(dir)
+	if err == nil {
+		t.Error("expected errors for bad.yml and us.yaml")
+	}
+	if len(names) != 2 {
+		t.Errorf("loaded %v, want acme and team", names)
+	}
+
+	for _, name := range []string{"acme", "team"} {
+		if _, err := r.Get(name); err != nil {
+			t.Errorf("Get(%q): %v", name, err)
+		}
+	}
+
+	// The built-in layout is kept
+	if us, _ := r.Get(NameUS); us == nil {
+		t.Error("Get(us): built-in layout missing")
+	} else if _, ok := us.(*FileLayout); ok {
+		t.Error("us.yaml replaced the built-in layout")
+	}
+
+	if names, err := r.LoadDir(filepath.Join(dir, "missing")); err != nil || names != nil {
+		t.Errorf("missing dir: got %v, %v", names, err)
+	}
+}
@@ -0,0 +1,608 @@
+package layouts
+
+// keysymRunes maps X11 keysym names to the Unicode character they type.
+// Generated from X11/keysymdef.h for the Latin, currency, Cyrillic, Greek,
+// publishing and technical sets. Single-character names (letters and
+// digits) resolve to themselves and are not listed; other keysyms can be
+// written as Unicode keysyms (U20AC) in symbols files.
+var keysymRunes = map[string]rune{
+	// Latin-1
+	"space":          0x0020,
+	"exclam":         0x0021,
+	"quotedbl":       0x0022,
+	"numbersign":     0x0023,
+	"dollar":         0x0024,
+	"percent":        0x0025,
+	"ampersand":      0x0026,
+	"apostrophe":     0x0027,
+	"parenleft":      0x0028,
+	"parenright":     0x0029,
+	"asterisk":       0x002A,
+	"plus":           0x002B,
+	"comma":          0x002C,
+	"minus":          0x002D,
+	"period":         0x002E,
+	"slash":          0x002F,
+	"colon":          0x003A,
+	"semicolon":      0x003B,
+	"less":           0x003C,
+	"equal":          0x003D,
+	"greater":        0x003E,
+	"question":       0x003F,
+	"at":             0x0040,
+	"bracketleft":    0x005B,
+	"backslash":      0x005C,
+	"bracketright":   0x005D,
+	"asciicircum":    0x005E,
+	"underscore":     0x005F,
+	"grave":          0x0060,
+	"braceleft":      0x007B,
+	"bar":            0x007C,
+	"braceright":     0x007D,
+	"asciitilde":     0x007E,
+	"nobreakspace":   0x00A0,
+	"exclamdown":     0x00A1,
+	"cent":           0x00A2,
+	"sterling":       0x00A3,
+	"currency":       0x00A4,
+	"yen":            0x00A5,
+	"brokenbar":      0x00A6,
+	"section":        0x00A7,
+	"diaeresis":      0x00A8,
+	"copyright":      0x00A9,
+	"ordfeminine":    0x00AA,
+	"guillemotleft":  0x00AB,
+	"notsign":        0x00AC,
+	"hyphen":         0x00AD,
+	"registered":     0x00AE,
+	"macron":         0x00AF,
+	"degree":         0x00B0,
+	"plusminus":      0x00B1,
+	"twosuperior":    0x00B2,
+	"threesuperior":  0x00B3,
+	"acute":          0x00B4,
+	"mu":             0x00B5,
+	"paragraph":      0x00B6,
+	"periodcentered": 0x00B7,
+	"cedilla":        0x00B8,
+	"onesuperior":    0x00B9,
+	"masculine":      0x00BA,
+	"guillemotright": 0x00BB,
+	"onequarter":     0x00BC,
+	"onehalf":        0x00BD,
+	"threequarters":  0x00BE,
+	"questiondown":   0x00BF,
+	"Agrave":         0x00C0,
+	"Aacute":         0x00C1,
+	"Acircumflex":    0x00C2,
+	"Atilde":         0x00C3,
+	"Adiaeresis":     0x00C4,
+	"Aring":          0x00C5,
+	"AE":             0x00C6,
+	"Ccedilla":       0x00C7,
+	"Egrave":         0x00C8,
+	"Eacute":         0x00C9,
+	"Ecircumflex":    0x00CA,
+	"Ediaeresis":     0x00CB,
+	"Igrave":         0x00CC,
+	"Iacute":         0x00CD,
+	"Icircumflex":    0x00CE,
+	"Idiaeresis":     0x00CF,
+	"ETH":            0x00D0,
+	"Ntilde":         0x00D1,
+	"Ograve":         0x00D2,
+	"Oacute":         0x00D3,
+	"Ocircumflex":    0x00D4,
+	"Otilde":         0x00D5,
+	"Odiaeresis":     0x00D6,
+	"multiply":       0x00D7,
+	"Oslash":         0x00D8,
+	"Ooblique":       0x00D8,
+	"Ugrave":         0x00D9,
+	"Uacute":         0x00DA,
+	"Ucircumflex":    0x00DB,
+	"Udiaeresis":     0x00DC,
+	"Yacute":         0x00DD,
+	"THORN":          0x00DE,
+	"ssharp":         0x00DF,
+	"agrave":         0x00E0,
+	"aacute":         0x00E1,
+	"acircumflex":    0x00E2,
+	"atilde":         0x00E3,
+	"adiaeresis":     0x00E4,
+	"aring":          0x00E5,
+	"ae":             0x00E6,
+	"ccedilla":       0x00E7,
+	"egrave":         0x00E8,
+	"eacute":         0x00E9,
+	"ecircumflex":    0x00EA,
+	"ediaeresis":     0x00EB,
+	"igrave":         0x00EC,
+	"iacute":         0x00ED,
+	"icircumflex":    0x00EE,
+	"idiaeresis":     0x00EF,
+	"eth":            0x00F0,
+	"ntilde":         0x00F1,
+	"ograve":         0x00F2,
+	"oacute":         0x00F3,
+	"ocircumflex":    0x00F4,
+	"otilde":         0x00F5,
+	"odiaeresis":     0x00F6,
+	"division":       0x00F7,
+	"oslash":         0x00F8,
+	"ooblique":       0x00F8,
+	"ugrave":         0x00F9,
+	"uacute":         0x00FA,
+	"ucircumflex":    0x00FB,
+	"udiaeresis":     0x00FC,
+	"yacute":         0x00FD,
+	"thorn":          0x00FE,
+	"ydiaeresis":     0x00FF,
+
+	// Latin-2
+	"Aogonek":      0x0104,
+	"breve":        0x02D8,
+	"Lstroke":      0x0141,
+	"Lcaron":       0x013D,
+	"Sacute":       0x015A,
+	"Scaron":       0x0160,
+	"Scedilla":     0x015E,
+	"Tcaron":       0x0164,
+	"Zacute":       0x0179,
+	"Zcaron":       0x017D,
+	"Zabovedot":    0x017B,
+	"aogonek":      0x0105,
+	"ogonek":       0x02DB,
+	"lstroke":      0x0142,
+	"lcaron":       0x013E,
+	"sacute":       0x015B,
+	"caron":        0x02C7,
+	"scaron":       0x0161,
+	"scedilla":     0x015F,
+	"tcaron":       0x0165,
+	"zacute":       0x017A,
+	"doubleacute":  0x02DD,
+	"zcaron":       0x017E,
+	"zabovedot":    0x017C,
+	"Racute":       0x0154,
+	"Abreve":       0x0102,
+	"Lacute":       0x0139,
+	"Cacute":       0x0106,
+	"Ccaron":       0x010C,
+	"Eogonek":      0x0118,
+	"Ecaron":       0x011A,
+	"Dcaron":       0x010E,
+	"Dstroke":      0x0110,
+	"Nacute":       0x0143,
+	"Ncaron":       0x0147,
+	"Odoubleacute": 0x0150,
+	"Rcaron":       0x0158,
+	"Uring":        0x016E,
+	"Udoubleacute": 0x0170,
+	"Tcedilla":     0x0162,
+	"racute":       0x0155,
+	"abreve":       0x0103,
+	"lacute":       0x013A,
+	"cacute":       0x0107,
+	"ccaron":       0x010D,
+	"eogonek":      0x0119,
+	"ecaron":       0x011B,
+	"dcaron":       0x010F,
+	"dstroke":      0x0111,
+	"nacute":       0x0144,
+	"ncaron":       0x0148,
+	"odoubleacute": 0x0151,
+	"rcaron":       0x0159,
+	"uring":        0x016F,
+	"udoubleacute": 0x0171,
+	"tcedilla":     0x0163,
+	"abovedot":     0x02D9,
+
+	// Latin-3
+	"Hstroke":     0x0126,
+	"Hcircumflex": 0x0124,
+	"Iabovedot":   0x0130,
+	"Gbreve":      0x011E,
+	"Jcircumflex": 0x0134,
+	"hstroke":     0x0127,
+	"hcircumflex": 0x0125,
+	"idotless":    0x0131,
+	"gbreve":      0x011F,
+	"jcircumflex": 0x0135,
+	"Cabovedot":   0x010A,
+	"Ccircumflex": 0x0108,
+	"Gabovedot":   0x0120,
+	"Gcircumflex": 0x011C,
+	"Ubreve":      0x016C,
+	"Scircumflex": 0x015C,
+	"cabovedot":   0x010B,
+	"ccircumflex": 0x0109,
+	"gabovedot":   0x0121,
+	"gcircumflex": 0x011D,
+	"ubreve":      0x016D,
+	"scircumflex": 0x015D,
+
+	// Latin-4
+	"kra":       0x0138,
+	"Rcedilla":  0x0156,
+	"Itilde":    0x0128,
+	"Lcedilla":  0x013B,
+	"Emacron":   0x0112,
+	"Gcedilla":  0x0122,
+	"Tslash":    0x0166,
+	"rcedilla":  0x0157,
+	"itilde":    0x0129,
+	"lcedilla":  0x013C,
+	"emacron":   0x0113,
+	"gcedilla":  0x0123,
+	"tslash":    0x0167,
+	"ENG":       0x014A,
+	"eng":       0x014B,
+	"Amacron":   0x0100,
+	"Iogonek":   0x012E,
+	"Eabovedot": 0x0116,
+	"Imacron":   0x012A,
+	"Ncedilla":  0x0145,
+	"Omacron":   0x014C,
+	"Kcedilla":  0x0136,
+	"Uogonek":   0x0172,
+	"Utilde":    0x0168,
+	"Umacron":   0x016A,
+	"amacron":   0x0101,
+	"iogonek":   0x012F,
+	"eabovedot": 0x0117,
+	"imacron":   0x012B,
+	"ncedilla":  0x0146,
+	"omacron":   0x014D,
+	"kcedilla":  0x0137,
+	"uogonek":   0x0173,
+	"utilde":    0x0169,
+	"umacron":   0x016B,
+
+	// Latin-8
+	"Wcircumflex": 0x0174,
+	"wcircumflex": 0x0175,
+	"Ycircumflex": 0x0176,
+	"ycircumflex": 0x0177,
+	"Babovedot":   0x1E02,
+	"babovedot":   0x1E03,
+	"Dabovedot":   0x1E0A,
+	"dabovedot":   0x1E0B,
+	"Fabovedot":   0x1E1E,
+	"fabovedot":   0x1E1F,
+	"Mabovedot":   0x1E40,
+	"mabovedot":   0x1E41,
+	"Pabovedot":   0x1E56,
+	"pabovedot":   0x1E57,
+	"Sabovedot":   0x1E60,
+	"sabovedot":   0x1E61,
+	"Tabovedot":   0x1E6A,
+	"tabovedot":   0x1E6B,
+	"Wgrave":      0x1E80,
+	"wgrave":      0x1E81,
+	"Wacute":      0x1E82,
+	"wacute":      0x1E83,
+	"Wdiaeresis":  0x1E84,
+	"wdiaeresis":  0x1E85,
+	"Ygrave":      0x1EF2,
+	"ygrave":      0x1EF3,
+
+	// Latin-9
+	"OE":         0x0152,
+	"oe":         0x0153,
+	"Ydiaeresis": 0x0178,
+
+	// Currency
+	"EcuSign":       0x20A0,
+	"ColonSign":     0x20A1,
+	"CruzeiroSign":  0x20A2,
+	"FFrancSign":    0x20A3,
+	"LiraSign":      0x20A4,
+	"MillSign":      0x20A5,
+	"NairaSign":     0x20A6,
+	"PesetaSign":    0x20A7,
+	"RupeeSign":     0x20A8,
+	"WonSign":       0x20A9,
+	"NewSheqelSign": 0x20AA,
+	"DongSign":      0x20AB,
+	"EuroSign":      0x20AC,
+
+	// Cyrillic
+	"Cyrillic_GHE_bar":          0x0492,
+	"Cyrillic_ghe_bar":          0x0493,
+	"Cyrillic_ZHE_descender":    0x0496,
+	"Cyrillic_zhe_descender":    0x0497,
+	"Cyrillic_KA_descender":     0x049A,
+	"Cyrillic_ka_descender":     0x049B,
+	"Cyrillic_KA_vertstroke":    0x049C,
+	"Cyrillic_ka_vertstroke":    0x049D,
+	"Cyrillic_EN_descender":     0x04A2,
+	"Cyrillic_en_descender":     0x04A3,
+	"Cyrillic_U_straight":       0x04AE,
+	"Cyrillic_u_straight":       0x04AF,
+	"Cyrillic_U_straight_bar":   0x04B0,
+	"Cyrillic_u_straight_bar":   0x04B1,
+	"Cyrillic_HA_descender":     0x04B2,
+	"Cyrillic_ha_descender":     0x04B3,
+	"Cyrillic_CHE_descender":    0x04B6,
+	"Cyrillic_che_descender":    0x04B7,
+	"Cyrillic_CHE_vertstroke":   0x04B8,
+	"Cyrillic_che_vertstroke":   0x04B9,
+	"Cyrillic_SHHA":             0x04BA,
+	"Cyrillic_shha":             0x04BB,
+	"Cyrillic_SCHWA":            0x04D8,
+	"Cyrillic_schwa":            0x04D9,
+	"Cyrillic_I_macron":         0x04E2,
+	"Cyrillic_i_macron":         0x04E3,
+	"Cyrillic_O_bar":            0x04E8,
+	"Cyrillic_o_bar":            0x04E9,
+	"Cyrillic_U_macron":         0x04EE,
+	"Cyrillic_u_macron":         0x04EF,
+	"Serbian_dje":               0x0452,
+	"Macedonia_gje":             0x0453,
+	"Cyrillic_io":               0x0451,
+	"Ukrainian_ie":              0x0454,
+	"Macedonia_dse":             0x0455,
+	"Ukrainian_i":               0x0456,
+	"Ukrainian_yi":              0x0457,
+	"Cyrillic_je":               0x0458,
+	"Cyrillic_lje":              0x0459,
+	"Cyrillic_nje":              0x045A,
+	"Serbian_tshe":              0x045B,
+	"Macedonia_kje":             0x045C,
+	"Ukrainian_ghe_with_upturn": 0x0491,
+	"Byelorussian_shortu":       0x045E,
+	"Cyrillic_dzhe":             0x045F,
+	"numerosign":                0x2116,
+	"Serbian_DJE":               0x0402,
+	"Macedonia_GJE":             0x0403,
+	"Cyrillic_IO":               0x0401,
+	"Ukrainian_IE":              0x0404,
+	"Macedonia_DSE":             0x0405,
+	"Ukrainian_I":               0x0406,
+	"Ukrainian_YI":              0x0407,
+	"Cyrillic_JE":               0x0408,
+	"Cyrillic_LJE":              0x0409,
+	"Cyrillic_NJE":              0x040A,
+	"Serbian_TSHE":              0x040B,
+	"Macedonia_KJE":             0x040C,
+	"Ukrainian_GHE_WITH_UPTURN": 0x0490,
+	"Byelorussian_SHORTU":       0x040E,
+	"Cyrillic_DZHE":             0x040F,
+	"Cyrillic_yu":               0x044E,
+	"Cyrillic_a":                0x0430,
+	"Cyrillic_be":               0x0431,
+	"Cyrillic_tse":              0x0446,
+	"Cyrillic_de":               0x0434,
+	"Cyrillic_ie":               0x0435,
+	"Cyrillic_ef":               0x0444,
+	"Cyrillic_ghe":              0x0433,
+	"Cyrillic_ha":               0x0445,
+	"Cyrillic_i":                0x0438,
+	"Cyrillic_shorti":           0x0439,
+	"Cyrillic_ka":               0x043A,
+	"Cyrillic_el":               0x043B,
+	"Cyrillic_em":               0x043C,
+	"Cyrillic_en":               0x043D,
+	"Cyrillic_o":                0x043E,
+	"Cyrillic_pe":               0x043F,
+	"Cyrillic_ya":               0x044F,
+	"Cyrillic_er":               0x0440,
+	"Cyrillic_es":               0x0441,
+	"Cyrillic_te":               0x0442,
+	"Cyrillic_u":                0x0443,
+	"Cyrillic_zhe":              0x0436,
+	"Cyrillic_ve":               0x0432,
+	"Cyrillic_softsign":         0x044C,
+	"Cyrillic_yeru":             0x044B,
+	"Cyrillic_ze":               0x0437,
+	"Cyrillic_sha":              0x0448,
+	"Cyrillic_e":                0x044D,
+	"Cyrillic_shcha":            0x0449,
+	"Cyrillic_che":              0x0447,
+	"Cyrillic_hardsign":         0x044A,
+	"Cyrillic_YU":               0x042E,
+	"Cyrillic_A":                0x0410,
+	"Cyrillic_BE":               0x0411,
+	"Cyrillic_TSE":              0x0426,
+	"Cyrillic_DE":               0x0414,
+	"Cyrillic_IE":               0x0415,
+	"Cyrillic_EF":               0x0424,
+	"Cyrillic_GHE":              0x0413,
+	"Cyrillic_HA":               0x0425,
+	"Cyrillic_I":                0x0418,
+	"Cyrillic_SHORTI":           0x0419,
+	"Cyrillic_KA":               0x041A,
+	"Cyrillic_EL":               0x041B,
+	"Cyrillic_EM":               0x041C,
+	"Cyrillic_EN":               0x041D,
+	"Cyrillic_O":                0x041E,
+	"Cyrillic_PE":               0x041F,
+	"Cyrillic_YA":               0x042F,
+	"Cyrillic_ER":               0x0420,
+	"Cyrillic_ES":               0x0421,
+	"Cyrillic_TE":               0x0422,
+	"Cyrillic_U":                0x0423,
+	"Cyrillic_ZHE":              0x0416,
+	"Cyrillic_VE":               0x0412,
+	"Cyrillic_SOFTSIGN":         0x042C,
+	"Cyrillic_YERU":             0x042B,
+	"Cyrillic_ZE":               0x0417,
+	"Cyrillic_SHA":              0x0428,
+	"Cyrillic_E":                0x042D,
+	"Cyrillic_SHCHA":            0x0429,
+	"Cyrillic_CHE":              0x0427,
+	"Cyrillic_HARDSIGN":         0x042A,
+
+	// Greek
+	"Greek_ALPHAaccent":           0x0386,
+	"Greek_EPSILONaccent":         0x0388,
+	"Greek_ETAaccent":             0x0389,
+	"Greek_IOTAaccent":            0x038A,
+	"Greek_IOTAdieresis":          0x03AA,
+	"Greek_OMICRONaccent":         0x038C,
+	"Greek_UPSILONaccent":         0x038E,
+	"Greek_UPSILONdieresis":       0x03AB,
+	"Greek_OMEGAaccent":           0x038F,
+	"Greek_accentdieresis":        0x0385,
+	"Greek_horizbar":              0x2015,
+	"Greek_alphaaccent":           0x03AC,
+	"Greek_epsilonaccent":         0x03AD,
+	"Greek_etaaccent":             0x03AE,
+	"Greek_iotaaccent":            0x03AF,
+	"Greek_iotadieresis":          0x03CA,
+	"Greek_iotaaccentdieresis":    0x0390,
+	"Greek_omicronaccent":         0x03CC,
+	"Greek_upsilonaccent":         0x03CD,
+	"Greek_upsilondieresis":       0x03CB,
+	"Greek_upsilonaccentdieresis": 0x03B0,
+	"Greek_omegaaccent":           0x03CE,
+	"Greek_ALPHA":                 0x0391,
+	"Greek_BETA":                  0x0392,
+	"Greek_GAMMA":                 0x0393,
+	"Greek_DELTA":                 0x0394,
+	"Greek_EPSILON":               0x0395,
+	"Greek_ZETA":                  0x0396,
+	"Greek_ETA":                   0x0397,
+	"Greek_THETA":                 0x0398,
+	"Greek_IOTA":                  0x0399,
+	"Greek_KAPPA":                 0x039A,
+	"Greek_LAMDA":                 0x039B,
+	"Greek_LAMBDA":                0x039B,
+	"Greek_MU":                    0x039C,
+	"Greek_NU":                    0x039D,
+	"Greek_XI":                    0x039E,
+	"Greek_OMICRON":               0x039F,
+	"Greek_PI":                    0x03A0,
+	"Greek_RHO":                   0x03A1,
+	"Greek_SIGMA":                 0x03A3,
+	"Greek_TAU":                   0x03A4,
+	"Greek_UPSILON":               0x03A5,
+	"Greek_PHI":                   0x03A6,
+	"Greek_CHI":                   0x03A7,
+	"Greek_PSI":                   0x03A8,
+	"Greek_OMEGA":                 0x03A9,
+	"Greek_alpha":                 0x03B1,
+	"Greek_beta":                  0x03B2,
+	"Greek_gamma":                 0x03B3,
+	"Greek_delta":                 0x03B4,
+	"Greek_epsilon":               0x03B5,
+	"Greek_zeta":                  0x03B6,
+	"Greek_eta":                   0x03B7,
+	"Greek_theta":                 0x03B8,
+	"Greek_iota":                  0x03B9,
+	"Greek_kappa":                 0x03BA,
+	"Greek_lamda":                 0x03BB,
+	"Greek_lambda":                0x03BB,
+	"Greek_mu":                    0x03BC,
+	"Greek_nu":                    0x03BD,
+	"Greek_xi":                    0x03BE,
+	"Greek_omicron":               0x03BF,
+	"Greek_pi":                    0x03C0,
+	"Greek_rho":                   0x03C1,
+	"Greek_sigma":                 0x03C3,
+	"Greek_finalsmallsigma":       0x03C2,
+	"Greek_tau":                   0x03C4,
+	"Greek_upsilon":               0x03C5,
+	"Greek_phi":                   0x03C6,
+	"Greek_chi":                   0x03C7,
+	"Greek_psi":                   0x03C8,
+	"Greek_omega":                 0x03C9,
+
+	// Publishing
+	"emspace":              0x2003,
+	"enspace":              0x2002,
+	"em3space":             0x2004,
+	"em4space":             0x2005,
+	"digitspace":           0x2007,
+	"punctspace":           0x2008,
+	"thinspace":            0x2009,
+	"hairspace":            0x200A,
+	"emdash":               0x2014,
+	"endash":               0x2013,
+	"ellipsis":             0x2026,
+	"doubbaselinedot":      0x2025,
+	"onethird":             0x2153,
+	"twothirds":            0x2154,
+	"onefifth":             0x2155,
+	"twofifths":            0x2156,
+	"threefifths":          0x2157,
+	"fourfifths":           0x2158,
+	"onesixth":             0x2159,
+	"fivesixths":           0x215A,
+	"careof":               0x2105,
+	"figdash":              0x2012,
+	"oneeighth":            0x215B,
+	"threeeighths":         0x215C,
+	"fiveeighths":          0x215D,
+	"seveneighths":         0x215E,
+	"trademark":            0x2122,
+	"leftsinglequotemark":  0x2018,
+	"rightsinglequotemark": 0x2019,
+	"leftdoublequotemark":  0x201C,
+	"rightdoublequotemark": 0x201D,
+	"prescription":         0x211E,
+	"permille":             0x2030,
+	"minutes":              0x2032,
+	"seconds":              0x2033,
+	"latincross":           0x271D,
+	"club":                 0x2663,
+	"diamond":              0x2666,
+	"heart":                0x2665,
+	"maltesecross":         0x2720,
+	"dagger":               0x2020,
+	"doubledagger":         0x2021,
+	"checkmark":            0x2713,
+	"ballotcross":          0x2717,
+	"musicalsharp":         0x266F,
+	"musicalflat":          0x266D,
+	"malesymbol":           0x2642,
+	"femalesymbol":         0x2640,
+	"telephone":            0x260E,
+	"telephonerecorder":    0x2315,
+	"phonographcopyright":  0x2117,
+	"caret":                0x2038,
+	"singlelowquotemark":   0x201A,
+	"doublelowquotemark":   0x201E,
+
+	// Technical
+	"leftradical":           0x23B7,
+	"topintegral":           0x2320,
+	"botintegral":           0x2321,
+	"topleftsqbracket":      0x23A1,
+	"botleftsqbracket":      0x23A3,
+	"toprightsqbracket":     0x23A4,
+	"botrightsqbracket":     0x23A6,
+	"topleftparens":         0x239B,
+	"botleftparens":         0x239D,
+	"toprightparens":        0x239E,
+	"botrightparens":        0x23A0,
+	"leftmiddlecurlybrace":  0x23A8,
+	"rightmiddlecurlybrace": 0x23AC,
+	"lessthanequal":         0x2264,
+	"notequal":              0x2260,
+	"greaterthanequal":      0x2265,
+	"integral":              0x222B,
+	"therefore":             0x2234,
+	"variation":             0x221D,
+	"infinity":              0x221E,
+	"nabla":                 0x2207,
+	"approximate":           0x223C,
+	"similarequal":          0x2243,
+	"ifonlyif":              0x21D4,
+	"implies":               0x21D2,
+	"identical":             0x2261,
+	"radical":               0x221A,
+	"includedin":            0x2282,
+	"includes":              0x2283,
+	"intersection":          0x2229,
+	"union":                 0x222A,
+	"logicaland":            0x2227,
+	"logicalor":             0x2228,
+	"partialderivative":     0x2202,
+	"function":              0x0192,
+	"leftarrow":             0x2190,
+	"uparrow":               0x2191,
+	"rightarrow":            0x2192,
+	"downarrow":             0x2193,
+}
@@ -2,7 +2,13 @@ package layouts
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
 )
 
 // KeySequence represents a single keystroke with its modifiers.
@@ -37,12 +43,50 @@ const (
 	ModAlt   Modifier = 1 << 3
 )
 
+// modifierNames lists the modifiers in the order they are pressed.
+var modifierNames = []struct {
+	mod  Modifier
+	name string
+}{
+	{ModCtrl, "ctrl"},
+	{ModAlt, "alt"},
+	{ModShift, "shift"},
+	{ModAltGr, "altgr"},
+}
+
+// Names returns the chord names of the modifiers in press order,
+// e.g. ["shift", "altgr"].
+func (m Modifier) Names() []string {
+	var names []string
+	for _, mn := range modifierNames {
+		if m&mn.mod != 0 {
+			names = append(names, mn.name)
+		}
+	}
+	return names
+}
+
+// String returns the keystroke as a chord, e.g. "shift+leftbrace".
+// Keycodes without a name are written as numbers.
+func (k KeySequence) String() string {
+	key := uinput.KeyName(k.Keycode)
+	if key == "" {
+		key = strconv.Itoa(int(k.Keycode))
+	}
+	return strings.Join(append(k.Modifier.Names(), key), "+")
+}
+
 // KeyMapping represents a character-to-keycode mapping.
 type KeyMapping struct {
 	Keycode  uint16
 	Modifier Modifier
 }
 
+// ErrUseFallback signals that a character cannot be typed with the layout
+// but may be entered with a fallback input method (e.g. Ctrl+Shift+U).
+// Layout errors wrapping it are matched with errors.Is.
+var ErrUseFallback = errors.New("use fallback input")
+
 // ErrCharNotSupported is returned when a character has no mapping in the layout.
 type ErrCharNotSupported struct {
 	Char   rune
@@ -52,3 +96,12 @@ type ErrCharNotSupported struct {
 func (e *ErrCharNotSupported) Error() string {
 	return fmt.Sprintf("character %q (U+%04X) not supported in %s layout", e.Char, e.Char, e.Layout)
 }
+
+// Unwrap returns ErrUseFallback for printable characters. Control
+// characters have no meaningful fallback and are dropped.
+func (e *ErrCharNotSupported) Unwrap() error {
+	if unicode.IsGraphic(e.Char) {
+		return ErrUseFallback
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package layouts
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/bnema/uinputd-go/internal/uinput"
@@ -435,3 +436,55 @@ func TestUnsupportedCharacters(t *testing.T) {
 		})
 	}
 }
+
+// TestUnsupportedCharactersFallback ensures that only printable characters
+// are offered to the fallback input method.
+func TestUnsupportedCharactersFallback(t *testing.T) {
+	layout := NewUS()
+	ctx := context.Background()
+
+	tests := []struct {
+		char     rune
+		fallback bool
+	}{
+		{'ô', true},
+		{'→', true},
+		{'😀', true},
+		{'\x07', false},
+		{'\u200b', false}, // Zero-width space
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.char), func(t *testing.T) {
+			_, err := layout.CharToKeySequence(ctx, tt.char)
+			if err == nil {
+				t.Fatalf("char %q should not be supported in US layout", tt.char)
+			}
+
+			if got := errors.Is(err, ErrUseFallback); got != tt.fallback {
+				t.Errorf("errors.Is(err, ErrUseFallback) = %v, want %v", got, tt.fallback)
+			}
+		})
+	}
+}
+
+// TestKeySequenceString ensures keystrokes are written as chords with the
+// modifiers in press order.
+func TestKeySequenceString(t *testing.T) {
+	tests := []struct {
+		key  KeySequence
+		want string
+	}{
+		{KeySequence{Keycode: uinput.KeyA}, "a"},
+		{KeySequence{Keycode: uinput.KeyLeftBrace, Modifier: ModShift}, "shift+leftbrace"},
+		{KeySequence{Keycode: uinput.KeyE, Modifier: ModAltGr | ModShift}, "shift+altgr+e"},
+		{KeySequence{Keycode: uinput.KeyU, Modifier: ModShift | ModCtrl}, "ctrl+shift+u"},
+		{KeySequence{Keycode: 600}, "600"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.key.String(); got != tt.want {
+			t.Errorf("KeySequence%+v.String() = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
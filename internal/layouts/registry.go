@@ -1,8 +1,14 @@
 package layouts
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Layout name constants
@@ -15,16 +21,34 @@ const (
 	NameIT = "it" // Italian QWERTY
 )
 
+// builtinNames lists the layouts compiled in, which layout files must not
+// replace.
+var builtinNames = []string{NameUS, NameUK, NameFR, NameDE, NameES, NameIT}
+
+// xkbRetryAfter is how long a failed "xkb:" load is remembered, so that
+// clients asking for a bad layout do not make every command parse the
+// symbols files again.
+var xkbRetryAfter = 30 * time.Second
+
+// xkbFailure is a remembered failed "xkb:" load.
+type xkbFailure struct {
+	err   error
+	until time.Time
+}
+
 // Registry manages available keyboard layouts.
 type Registry struct {
-	mu      sync.RWMutex
-	layouts map[string]Layout
+	mu        sync.RWMutex
+	layouts   map[string]Layout
+	xkbDir    string                // Symbols directory for "xkb:" layouts
+	xkbFailed map[string]xkbFailure // Failed "xkb:" loads, by spec
 }
 
 // NewRegistry creates a new layout registry with default layouts.
 func NewRegistry() *Registry {
 	r := &Registry{
 		layouts: make(map[string]Layout),
+		xkbDir:  DefaultXKBDir,
 	}
 
 	// Register default layouts
@@ -46,18 +70,106 @@ func (r *Registry) Register(layout Layout) {
 }
 
 // Get retrieves a layout by name.
+// Layouts named "xkb:<layout>(<variant>)" are loaded from the XKB symbols
+// directory on first use.
 func (r *Registry) Get(name string) (Layout, error) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	layout, ok := r.layouts[name]
-	if !ok {
-		return nil, fmt.Errorf("layout %q not found (available: %v)", name, r.Available())
+	r.mu.RUnlock()
+
+	if ok {
+		return layout, nil
+	}
+
+	if spec, isXKB := strings.CutPrefix(name, XKBPrefix); isXKB {
+		return r.LoadXKB(spec)
+	}
+
+	return nil, fmt.Errorf("layout %q not found (available: %v)", name, r.Available())
+}
+
+// SetXKBDir sets the directory XKB symbols files are loaded from.
+func (r *Registry) SetXKBDir(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.xkbDir = dir
+	r.xkbFailed = nil
+}
+
+// LoadXKB parses an XKB layout such as "de(nodeadkeys)" and registers it
+// as "xkb:de(nodeadkeys)". A failed load returns the same error without
+// parsing again for xkbRetryAfter.
+func (r *Registry) LoadXKB(spec string) (Layout, error) {
+	r.mu.RLock()
+	dir := r.xkbDir
+	failed, ok := r.xkbFailed[spec]
+	r.mu.RUnlock()
+
+	now := time.Now()
+	if ok && now.Before(failed.until) {
+		return nil, failed.err
+	}
+
+	layout, err := LoadXKB(dir, spec)
+	if err != nil {
+		err = fmt.Errorf("layout %q: %w", XKBPrefix+spec, err)
+		r.rememberXKBFailure(spec, err, now)
+		return nil, err
 	}
 
+	r.Register(layout)
 	return layout, nil
 }
 
+// rememberXKBFailure records a failed "xkb:" load, dropping expired ones so
+// that the map does not grow with every bad name a client tries.
+func (r *Registry) rememberXKBFailure(spec string, err error, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.xkbFailed == nil {
+		r.xkbFailed = make(map[string]xkbFailure)
+	}
+	for s, f := range r.xkbFailed {
+		if !now.Before(f.until) {
+			delete(r.xkbFailed, s)
+		}
+	}
+	r.xkbFailed[spec] = xkbFailure{err: err, until: now.Add(xkbRetryAfter)}
+}
+
+// LoadDir registers every layout file in dir (see LayoutFileExtensions)
+// and returns the names of the layouts loaded. Invalid files are skipped
+// and reported in the returned error. A missing directory is not an error.
+func (r *Registry) LoadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("layouts directory: %w", err)
+	}
+
+	var names []string
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(LayoutFileExtensions, filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		layout, err := LoadLayoutFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		r.Register(layout)
+		names = append(names, layout.Name())
+	}
+
+	return names, errors.Join(errs...)
+}
+
 // Available returns a list of available layout names.
 func (r *Registry) Available() []string {
 	r.mu.RLock()
@@ -0,0 +1,676 @@
+package layouts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// XKBPrefix marks layout names loaded from XKB symbols files,
+// e.g. "xkb:de(nodeadkeys)".
+const XKBPrefix = "xkb:"
+
+// DefaultXKBDir is where most distributions install XKB symbols files.
+const DefaultXKBDir = "/usr/share/X11/xkb/symbols"
+
+// maxXKBIncludeDepth bounds include nesting so that cyclic includes fail
+// instead of recursing forever.
+const maxXKBIncludeDepth = 16
+
+// XKBLayout implements a keyboard layout parsed from an XKB symbols file.
+// Levels 1-4 map to no modifier, Shift, AltGr and Shift+AltGr.
+type XKBLayout struct {
+	name            string
+	baseMappings    map[rune]KeyMapping
+	deadKeyRegistry DeadKeyRegistry
+	deadKeys        map[rune]KeyMapping
+}
+
+// LoadXKB parses the layout spec (e.g. "de" or "de(nodeadkeys)") from the
+// symbols files in dir, following include statements. Without a variant,
+// the variant marked default in the file is used.
+func LoadXKB(dir, spec string) (*XKBLayout, error) {
+	p := &xkbParser{dir: dir, files: make(map[string][]xkbBlock)}
+
+	// Layouts rarely define these keys themselves (they come from "pc")
+	keys := xkbKeymap{
+		"SPCE": {"space"},
+		"TAB":  {"Tab"},
+		"RTRN": {"Return"},
+	}
+	if err := p.load(spec, 0, xkbOverride, keys); err != nil {
+		return nil, err
+	}
+
+	return newXKBLayout(XKBPrefix+spec, keys), nil
+}
+
+// Name returns the layout name, e.g. "xkb:de(nodeadkeys)".
+func (l *XKBLayout) Name() string {
+	return l.name
+}
+
+// CharToKeySequence converts a Unicode character to a sequence of keystrokes.
+func (l *XKBLayout) CharToKeySequence(ctx context.Context, char rune) ([]KeySequence, error) {
+	// First, check if it's a direct mapping
+	if mapping, ok := l.baseMappings[char]; ok {
+		return []KeySequence{{Keycode: mapping.Keycode, Modifier: mapping.Modifier}}, nil
+	}
+
+	// Check if it needs a dead key combination
+	if comp, ok := l.deadKeyRegistry[char]; ok {
+		deadKeyMapping, hasDead := l.deadKeys[comp.DeadKey]
+		if !hasDead {
+			return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+		}
+
+		baseMapping, hasBase := l.baseMappings[comp.BaseChar]
+		if !hasBase {
+			return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+		}
+
+		return []KeySequence{
+			{Keycode: deadKeyMapping.Keycode, Modifier: deadKeyMapping.Modifier},
+			{Keycode: baseMapping.Keycode, Modifier: baseMapping.Modifier},
+		}, nil
+	}
+
+	// Dead circumflex, grave and tilde followed by space type the accent itself
+	if strings.ContainsRune("^`~", char) {
+		deadKeyMapping, hasDead := l.deadKeys[char]
+		spaceMapping, hasSpace := l.baseMappings[' ']
+		if hasDead && hasSpace {
+			return []KeySequence{
+				{Keycode: deadKeyMapping.Keycode, Modifier: deadKeyMapping.Modifier},
+				{Keycode: spaceMapping.Keycode, Modifier: spaceMapping.Modifier},
+			}, nil
+		}
+	}
+
+	return nil, &ErrCharNotSupported{Char: char, Layout: l.name}
+}
+
+// xkbLevelModifiers maps XKB shift levels 1-4 to modifiers.
+var xkbLevelModifiers = [4]Modifier{ModNone, ModShift, ModAltGr, ModShift | ModAltGr}
+
+// newXKBLayout builds the character mappings of a parsed keymap.
+// When a character is reachable from several keys, the lowest level wins,
+// then the lowest keycode.
+func newXKBLayout(name string, keys xkbKeymap) *XKBLayout {
+	l := &XKBLayout{
+		name:            name,
+		baseMappings:    make(map[rune]KeyMapping),
+		deadKeyRegistry: BuildDeadKeyRegistry(),
+		deadKeys:        make(map[rune]KeyMapping),
+	}
+
+	keyNames := make([]string, 0, len(keys))
+	for keyName := range keys {
+		if _, ok := xkbKeycodes[keyName]; ok {
+			keyNames = append(keyNames, keyName)
+		}
+	}
+	sort.Slice(keyNames, func(i, j int) bool {
+		return xkbKeycodes[keyNames[i]] < xkbKeycodes[keyNames[j]]
+	})
+
+	for level := range xkbLevelModifiers {
+		for _, keyName := range keyNames {
+			sym := keys[keyName][level]
+			mapping := KeyMapping{Keycode: xkbKeycodes[keyName], Modifier: xkbLevelModifiers[level]}
+
+			if dead, ok := xkbDeadKeysyms[sym]; ok {
+				if _, exists := l.deadKeys[dead]; !exists {
+					l.deadKeys[dead] = mapping
+				}
+				continue
+			}
+
+			char, ok := keysymToRune(sym)
+			if !ok {
+				continue
+			}
+			if _, exists := l.baseMappings[char]; !exists {
+				l.baseMappings[char] = mapping
+			}
+		}
+	}
+
+	// One-level alphabetic keys get their uppercase on Shift, like XKB's
+	// automatic ALPHABETIC key type
+	for _, keyName := range keyNames {
+		levels := keys[keyName]
+		if levels[1] != "" {
+			continue
+		}
+		char, ok := keysymToRune(levels[0])
+		if !ok || !unicode.IsLower(char) {
+			continue
+		}
+		if upper := unicode.ToUpper(char); upper != char {
+			if _, exists := l.baseMappings[upper]; !exists {
+				l.baseMappings[upper] = KeyMapping{Keycode: xkbKeycodes[keyName], Modifier: ModShift}
+			}
+		}
+	}
+
+	return l
+}
+
+// xkbKeycodes maps XKB key names (evdev keycodes) to Linux keycodes.
+var xkbKeycodes = map[string]uint16{
+	"TLDE": uinput.KeyGrave,
+	"AE01": uinput.Key1,
+	"AE02": uinput.Key2,
+	"AE03": uinput.Key3,
+	"AE04": uinput.Key4,
+	"AE05": uinput.Key5,
+	"AE06": uinput.Key6,
+	"AE07": uinput.Key7,
+	"AE08": uinput.Key8,
+	"AE09": uinput.Key9,
+	"AE10": uinput.Key0,
+	"AE11": uinput.KeyMinus,
+	"AE12": uinput.KeyEqual,
+	"AD01": uinput.KeyQ,
+	"AD02": uinput.KeyW,
+	"AD03": uinput.KeyE,
+	"AD04": uinput.KeyR,
+	"AD05": uinput.KeyT,
+	"AD06": uinput.KeyY,
+	"AD07": uinput.KeyU,
+	"AD08": uinput.KeyI,
+	"AD09": uinput.KeyO,
+	"AD10": uinput.KeyP,
+	"AD11": uinput.KeyLeftBrace,
+	"AD12": uinput.KeyRightBrace,
+	"AC01": uinput.KeyA,
+	"AC02": uinput.KeyS,
+	"AC03": uinput.KeyD,
+	"AC04": uinput.KeyF,
+	"AC05": uinput.KeyG,
+	"AC06": uinput.KeyH,
+	"AC07": uinput.KeyJ,
+	"AC08": uinput.KeyK,
+	"AC09": uinput.KeyL,
+	"AC10": uinput.KeySemicolon,
+	"AC11": uinput.KeyApostrophe,
+	"AC12": uinput.KeyBackslash, // Alias of BKSL on ISO keyboards
+	"BKSL": uinput.KeyBackslash,
+	"LSGT": uinput.Key102ND,
+	"AB01": uinput.KeyZ,
+	"AB02": uinput.KeyX,
+	"AB03": uinput.KeyC,
+	"AB04": uinput.KeyV,
+	"AB05": uinput.KeyB,
+	"AB06": uinput.KeyN,
+	"AB07": uinput.KeyM,
+	"AB08": uinput.KeyComma,
+	"AB09": uinput.KeyDot,
+	"AB10": uinput.KeySlash,
+	"SPCE": uinput.KeySpace,
+	"TAB":  uinput.KeyTab,
+	"RTRN": uinput.KeyEnter,
+}
+
+// xkbDeadKeysyms maps XKB dead keysyms to the dead key characters used by
+// CommonDeadKeyCompositions. Other dead keys are ignored.
+var xkbDeadKeysyms = map[string]rune{
+	"dead_circumflex":  '^',
+	"dead_acute":       '´',
+	"dead_grave":       '`',
+	"dead_diaeresis":   '¨',
+	"dead_tilde":       '~',
+	"dead_perispomeni": '~',
+}
+
+// keysymToRune resolves an XKB keysym to the character it types.
+// Keysyms may be names ("exclam"), single characters ("a"), Unicode
+// keysyms ("U20AC") or numeric keysyms ("0x10020ac").
+func keysymToRune(sym string) (rune, bool) {
+	if sym == "" {
+		return 0, false
+	}
+
+	if utf8.RuneCountInString(sym) == 1 {
+		char, _ := utf8.DecodeRuneInString(sym)
+		return char, true
+	}
+
+	if char, ok := keysymRunes[sym]; ok {
+		return char, true
+	}
+
+	switch sym {
+	case "Tab":
+		return '\t', true
+	case "Return":
+		return '\n', true
+	}
+
+	if hex, ok := strings.CutPrefix(sym, "U"); ok && len(hex) >= 4 {
+		if code, err := strconv.ParseUint(hex, 16, 32); err == nil && utf8.ValidRune(rune(code)) {
+			return rune(code), true
+		}
+		return 0, false
+	}
+
+	if hex, ok := strings.CutPrefix(strings.ToLower(sym), "0x"); ok {
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case code >= 0x01000100 && code <= 0x0110ffff:
+			return rune(code - 0x01000000), true
+		case code >= 0x20 && code <= 0x7e, code >= 0xa0 && code <= 0xff:
+			return rune(code), true // Latin-1 keysyms equal their code point
+		}
+	}
+
+	return 0, false
+}
+
+// xkbLevels holds the keysyms of a key's first group, one per level.
+type xkbLevels [4]string
+
+// xkbKeymap holds the keysyms of each key, by XKB key name (e.g. "AE01").
+type xkbKeymap map[string]xkbLevels
+
+// xkbMergeMode controls how included or redefined keys combine with
+// the keys already defined.
+type xkbMergeMode int
+
+const (
+	xkbOverride xkbMergeMode = iota // New levels replace existing ones
+	xkbAugment                      // New levels only fill empty ones
+	xkbReplace                      // The new definition replaces the key
+)
+
+// xkbBlock is one xkb_symbols variant of a symbols file.
+type xkbBlock struct {
+	name       string
+	isDefault  bool
+	statements []string
+}
+
+// xkbParser loads symbols files from a directory, caching parsed files
+// across includes.
+type xkbParser struct {
+	dir   string
+	files map[string][]xkbBlock
+}
+
+var (
+	xkbFileRe    = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$`)
+	xkbVariantRe = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+	xkbBlockRe   = regexp.MustCompile(`xkb_symbols\s+"([^"]*)"\s*\{`)
+	xkbKeyRe     = regexp.MustCompile(`(?s)^key\s*<([A-Za-z0-9+-]+)>\s*\{(.*)\}$`)
+	xkbIncludeRe = regexp.MustCompile(`^(include|override|augment|replace)\s*"([^"]*)"$`)
+)
+
+// load applies the keys of spec to keys with the given merge mode.
+func (p *xkbParser) load(spec string, depth int, mode xkbMergeMode, keys xkbKeymap) error {
+	if depth > maxXKBIncludeDepth {
+		return fmt.Errorf("xkb %s: includes nested too deeply", spec)
+	}
+
+	file, variant, err := splitXKBSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	blocks, err := p.parseFile(file)
+	if err != nil {
+		return err
+	}
+
+	block, err := findXKBBlock(blocks, file, variant)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range block.statements {
+		if err := p.apply(stmt, depth, mode, keys); err != nil {
+			return fmt.Errorf("xkb %s: %w", spec, err)
+		}
+	}
+
+	return nil
+}
+
+// apply executes a single statement of an xkb_symbols block.
+// Statements other than includes and key definitions are ignored.
+func (p *xkbParser) apply(stmt string, depth int, mode xkbMergeMode, keys xkbKeymap) error {
+	if m := xkbIncludeRe.FindStringSubmatch(stmt); m != nil {
+		includeMode := mode
+		switch m[1] {
+		case "augment":
+			includeMode = xkbAugment
+		case "override":
+			includeMode = xkbOverride
+		}
+		return p.include(m[2], depth, includeMode, keys)
+	}
+
+	// Strip the merge mode of "override key <...>" and friends
+	keyMode := mode
+	if word, rest, ok := strings.Cut(stmt, " "); ok {
+		switch word {
+		case "override":
+			keyMode, stmt = xkbOverride, strings.TrimSpace(rest)
+		case "augment":
+			keyMode, stmt = xkbAugment, strings.TrimSpace(rest)
+		case "replace":
+			keyMode, stmt = xkbReplace, strings.TrimSpace(rest)
+		}
+	}
+
+	m := xkbKeyRe.FindStringSubmatch(stmt)
+	if m == nil {
+		return nil
+	}
+
+	levels, ok := parseXKBSymbols(m[2])
+	if !ok {
+		return nil // Actions-only key
+	}
+	mergeXKBKey(keys, m[1], levels, keyMode)
+	return nil
+}
+
+// include loads every part of an include string such as "latin(type4)" or
+// "pc+us(intl)". Parts joined with "|" augment instead of overriding;
+// parts for groups other than the first ("ru:2") are skipped.
+func (p *xkbParser) include(specs string, depth int, mode xkbMergeMode, keys xkbKeymap) error {
+	partMode := mode
+	for specs != "" {
+		i := strings.IndexAny(specs[1:], "+|") + 1
+		part := specs
+		if i > 0 {
+			part = specs[:i]
+		}
+
+		switch part[0] {
+		case '+':
+			partMode, part = xkbOverride, part[1:]
+		case '|':
+			partMode, part = xkbAugment, part[1:]
+		}
+
+		if name, group, ok := strings.Cut(part, ":"); ok {
+			if group != "1" {
+				part = ""
+			} else {
+				part = name
+			}
+		}
+		if part != "" {
+			if err := p.load(part, depth+1, partMode, keys); err != nil {
+				return err
+			}
+		}
+
+		if i == 0 {
+			break
+		}
+		specs = specs[i:]
+	}
+	return nil
+}
+
+// parseFile reads and splits a symbols file into its variant blocks.
+func (p *xkbParser) parseFile(file string) ([]xkbBlock, error) {
+	if blocks, ok := p.files[file]; ok {
+		return blocks, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, fmt.Errorf("xkb symbols file: %w", err)
+	}
+
+	blocks := parseXKBBlocks(stripXKBComments(string(data)))
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("xkb symbols file %s: no xkb_symbols section", file)
+	}
+
+	p.files[file] = blocks
+	return blocks, nil
+}
+
+// splitXKBSpec splits "de(nodeadkeys)" into file and variant. The file
+// name is restricted to relative paths inside the symbols directory.
+func splitXKBSpec(spec string) (file, variant string, err error) {
+	file = spec
+	if open := strings.IndexByte(spec, '('); open >= 0 {
+		if !strings.HasSuffix(spec, ")") {
+			return "", "", fmt.Errorf("invalid xkb layout %q", spec)
+		}
+		file, variant = spec[:open], spec[open+1:len(spec)-1]
+	}
+
+	if !xkbFileRe.MatchString(file) || !xkbVariantRe.MatchString(variant) {
+		return "", "", fmt.Errorf("invalid xkb layout %q", spec)
+	}
+	return file, variant, nil
+}
+
+// findXKBBlock returns the named variant, or the default one (the first
+// block when none is marked default) if variant is empty.
+func findXKBBlock(blocks []xkbBlock, file, variant string) (*xkbBlock, error) {
+	if variant == "" {
+		for i := range blocks {
+			if blocks[i].isDefault {
+				return &blocks[i], nil
+			}
+		}
+		return &blocks[0], nil
+	}
+
+	for i := range blocks {
+		if blocks[i].name == variant {
+			return &blocks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("xkb symbols file %s: variant %q not found", file, variant)
+}
+
+// stripXKBComments removes // and /* */ comments outside of strings.
+func stripXKBComments(src string) string {
+	var b strings.Builder
+	b.Grow(len(src))
+
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case inString:
+			if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			c = '\n'
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			c = ' '
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// parseXKBBlocks splits comment-free source into xkb_symbols blocks and
+// their top-level statements.
+func parseXKBBlocks(src string) []xkbBlock {
+	var blocks []xkbBlock
+
+	prev := 0
+	for _, loc := range xkbBlockRe.FindAllStringSubmatchIndex(src, -1) {
+		if loc[0] < prev {
+			continue // Inside a previous block
+		}
+
+		// Flags such as "default partial alphanumeric_keys" precede the block
+		flags := strings.Fields(src[prev:loc[0]])
+		block := xkbBlock{
+			name:      src[loc[2]:loc[3]],
+			isDefault: slices.Contains(flags, "default"),
+		}
+
+		body, end := matchXKBBrace(src, loc[1])
+		block.statements = splitXKBStatements(body)
+		blocks = append(blocks, block)
+
+		prev = end
+		if prev < len(src) && src[prev] == ';' {
+			prev++
+		}
+	}
+
+	return blocks
+}
+
+// matchXKBBrace returns the text up to the brace closing the one opened
+// just before start, and the index following it.
+func matchXKBBrace(src string, start int) (string, int) {
+	depth := 1
+	inString := false
+	for i := start; i < len(src); i++ {
+		switch c := src[i]; {
+		case inString:
+			inString = c != '"'
+		case c == '"':
+			inString = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return src[start:i], i + 1
+			}
+		}
+	}
+	return src[start:], len(src)
+}
+
+// splitXKBStatements splits a block body at top-level semicolons. Include
+// statements may omit the semicolon, so each one ends at its string.
+func splitXKBStatements(body string) []string {
+	var stmts []string
+	add := func(s string) {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+
+	depth, start := 0, 0
+	inString := false
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case inString:
+			if c == '"' {
+				inString = false
+				if xkbIncludeRe.MatchString(strings.Join(strings.Fields(body[start:i+1]), " ")) {
+					add(body[start : i+1])
+					start = i + 1
+				}
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == ';' && depth == 0:
+			add(body[start:i])
+			start = i + 1
+		}
+	}
+	add(body[start:])
+
+	return stmts
+}
+
+// parseXKBSymbols extracts the first group's keysyms from a key body such
+// as `[ a, A ]` or `type[Group1]="FOUR_LEVEL", symbols[Group1]= [ a, A ]`.
+func parseXKBSymbols(body string) (xkbLevels, bool) {
+	var levels xkbLevels
+
+	if i := strings.Index(body, "symbols["); i >= 0 {
+		body = body[i+len("symbols["):]
+		if eq := strings.IndexByte(body, '='); eq >= 0 {
+			body = body[eq+1:]
+		}
+	}
+
+	for {
+		open := strings.IndexByte(body, '[')
+		if open < 0 {
+			return levels, false
+		}
+		end := strings.IndexByte(body[open:], ']')
+		if end < 0 {
+			return levels, false
+		}
+
+		list := body[open+1 : open+end]
+		body = body[open+end+1:]
+
+		// Skip group indexes ("[Group1]") and action lists
+		trimmed := strings.ToLower(strings.TrimSpace(list))
+		if strings.HasPrefix(trimmed, "group") || strings.ContainsRune(list, '(') {
+			continue
+		}
+
+		for i, sym := range strings.Split(list, ",") {
+			if i >= len(levels) {
+				break
+			}
+			sym = strings.TrimSpace(sym)
+			if sym != "NoSymbol" && sym != "VoidSymbol" {
+				levels[i] = sym
+			}
+		}
+		return levels, true
+	}
+}
+
+// mergeXKBKey combines a key definition with the existing one.
+func mergeXKBKey(keys xkbKeymap, name string, levels xkbLevels, mode xkbMergeMode) {
+	existing, ok := keys[name]
+	if !ok || mode == xkbReplace {
+		keys[name] = levels
+		return
+	}
+
+	for i, sym := range levels {
+		if sym == "" {
+			continue
+		}
+		if mode == xkbOverride || existing[i] == "" {
+			existing[i] = sym
+		}
+	}
+	keys[name] = existing
+}
@@ -0,0 +1,220 @@
+package layouts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// writeXKBTestDir writes the test symbols files and returns their directory.
+func writeXKBTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"latin": `
+default partial alphanumeric_keys
+xkb_symbols "basic" {
+    key <AE01> { [ 1, exclam ] };
+    key <AD01> { [ q, Q, at, Greek_OMEGA ] };
+    key <AD03> { [ e, E ] };
+    key <AD06> { [ y, Y ] };
+    key <AD09> { [ o, O ] };
+    key <AC01> { [ a, A ] };
+    key <AB01> { [ z, Z ] };
+};
+`,
+		"de": `
+/* German */
+default partial alphanumeric_keys
+xkb_symbols "basic" {
+    include "latin(basic)"
+    name[Group1]="German";
+
+    key <AD03> { [ e, E, EuroSign, EuroSign ] };
+    key <AD06> { [ z, Z ] };
+    key <AB01> { [ y, Y ] };
+    key <AE11> { type[Group1]="FOUR_LEVEL_PLUS_LOCK", symbols[Group1]=
+                 [ ssharp, question, backslash, questiondown, 0x1001E9E ] };
+    key <TLDE> { [ dead_circumflex, degree, U2032, U2033 ] };
+};
+
+partial alphanumeric_keys
+xkb_symbols "nodeadkeys" {
+    include "de(basic)"
+    key <TLDE> { [ asciicircum, degree ] };
+};
+`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestXKBLayout(t *testing.T) {
+	dir := writeXKBTestDir(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		spec     string
+		char     rune
+		expected []KeySequence
+		desc     string
+	}{
+		{"de", 'z', []KeySequence{{Keycode: uinput.KeyY, Modifier: ModNone}}, "override from layout"},
+		{"de", 'Y', []KeySequence{{Keycode: uinput.KeyZ, Modifier: ModShift}}, "level 2"},
+		{"de", '!', []KeySequence{{Keycode: uinput.Key1, Modifier: ModShift}}, "inherited from include"},
+		{"de", '@', []KeySequence{{Keycode: uinput.KeyQ, Modifier: ModAltGr}}, "level 3"},
+		{"de", 'Ω', []KeySequence{{Keycode: uinput.KeyQ, Modifier: ModShift | ModAltGr}}, "level 4"},
+		{"de", '€', []KeySequence{{Keycode: uinput.KeyE, Modifier: ModAltGr}}, "keysym name"},
+		{"de", 'ß', []KeySequence{{Keycode: uinput.KeyMinus, Modifier: ModNone}}, "symbols[Group1] syntax"},
+		{"de", '′', []KeySequence{{Keycode: uinput.KeyGrave, Modifier: ModAltGr}}, "unicode keysym"},
+		{"de", ' ', []KeySequence{{Keycode: uinput.KeySpace, Modifier: ModNone}}, "space"},
+		{"de", '\n', []KeySequence{{Keycode: uinput.KeyEnter, Modifier: ModNone}}, "newline"},
+		{
+			"de", 'ô',
+			[]KeySequence{
+				{Keycode: uinput.KeyGrave, Modifier: ModNone}, // dead ^
+				{Keycode: uinput.KeyO, Modifier: ModNone},
+			},
+			"dead key composition",
+		},
+		{
+			"de", '^',
+			[]KeySequence{
+				{Keycode: uinput.KeyGrave, Modifier: ModNone}, // dead ^
+				{Keycode: uinput.KeySpace, Modifier: ModNone},
+			},
+			"dead key followed by space",
+		},
+		{"de(nodeadkeys)", '^', []KeySequence{{Keycode: uinput.KeyGrave, Modifier: ModNone}}, "variant override"},
+		{"de(nodeadkeys)", '′', []KeySequence{{Keycode: uinput.KeyGrave, Modifier: ModAltGr}}, "variant keeps unset levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.desc, func(t *testing.T) {
+			layout, err := LoadXKB(dir, tt.spec)
+			if err != nil {
+				t.Fatalf("LoadXKB(%q): %v", tt.spec, err)
+			}
+
+			seq, err := layout.CharToKeySequence(ctx, tt.char)
+			if err != nil {
+				t.Fatalf("char %q not supported: %v", tt.char, err)
+			}
+
+			if len(seq) != len(tt.expected) {
+				t.Fatalf("char %q: got %d keystrokes, want %d", tt.char, len(seq), len(tt.expected))
+			}
+
+			for i := range seq {
+				if seq[i] != tt.expected[i] {
+					t.Errorf("char %q keystroke[%d]: got %+v, want %+v", tt.char, i, seq[i], tt.expected[i])
+				}
+			}
+		})
+	}
+
+	t.Run("dead key removed by variant", func(t *testing.T) {
+		layout, err := LoadXKB(dir, "de(nodeadkeys)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := layout.CharToKeySequence(ctx, 'ô'); err == nil {
+			t.Error("expected ô to be unsupported without dead keys")
+		}
+	})
+}
+
+func TestXKBLayoutErrors(t *testing.T) {
+	dir := writeXKBTestDir(t)
+
+	for _, spec := range []string{
+		"missing",
+		"de(missing)",
+		"../de",
+		"/etc/passwd",
+		"de(basic",
+	} {
+		if _, err := LoadXKB(dir, spec); err == nil {
+			t.Errorf("LoadXKB(%q): expected error", spec)
+		}
+	}
+}
+
+func TestRegistryLoadsXKBOnDemand(t *testing.T) {
+	r := NewRegistry()
+	r.SetXKBDir(writeXKBTestDir(t))
+
+	layout, err := r.Get("xkb:de(nodeadkeys)")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if layout.Name() != "xkb:de(nodeadkeys)" {
+		t.Errorf("Name() = %q", layout.Name())
+	}
+
+	// Second lookup is served from the registry
+	again, err := r.Get("xkb:de(nodeadkeys)")
+	if err != nil || again != layout {
+		t.Errorf("expected cached layout, got %v, %v", again, err)
+	}
+
+	if _, err := r.Get("xkb:missing"); err == nil {
+		t.Error("expected error for missing xkb layout")
+	}
+}
+
+func TestRegistryRemembersXKBFailures(t *testing.T) {
+	dir := writeXKBTestDir(t)
+	r := NewRegistry()
+	r.SetXKBDir(dir)
+
+	if _, err := r.Get("xkb:fr"); err == nil {
+		t.Fatal("expected error for missing xkb layout")
+	}
+
+	// The file appearing is not noticed until the failure expires
+	data, err := os.ReadFile(filepath.Join(dir, "de"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fr"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get("xkb:fr"); err == nil {
+		t.Error("expected the remembered error")
+	}
+
+	// Setting the directory forgets failures
+	r.SetXKBDir(dir)
+	if _, err := r.Get("xkb:fr"); err != nil {
+		t.Errorf("Get after SetXKBDir: %v", err)
+	}
+}
+
+func TestRegistryExpiresXKBFailures(t *testing.T) {
+	defer func(d time.Duration) { xkbRetryAfter = d }(xkbRetryAfter)
+	xkbRetryAfter = 0
+
+	r := NewRegistry()
+	r.SetXKBDir(writeXKBTestDir(t))
+	for _, spec := range []string{"a", "b", "c"} {
+		if _, err := r.LoadXKB(spec); err == nil {
+			t.Fatalf("LoadXKB(%q): expected error", spec)
+		}
+	}
+
+	// Expired failures are dropped as new ones are recorded
+	if len(r.xkbFailed) != 1 {
+		t.Errorf("remembered %d failures, want only the last one", len(r.xkbFailed))
+	}
+}
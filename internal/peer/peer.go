@@ -0,0 +1,114 @@
+// Package peer identifies the processes connected to the daemon's socket,
+// from the kernel's SO_PEERCRED credentials and /proc.
+package peer
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Cred is the identity of the process at the other end of a connection,
+// as of when it connected.
+type Cred struct {
+	PID     int32
+	UID     uint32
+	GID     uint32
+	Groups  []uint32 // Supplementary groups, nil if unknown
+	Exe     string   // Resolved executable path, empty if unknown
+	Cmdline []string // Command line arguments, nil if unknown
+}
+
+// InGroup reports whether the process runs with gid as its primary or a
+// supplementary group.
+func (c *Cred) InGroup(gid uint32) bool {
+	if c.GID == gid {
+		return true
+	}
+	for _, g := range c.Groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// FromConn returns the credentials of the peer of a Unix socket
+// connection. The kernel vouches for PID, UID and GID; groups, executable
+// and command line are read from /proc and left empty if unreadable.
+func FromConn(conn net.Conn) (*Cred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("not a unix socket connection: %T", conn)
+	}
+
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *unix.Ucred
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, serr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if serr != nil {
+		return nil, fmt.Errorf("SO_PEERCRED: %w", serr)
+	}
+
+	cred := &Cred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}
+	proc := "/proc/" + strconv.Itoa(int(ucred.Pid))
+	cred.Exe, _ = os.Readlink(proc + "/exe")
+	cred.Groups, _ = readGroups(proc + "/status")
+	cred.Cmdline, _ = readCmdline(proc + "/cmdline")
+	return cred, nil
+}
+
+// readCmdline parses a /proc/PID/cmdline file: NUL-terminated arguments.
+func readCmdline(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s: empty command line", path) // Kernel threads, zombies
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"), nil
+}
+
+// readGroups parses the supplementary groups of a /proc/PID/status file.
+func readGroups(path string) ([]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields, ok := strings.CutPrefix(scanner.Text(), "Groups:")
+		if !ok {
+			continue
+		}
+		groups := []uint32{}
+		for _, field := range strings.Fields(fields) {
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid group %q", path, field)
+			}
+			groups = append(groups, uint32(gid))
+		}
+		return groups, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: no Groups line", path)
+}
@@ -0,0 +1,95 @@
+package peer
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFromConn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	defer conn.Close()
+
+	cred, err := FromConn(conn)
+	if err != nil {
+		t.Fatalf("FromConn() error = %v", err)
+	}
+
+	// Both ends are this test process
+	if int(cred.PID) != os.Getpid() || int(cred.UID) != os.Getuid() || int(cred.GID) != os.Getgid() {
+		t.Errorf("FromConn() = pid %d uid %d gid %d, want %d %d %d", cred.PID, cred.UID, cred.GID, os.Getpid(), os.Getuid(), os.Getgid())
+	}
+	if exe, _ := os.Executable(); cred.Exe != exe {
+		t.Errorf("FromConn() exe = %q, want %q", cred.Exe, exe)
+	}
+	if cred.Groups == nil {
+		t.Error("FromConn() groups not read")
+	}
+	if !slices.Equal(cred.Cmdline, os.Args) {
+		t.Errorf("FromConn() cmdline = %q, want %q", cred.Cmdline, os.Args)
+	}
+}
+
+func TestFromConnNotUnix(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	defer conn.Close()
+
+	if _, err := FromConn(conn); err == nil {
+		t.Error("FromConn() of a pipe error = nil")
+	}
+}
+
+func TestReadGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status")
+	os.WriteFile(path, []byte("Name:\tbash\nUid:\t1000\t1000\t1000\t1000\nGroups:\t10 104 1000 \nNSpid:\t42\n"), 0o644)
+
+	groups, err := readGroups(path)
+	if err != nil {
+		t.Fatalf("readGroups() error = %v", err)
+	}
+	if len(groups) != 3 || groups[0] != 10 || groups[1] != 104 || groups[2] != 1000 {
+		t.Errorf("readGroups() = %v, want [10 104 1000]", groups)
+	}
+
+	cred := &Cred{GID: 1000, Groups: groups}
+	if !cred.InGroup(104) || !cred.InGroup(1000) || cred.InGroup(0) {
+		t.Error("InGroup() mismatch")
+	}
+}
+
+func TestReadCmdline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmdline")
+	os.WriteFile(path, []byte("python3\x00-m\x00dictate\x00--lang=en\x00"), 0o644)
+
+	args, err := readCmdline(path)
+	if err != nil {
+		t.Fatalf("readCmdline() error = %v", err)
+	}
+	if want := []string{"python3", "-m", "dictate", "--lang=en"}; !slices.Equal(args, want) {
+		t.Errorf("readCmdline() = %q, want %q", args, want)
+	}
+
+	os.WriteFile(path, nil, 0o644)
+	if _, err := readCmdline(path); err == nil {
+		t.Error("readCmdline() of an empty file error = nil")
+	}
+}
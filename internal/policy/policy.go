@@ -0,0 +1,363 @@
+// Package policy decides which commands each client of the daemon may
+// send, from a policy file keyed by the client's UID, GID or executable.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	"gopkg.in/yaml.v3"
+)
+
+// Default actions for clients no rule matches.
+const (
+	DefaultDeny  = "deny"
+	DefaultAllow = "allow"
+)
+
+// Policy is a parsed policy file.
+//
+// Example:
+//
+//	default: deny                  # clients no rule matches (default: deny)
+//	deny_chords:                   # denied to every client
+//	  - ctrl+alt+f*                # VT switching
+//	  - ctrl+alt+delete
+//	rules:                         # the first matching rule applies
+//	  - name: dictation
+//	    match: { user: alice, exe: /usr/bin/nerd-dictation }
+//	    commands: [type, stream, stream_open, stream_chunk, stream_flush, stream_close]
+//	    layouts: [us, "xkb:*"]
+//	    max_commands_per_second: 20
+//	  - name: admins
+//	    match: { group: wheel }    # every other command and layout
+//
+// A rule matches a client when every criterion it sets matches: uid or
+// user, gid or group (primary or supplementary), and exe (a path or glob
+// pattern). A rule without criteria matches every client.
+type Policy struct {
+	rules []*Rule
+	dflt  *Rule // Applies when no rule matches, nil to deny
+}
+
+// Rule is what a policy allows the clients it matches.
+type Rule struct {
+	// Name identifies the rule in logs and errors
+	Name string
+
+	uid *uint32
+	gid *uint32
+	exe string
+
+	commands   []string // Nil for every command
+	layouts    []string // Nil for every layout
+	denyChords []chordPattern
+	maxRate    float64 // Commands per second, 0 for no limit
+}
+
+// chordPattern is a denied chord: the keys matching its key pattern,
+// pressed while all of its modifiers are held.
+type chordPattern struct {
+	text      string
+	modifiers []uint16 // Left-side keycodes, see modifierClass
+	keys      []uint16
+}
+
+// file is the layout of a policy file.
+type file struct {
+	Default    string     `yaml:"default"`
+	DenyChords []string   `yaml:"deny_chords"`
+	Rules      []fileRule `yaml:"rules"`
+}
+
+type fileRule struct {
+	Name  string `yaml:"name"`
+	Match struct {
+		UID   *uint32 `yaml:"uid"`
+		User  string  `yaml:"user"`
+		GID   *uint32 `yaml:"gid"`
+		Group string  `yaml:"group"`
+		Exe   string  `yaml:"exe"`
+	} `yaml:"match"`
+	Commands             []string `yaml:"commands"`
+	Layouts              []string `yaml:"layouts"`
+	DenyChords           []string `yaml:"deny_chords"`
+	MaxCommandsPerSecond float64  `yaml:"max_commands_per_second"`
+}
+
+// Load reads and parses a policy file.
+func Load(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filename, data)
+}
+
+// Parse parses a policy definition. Every invalid entry is reported,
+// joined with errors.Join.
+func Parse(filename string, data []byte) (*Policy, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	var errs []error
+	errorf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{filename}, args...)...))
+	}
+
+	global, err := parseChords(f.DenyChords)
+	if err != nil {
+		errorf("deny_chords: %v", err)
+	}
+
+	p := &Policy{}
+	switch f.Default {
+	case "", DefaultDeny:
+	case DefaultAllow:
+		p.dflt = &Rule{Name: "default", denyChords: global}
+	default:
+		errorf("invalid default %q (want %s or %s)", f.Default, DefaultDeny, DefaultAllow)
+	}
+
+	for i, fr := range f.Rules {
+		rule, err := parseRule(fr, global)
+		if err != nil {
+			name := fr.Name
+			if name == "" {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			errorf("rule %s: %v", name, err)
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = "#" + strconv.Itoa(i+1)
+		}
+		p.rules = append(p.rules, rule)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseRule builds a rule, with the global denied chords added to its own.
+func parseRule(fr fileRule, global []chordPattern) (*Rule, error) {
+	m := fr.Match
+	rule := &Rule{
+		Name:     fr.Name,
+		uid:      m.UID,
+		gid:      m.GID,
+		exe:      m.Exe,
+		commands: fr.Commands,
+		layouts:  fr.Layouts,
+		maxRate:  fr.MaxCommandsPerSecond,
+	}
+
+	if m.User != "" {
+		if m.UID != nil {
+			return nil, fmt.Errorf("both uid and user given")
+		}
+		u, err := user.Lookup(m.User)
+		if err != nil {
+			return nil, err
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: invalid uid %q", m.User, u.Uid)
+		}
+		rule.uid = ptr(uint32(uid))
+	}
+	if m.Group != "" {
+		if m.GID != nil {
+			return nil, fmt.Errorf("both gid and group given")
+		}
+		g, err := user.LookupGroup(m.Group)
+		if err != nil {
+			return nil, err
+		}
+		gid, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: invalid gid %q", m.Group, g.Gid)
+		}
+		rule.gid = ptr(uint32(gid))
+	}
+
+	for _, pattern := range append([]string{m.Exe}, fr.Layouts...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	if fr.MaxCommandsPerSecond < 0 {
+		return nil, fmt.Errorf("negative max_commands_per_second")
+	}
+
+	chords, err := parseChords(fr.DenyChords)
+	if err != nil {
+		return nil, fmt.Errorf("deny_chords: %w", err)
+	}
+	rule.denyChords = append(slices.Clone(global), chords...)
+
+	return rule, nil
+}
+
+// parseChords parses denied chord patterns such as "ctrl+alt+f*": modifier
+// names, then a key name or glob pattern of key names.
+func parseChords(patterns []string) ([]chordPattern, error) {
+	var chords []chordPattern
+	var errs []error
+
+	for _, text := range patterns {
+		parts := strings.Split(strings.ToLower(strings.TrimSpace(text)), "+")
+		cp := chordPattern{text: text}
+
+		for _, name := range parts[:len(parts)-1] {
+			code, ok := uinput.ModifierNames[strings.TrimSpace(name)]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%q: unknown modifier %q", text, name))
+				continue
+			}
+			cp.modifiers = append(cp.modifiers, modifierClass(code))
+		}
+
+		keyPattern := strings.TrimPrefix(strings.TrimSpace(parts[len(parts)-1]), "key_")
+		for name, code := range uinput.KeyNames {
+			if ok, err := path.Match(keyPattern, name); err != nil {
+				errs = append(errs, fmt.Errorf("%q: invalid key pattern", text))
+				break
+			} else if ok && !slices.Contains(cp.keys, code) {
+				cp.keys = append(cp.keys, code)
+			}
+		}
+		if len(cp.keys) == 0 {
+			errs = append(errs, fmt.Errorf("%q: no key matches %q", text, keyPattern))
+		}
+
+		chords = append(chords, cp)
+	}
+
+	return chords, errors.Join(errs...)
+}
+
+// modifierClass maps both sides of a modifier to the left-side keycode, so
+// that "ctrl" in a pattern matches either Ctrl key.
+func modifierClass(code uint16) uint16 {
+	switch code {
+	case uinput.KeyRightShift:
+		return uinput.KeyLeftShift
+	case uinput.KeyRightCtrl:
+		return uinput.KeyLeftCtrl
+	case uinput.KeyRightAlt:
+		return uinput.KeyLeftAlt
+	case uinput.KeyRightMeta:
+		return uinput.KeyLeftMeta
+	}
+	return code
+}
+
+// Match returns the rule applying to the client, or nil if the client is
+// denied everything. Clients of unknown identity only match rules without
+// criteria.
+func (p *Policy) Match(cred *peer.Cred) *Rule {
+	for _, rule := range p.rules {
+		if rule.matches(cred) {
+			return rule
+		}
+	}
+	return p.dflt
+}
+
+// Rules returns the number of rules of the policy.
+func (p *Policy) Rules() int {
+	return len(p.rules)
+}
+
+// RateLimited reports whether any rule limits the rate of commands.
+func (p *Policy) RateLimited() bool {
+	for _, r := range p.rules {
+		if r.maxRate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether every criterion of the rule matches the client.
+func (r *Rule) matches(cred *peer.Cred) bool {
+	if cred == nil {
+		return r.uid == nil && r.gid == nil && r.exe == ""
+	}
+	if r.uid != nil && *r.uid != cred.UID {
+		return false
+	}
+	if r.gid != nil && !cred.InGroup(*r.gid) {
+		return false
+	}
+	if r.exe != "" {
+		if ok, _ := path.Match(r.exe, cred.Exe); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsCommand reports whether the rule allows the command type.
+func (r *Rule) AllowsCommand(cmdType string) bool {
+	return r.commands == nil || slices.Contains(r.commands, cmdType)
+}
+
+// AllowsLayout reports whether the rule allows typing with the layout.
+func (r *Rule) AllowsLayout(name string) bool {
+	if r.layouts == nil {
+		return true
+	}
+	for _, pattern := range r.layouts {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedChord returns the denied chord pattern the keys form, if any. Keys
+// are every keycode held down once the press is done, modifiers included.
+func (r *Rule) DeniedChord(keys []uint16) (string, bool) {
+	held := make(map[uint16]bool, len(keys))
+	for _, code := range keys {
+		held[modifierClass(code)] = true
+	}
+
+	for _, cp := range r.denyChords {
+		if !slices.ContainsFunc(cp.keys, func(code uint16) bool { return held[code] }) {
+			continue
+		}
+		if !slices.ContainsFunc(cp.modifiers, func(code uint16) bool { return !held[code] }) {
+			return cp.text, true
+		}
+	}
+	return "", false
+}
+
+// MaxCommandsPerSecond returns the rate of commands the clients the rule
+// matches may send together, or 0 for no limit. The daemon's rate limiter
+// enforces it.
+func (r *Rule) MaxCommandsPerSecond() float64 {
+	return r.maxRate
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+const testPolicy = `
+deny_chords: [ctrl+alt+f*]
+rules:
+  - name: dictation
+    match: { uid: 1000, exe: /usr/bin/dict* }
+    commands: [type]
+    layouts: [us, "xkb:*"]
+    deny_chords: [super+l]
+  - name: input
+    match: { gid: 104 }
+    max_commands_per_second: 1
+`
+
+func TestMatch(t *testing.T) {
+	p, err := Parse("test.yaml", []byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cred *peer.Cred
+		want string
+	}{
+		{"uid and exe", &peer.Cred{UID: 1000, Exe: "/usr/bin/dictate"}, "dictation"},
+		{"other exe", &peer.Cred{UID: 1000, Exe: "/usr/bin/bash"}, ""},
+		{"supplementary group", &peer.Cred{UID: 1000, GID: 1000, Groups: []uint32{104}}, "input"},
+		{"primary group", &peer.Cred{UID: 0, GID: 104}, "input"},
+		{"unknown peer", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := p.Match(tt.cred)
+			if tt.want == "" {
+				if rule != nil {
+					t.Errorf("Match() = %s, want denied", rule.Name)
+				}
+				return
+			}
+			if rule == nil || rule.Name != tt.want {
+				t.Errorf("Match() = %v, want %s", rule, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule(t *testing.T) {
+	p, err := Parse("test.yaml", []byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	dictation := p.Match(&peer.Cred{UID: 1000, Exe: "/usr/bin/dictate"})
+	input := p.Match(&peer.Cred{GID: 104})
+
+	if !dictation.AllowsCommand("type") || dictation.AllowsCommand("key") {
+		t.Error("AllowsCommand() should only allow type")
+	}
+	if !input.AllowsCommand("key") {
+		t.Error("AllowsCommand() without commands should allow every command")
+	}
+	if !dictation.AllowsLayout("us") || !dictation.AllowsLayout("xkb:de") || dictation.AllowsLayout("fr") {
+		t.Error("AllowsLayout() mismatch")
+	}
+
+	if input.MaxCommandsPerSecond() != 1 || dictation.MaxCommandsPerSecond() != 0 {
+		t.Error("MaxCommandsPerSecond() mismatch")
+	}
+	if !p.RateLimited() {
+		t.Error("RateLimited() = false with a rule limiting the rate")
+	}
+}
+
+func TestDeniedChord(t *testing.T) {
+	p, err := Parse("test.yaml", []byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	dictation := p.Match(&peer.Cred{UID: 1000, Exe: "/usr/bin/dictate"})
+	input := p.Match(&peer.Cred{GID: 104})
+
+	tests := []struct {
+		name string
+		rule *Rule
+		keys []uint16
+		want string
+	}{
+		{"vt switch", input, []uint16{uinput.KeyLeftCtrl, uinput.KeyLeftAlt, uinput.KeyF2}, "ctrl+alt+f*"},
+		{"right side modifiers", input, []uint16{uinput.KeyRightCtrl, uinput.KeyRightAlt, uinput.KeyF12}, "ctrl+alt+f*"},
+		{"missing modifier", input, []uint16{uinput.KeyLeftCtrl, uinput.KeyF2}, ""},
+		{"other key", input, []uint16{uinput.KeyLeftCtrl, uinput.KeyLeftAlt, uinput.KeyT}, ""},
+		{"rule chord", dictation, []uint16{uinput.KeyLeftMeta, uinput.KeyL}, "super+l"},
+		{"rule chord elsewhere", input, []uint16{uinput.KeyLeftMeta, uinput.KeyL}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, denied := tt.rule.DeniedChord(tt.keys)
+			if got != tt.want || denied != (tt.want != "") {
+				t.Errorf("DeniedChord() = %q, %v, want %q", got, denied, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	p, err := Parse("test.yaml", []byte("default: allow\ndeny_chords: [ctrl+alt+delete]\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	rule := p.Match(nil)
+	if rule == nil {
+		t.Fatal("Match() with default allow = nil")
+	}
+	if !rule.AllowsCommand("key") || !rule.AllowsLayout("fr") {
+		t.Error("Default rule should allow every command and layout")
+	}
+	if _, denied := rule.DeniedChord([]uint16{uinput.KeyLeftCtrl, uinput.KeyLeftAlt, uinput.KeyDelete}); !denied {
+		t.Error("Default rule should deny the global chords")
+	}
+
+	p, err = Parse("test.yaml", []byte("rules: []\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if rule := p.Match(&peer.Cred{}); rule != nil {
+		t.Errorf("Match() with default deny = %s, want nil", rule.Name)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown field", "rules:\n  - name: a\n    allow: [type]\n", "field allow not found"},
+		{"invalid default", "default: maybe\n", "invalid default"},
+		{"unknown modifier", "deny_chords: [hyper+f1]\n", "unknown modifier"},
+		{"no matching key", "deny_chords: [ctrl+nokey*]\n", "no key matches"},
+		{"invalid layout pattern", "rules:\n  - name: a\n    layouts: [\"[\"]\n", "rule a: invalid pattern"},
+		{"uid and user", "rules:\n  - match: { uid: 0, user: root }\n", "rule #1: both uid and user"},
+		{"negative rate", "rules:\n  - max_commands_per_second: -1\n", "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("test.yaml", []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - match: { user: root, group: root }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Rules() != 1 || p.Match(&peer.Cred{UID: 0, GID: 0}) == nil {
+		t.Error("Load() rule should match root")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() of a missing file error = nil")
+	}
+}
@@ -10,33 +10,202 @@ const (
 	CommandType_Stream CommandType = "stream" // Stream text in real-time
 	CommandType_Key    CommandType = "key"    // Send a single key press
 	CommandType_Ping   CommandType = "ping"   // Health check
+	CommandType_Plan   CommandType = "plan"   // Resolve the keystrokes of a text without typing it
+
+	// Daemon introspection
+	CommandType_Status       CommandType = "status"       // Uptime, counters and device health
+	CommandType_Capabilities CommandType = "capabilities" // Version, layouts, devices and limits
+
+	// Typing jobs (type, stream and stream sessions), from any connection
+	CommandType_Jobs   CommandType = "jobs"   // List running jobs
+	CommandType_Cancel CommandType = "cancel" // Abort running jobs
+	CommandType_Pause  CommandType = "pause"  // Pause a job before its next character
+	CommandType_Resume CommandType = "resume" // Resume a paused job where it left off
+
+	// Held keys, released automatically when the connection closes
+	CommandType_KeyDown CommandType = "keydown" // Press and hold keys
+	CommandType_KeyUp   CommandType = "keyup"   // Release held keys
+
+	// Incremental streaming session (one per connection)
+	CommandType_StreamOpen  CommandType = "stream_open"  // Open a session
+	CommandType_StreamChunk CommandType = "stream_chunk" // Type a chunk as soon as it arrives
+	CommandType_StreamFlush CommandType = "stream_flush" // Wait until queued chunks are typed
+	CommandType_StreamClose CommandType = "stream_close" // Flush and end the session
+
+	// Virtual pointer (mouse); held buttons are released when the connection closes
+	CommandType_MouseMove   CommandType = "mouse_move"   // Move the pointer
+	CommandType_MouseClick  CommandType = "mouse_click"  // Click a button
+	CommandType_MouseDown   CommandType = "mouse_down"   // Press and hold a button
+	CommandType_MouseUp     CommandType = "mouse_up"     // Release a held button
+	CommandType_MouseScroll CommandType = "mouse_scroll" // Scroll the wheels
+
+	// Absolute pointer (tablet/touchscreen)
+	CommandType_AbsPointer CommandType = "abs_pointer" // Move or tap at screen coordinates
+
+	// Virtual gamepad, created on first use; held buttons and moved axes
+	// are reset when the connection closes
+	CommandType_GamepadButton CommandType = "gamepad_button" // Tap, press or release a button
+	CommandType_GamepadAxis   CommandType = "gamepad_axis"   // Set stick, trigger or D-pad values
 )
 
 // Command is the top-level message sent from client to daemon.
+// A connection may carry any number of newline-delimited commands; the
+// optional ID is echoed back in the matching Response so clients can
+// pipeline requests.
+//
+// Device optionally names a device declared in the daemon's devices.extra
+// config; without it, commands use the daemon's built-in devices.
+//
+// Commands driving devices run one at a time, in arrival order; those with
+// a higher Priority jump the queue, e.g. an urgent key chord waiting behind
+// a long text.
 type Command struct {
-	Type    CommandType     `json:"type"`
-	Payload json.RawMessage `json:"payload"`
+	ID       string          `json:"id,omitempty"`
+	Device   string          `json:"device,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	Type     CommandType     `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
 }
 
+// Fallback strategies for characters the layout cannot type.
+const (
+	Fallback_None       = "none"        // Drop the character
+	Fallback_UnicodeHex = "unicode_hex" // GTK/IBus entry: Ctrl+Shift+U, code point in hex, space
+)
+
 // TypePayload is the payload for the "type" command (batch typing).
 type TypePayload struct {
-	Text   string `json:"text"`
-	Layout string `json:"layout,omitempty"` // Optional, falls back to config default
+	Text     string `json:"text"`
+	Layout   string `json:"layout,omitempty"`   // Optional, falls back to config default
+	Fallback string `json:"fallback,omitempty"` // Optional, falls back to config default
+	Strict   bool   `json:"strict,omitempty"`   // Type nothing if any character would be dropped
+}
+
+// PlanPayload is the payload for the "plan" command (dry run of "type").
+type PlanPayload struct {
+	Text     string `json:"text"`
+	Layout   string `json:"layout,omitempty"`
+	Fallback string `json:"fallback,omitempty"`
 }
 
 // StreamPayload is the payload for the "stream" command (real-time typing).
 type StreamPayload struct {
 	Text      string `json:"text"`
 	Layout    string `json:"layout,omitempty"`
+	Fallback  string `json:"fallback,omitempty"`
+	Strict    bool   `json:"strict,omitempty"`     // Type nothing if any character would be dropped
 	DelayMs   int    `json:"delay_ms,omitempty"`   // Delay between words
 	CharDelay int    `json:"char_delay,omitempty"` // Delay between chars
 }
 
+// StreamOpenPayload is the payload for the "stream_open" command.
+// Layout, fallback and delays apply to every chunk of the session.
+type StreamOpenPayload struct {
+	Layout    string `json:"layout,omitempty"`
+	Fallback  string `json:"fallback,omitempty"`
+	DelayMs   int    `json:"delay_ms,omitempty"`   // Delay after whitespace
+	CharDelay int    `json:"char_delay,omitempty"` // Delay between chars
+}
+
+// StreamChunkPayload is the payload for the "stream_chunk" command.
+type StreamChunkPayload struct {
+	Text string `json:"text"`
+}
+
+// StreamFlushPayload is empty for stream_flush command.
+type StreamFlushPayload struct{}
+
+// StreamClosePayload is empty for stream_close command.
+type StreamClosePayload struct{}
+
 // KeyPayload is the payload for the "key" command (single keypress).
+// When Chord is set, Keycode and Modifier are ignored.
 type KeyPayload struct {
 	Keycode  uint16 `json:"keycode"`
 	Modifier string `json:"modifier,omitempty"` // "shift", "ctrl", "alt", "altgr"
+	Chord    string `json:"chord,omitempty"`    // Named keys, e.g. "ctrl+shift+t", "super+enter"
+}
+
+// KeyHoldPayload is the payload for the "keydown" and "keyup" commands.
+// Either Keycode or Chord must be set. Chord keys are pressed in order on
+// keydown and released in reverse order on keyup.
+type KeyHoldPayload struct {
+	Keycode uint16 `json:"keycode,omitempty"`
+	Chord   string `json:"chord,omitempty"` // Named keys, e.g. "shift", "ctrl+a"
+}
+
+// MouseMovePayload is the payload for the "mouse_move" command.
+// Moves are relative by default. Absolute moves go to the absolute pointer
+// when it is enabled in tablet mode. Otherwise they are emulated on the
+// relative pointer by homing it to the top-left corner first, so they are
+// only pixel-accurate with a flat pointer acceleration profile.
+type MouseMovePayload struct {
+	X        int32 `json:"x"`
+	Y        int32 `json:"y"`
+	Absolute bool  `json:"absolute,omitempty"`
+}
+
+// MouseButtonPayload is the payload for the "mouse_click", "mouse_down"
+// and "mouse_up" commands.
+type MouseButtonPayload struct {
+	Button string `json:"button,omitempty"` // "left" (default), "right", "middle", "side", "extra", ...
+	Count  int    `json:"count,omitempty"`  // Clicks for mouse_click (default: 1, 2 for a double click)
+}
+
+// MouseScrollPayload is the payload for the "mouse_scroll" command.
+// Positive values scroll up and right. Without HiRes, values are wheel
+// detents; with HiRes they are 1/120 of a detent for smooth scrolling.
+type MouseScrollPayload struct {
+	Vertical   int32 `json:"vertical,omitempty"`
+	Horizontal int32 `json:"horizontal,omitempty"`
+	HiRes      bool  `json:"hi_res,omitempty"`
+}
+
+// AbsPointerPayload is the payload for the "abs_pointer" command.
+// Coordinates are pixels of the configured coordinate space, or fractions
+// of it between 0 and 1 when Normalized is set.
+type AbsPointerPayload struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Normalized bool    `json:"normalized,omitempty"`
+	Action     string  `json:"action,omitempty"` // "move" (default) or "tap"
+	Button     string  `json:"button,omitempty"` // Tablet button to tap with (default: left)
+}
+
+// GamepadButtonPayload is the payload for the "gamepad_button" command.
+type GamepadButtonPayload struct {
+	Button string `json:"button"`           // "south", "east", "north", "west", "tl", "tr", "start", ...
+	Action string `json:"action,omitempty"` // "tap" (default), "press" or "release"
+}
+
+// GamepadAxisPayload is the payload for the "gamepad_axis" command.
+// All axes are updated in a single report. Sticks range from -32768 to
+// 32767, triggers from 0 to 255 and the D-pad from -1 to 1.
+type GamepadAxisPayload struct {
+	Axes map[string]int32 `json:"axes"` // e.g. {"left_x": 32767, "right_trigger": 255}
 }
 
 // PingPayload is empty for ping command.
 type PingPayload struct{}
+
+// StatusPayload is empty for status command.
+type StatusPayload struct{}
+
+// CapabilitiesPayload is empty for capabilities command.
+type CapabilitiesPayload struct{}
+
+// JobsPayload is empty for jobs command.
+type JobsPayload struct{}
+
+// JobPayload is the payload for the "pause" and "resume" commands.
+type JobPayload struct {
+	Job string `json:"job"` // ID of the job
+}
+
+// CancelPayload is the payload for the "cancel" command. Cancelled jobs
+// stop before their next character and release the keys their connection
+// holds.
+type CancelPayload struct {
+	Job string `json:"job,omitempty"` // ID of the job to cancel
+	All bool   `json:"all,omitempty"` // Cancel every running job instead
+}
@@ -1,10 +1,185 @@
 package protocol
 
+import "time"
+
 // Response is sent from daemon back to client.
 type Response struct {
+	ID      string `json:"id,omitempty"` // Echoes Command.ID
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
 	Message string `json:"message,omitempty"`
+	Code    string `json:"code,omitempty"`   // Machine-readable error code, if any
+	Layout  string `json:"layout,omitempty"` // Layout used by typing commands
+	Job     string `json:"job,omitempty"`    // ID of the job of typing commands
+
+	// Outcome of typing commands
+	Result *TypeResult `json:"result,omitempty"`
+
+	// Keystrokes of each character for plan commands
+	Plan []PlanStep `json:"plan,omitempty"`
+
+	// Daemon state and features for status and capabilities commands
+	Status       *Status       `json:"status,omitempty"`
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+
+	// Running jobs for jobs commands, the jobs addressed by cancel, pause
+	// and resume commands
+	Jobs []JobInfo `json:"jobs,omitempty"`
+}
+
+// Error codes of failures that clients may want to handle.
+const (
+	ErrorCode_UnsupportedChars = "unsupported_chars" // Strict typing rejected, see Result.Dropped
+	ErrorCode_Cancelled        = "cancelled"         // Job aborted by a cancel command
+	ErrorCode_Busy             = "busy"              // Command queue or connection limit full, retry later
+	ErrorCode_MessageTooLarge  = "message_too_large" // Command over the daemon's max_message_size
+	ErrorCode_PermissionDenied = "permission_denied" // Command not allowed by the daemon's policy
+	ErrorCode_RateLimited      = "rate_limited"      // Over a rate limit of the daemon or the client's policy rule
+)
+
+// TypeResult reports how the text of a typing command was typed.
+// Stream sessions report every chunk typed so far on stream_flush and
+// stream_close. When a strict command is rejected, Dropped lists the
+// characters the layout cannot type and nothing is typed.
+type TypeResult struct {
+	Typed     int          `json:"typed"`              // Characters typed, including by fallback
+	Fallback  []CharReport `json:"fallback,omitempty"` // Entered with the fallback strategy
+	Dropped   []CharReport `json:"dropped,omitempty"`  // Not typed at all
+	ElapsedMs int64        `json:"elapsed_ms"`
+}
+
+// CharReport identifies a character of the typed text.
+type CharReport struct {
+	Char     string `json:"char"`
+	Position int    `json:"position"` // Index of the character (not byte) in the text
+}
+
+// How a character is typed, as reported by plan commands.
+const (
+	PlanMethod_Layout   = "layout"   // With the layout's keys
+	PlanMethod_Fallback = "fallback" // With the fallback strategy
+	PlanMethod_Dropped  = "dropped"  // Not typed
+)
+
+// PlanStep lists the keystrokes a type command sends for one character.
+type PlanStep struct {
+	Char     string       `json:"char"`
+	Position int          `json:"position"`
+	Method   string       `json:"method"`
+	Keys     []PlannedKey `json:"keys,omitempty"`  // In order, e.g. dead key then base key
+	Error    string       `json:"error,omitempty"` // Why the layout cannot type the character
+}
+
+// PlannedKey is a single keystroke of a plan.
+type PlannedKey struct {
+	Keycode   uint16   `json:"keycode"`
+	Modifiers []string `json:"modifiers,omitempty"` // "ctrl", "alt", "shift", "altgr"
+	Chord     string   `json:"chord"`               // e.g. "shift+a"
+}
+
+// States of a typing job.
+const (
+	JobState_Queued  = "queued" // Waiting for commands of other clients
+	JobState_Running = "running"
+	JobState_Paused  = "paused"
+)
+
+// JobInfo describes a running typing job and its progress. Offsets count
+// characters (runes), not bytes; stream sessions count the characters of
+// the chunks received so far.
+type JobInfo struct {
+	ID        string      `json:"id"`
+	Type      CommandType `json:"type"` // Command that started the job
+	StartedAt time.Time   `json:"started_at"`
+	State     string      `json:"state"`
+	Offset    int         `json:"offset"` // Characters of the text typed so far
+	Total     int         `json:"total"`  // Characters of the text
+	Percent   float64     `json:"percent"`
+	Peer      *PeerInfo   `json:"peer,omitempty"` // Client that started the job, if identified
+}
+
+// PeerInfo identifies the process of a client, from the credentials the
+// kernel reports for its socket and from /proc.
+type PeerInfo struct {
+	PID     int32    `json:"pid"`
+	UID     uint32   `json:"uid"`
+	GID     uint32   `json:"gid"`
+	Exe     string   `json:"exe,omitempty"`
+	Cmdline []string `json:"cmdline,omitempty"`
+}
+
+// ClientInfo describes an open client connection.
+type ClientInfo struct {
+	Peer        *PeerInfo `json:"peer,omitempty"` // Nil if the client could not be identified
+	ConnectedAt time.Time `json:"connected_at"`
+	Commands    uint64    `json:"commands"` // Commands sent on the connection
+}
+
+// Status reports the state of the daemon.
+type Status struct {
+	Version        string         `json:"version"`
+	StartedAt      time.Time      `json:"started_at"`
+	UptimeMs       int64          `json:"uptime_ms"`
+	CommandsServed uint64         `json:"commands_served"` // Since startup, this one included
+	Connections    int            `json:"connections"`     // Open client connections
+	Healthy        bool           `json:"healthy"`         // Every created device works
+	Devices        []DeviceStatus `json:"devices"`
+	Jobs           []JobInfo      `json:"jobs,omitempty"`    // Running typing jobs
+	Clients        []ClientInfo   `json:"clients,omitempty"` // Open connections, oldest first
+
+	// Counters of the rate limits, if any: daemon-wide, then per client UID
+	RateLimits []RateLimitStatus `json:"rate_limits,omitempty"`
+}
+
+// RateLimitStatus reports the limits of a rate limit scope and what they
+// let through and held back since startup.
+type RateLimitStatus struct {
+	UID                 *uint32 `json:"uid,omitempty"`                   // Nil for the daemon-wide and rule limits
+	Rule                string  `json:"rule,omitempty"`                  // Policy rule of max_commands_per_second limits
+	CommandsPerSecond   float64 `json:"commands_per_second,omitempty"`   // 0 for no limit
+	KeystrokesPerSecond float64 `json:"keystrokes_per_second,omitempty"` // 0 for no limit
+	Commands            uint64  `json:"commands"`                        // Commands let through
+	Keystrokes          uint64  `json:"keystrokes"`                      // Keystrokes let through
+	Delayed             uint64  `json:"delayed"`                         // Commands and keystrokes held back
+	Rejected            uint64  `json:"rejected"`                        // Commands rejected
+}
+
+// DeviceStatus reports the health of a virtual device.
+type DeviceStatus struct {
+	DeviceInfo
+	Created bool   `json:"created"` // False for devices created on first use
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"` // Why the device is unhealthy
+}
+
+// DeviceInfo describes a virtual device of the daemon.
+type DeviceInfo struct {
+	Name    string `json:"name"`              // Name reported to the system
+	Profile string `json:"profile"`           // "keyboard", "pointer", "tablet", ...
+	Builtin bool   `json:"builtin,omitempty"` // Used by commands without a device field
+}
+
+// Capabilities describes what the daemon supports, so that clients can
+// check for features instead of guessing.
+type Capabilities struct {
+	Version         string        `json:"version"`
+	Commands        []CommandType `json:"commands"` // Commands served with the current devices
+	DefaultLayout   string        `json:"default_layout"`
+	LayoutDetect    bool          `json:"layout_detect"` // Commands without layout use the session's
+	Layouts         []string      `json:"layouts"`       // Loaded layouts; "xkb:..." layouts load on demand
+	Fallbacks       []string      `json:"fallbacks"`
+	DefaultFallback string        `json:"default_fallback"`
+	Devices         []DeviceInfo  `json:"devices"`
+	Limits          Limits        `json:"limits"`
+}
+
+// Limits are the daemon's performance settings.
+type Limits struct {
+	MaxMessageSize    int `json:"max_message_size"`
+	BufferSize        int `json:"buffer_size"`
+	MaxConcurrentCmds int `json:"max_concurrent_cmds"`
+	StreamDelayMs     int `json:"stream_delay_ms"`
+	CharDelayMs       int `json:"char_delay_ms"`
 }
 
 // NewSuccessResponse creates a successful response.
@@ -0,0 +1,109 @@
+// Package ratelimit implements the token buckets limiting how fast clients
+// may drive the daemon.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket: it holds up to burst tokens and refills at
+// rate tokens per second. The zero value, and a nil bucket, allow
+// everything.
+type Bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	now func() time.Time // Replaced by tests
+}
+
+// New returns a full bucket refilling at rate tokens per second. A burst
+// below 1 is raised to max(rate, 1) tokens, e.g. one second's worth.
+func New(rate, burst float64) *Bucket {
+	if burst < 1 {
+		burst = max(rate, 1)
+	}
+	return &Bucket{rate: rate, burst: burst, tokens: burst, now: time.Now}
+}
+
+// Burst returns the most tokens the bucket holds, or 0 for a bucket
+// allowing everything.
+func (b *Bucket) Burst() float64 {
+	if b == nil || b.rate <= 0 {
+		return 0
+	}
+	return b.burst
+}
+
+// Allow takes n tokens if the bucket holds them, and reports whether it
+// did.
+func (b *Bucket) Allow(n float64) bool {
+	_, ok := b.Reserve(n, 0)
+	return ok
+}
+
+// Reserve takes n tokens if the bucket holds them within maxWait, and
+// returns how long the caller must wait before using them. Tokens taken
+// ahead of time are owed by the bucket, so callers are served in order.
+// If the wait would exceed maxWait, nothing is taken and ok is false.
+func (b *Bucket) Reserve(n float64, maxWait time.Duration) (wait time.Duration, ok bool) {
+	if b == nil || b.rate <= 0 {
+		return 0, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens < n {
+		wait = time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+		if wait > maxWait {
+			return 0, false
+		}
+	}
+	b.tokens -= n
+	return wait, true
+}
+
+// Delay returns how long Reserve(n) would wait, without taking anything.
+func (b *Bucket) Delay(n float64) time.Duration {
+	if b == nil || b.rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Refund gives back n tokens taken by Reserve, up to the burst.
+func (b *Bucket) Refund(n float64) {
+	if b == nil || b.rate <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens = min(b.burst, b.tokens+n)
+}
+
+// refill adds the tokens earned since the last call. The caller must hold
+// b.mu.
+func (b *Bucket) refill() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock tests move by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestBucket(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b := New(2, 3)
+	b.now = clock.now
+
+	// The burst is available at once
+	for i := 0; i < 3; i++ {
+		if !b.Allow(1) {
+			t.Fatalf("Allow() #%d = false, want true", i)
+		}
+	}
+	if b.Allow(1) {
+		t.Error("Allow() over the burst = true, want false")
+	}
+
+	// Tokens come back at the rate, up to the burst
+	clock.t = clock.t.Add(500 * time.Millisecond)
+	if !b.Allow(1) || b.Allow(1) {
+		t.Error("Expected a single token after 500ms at 2/s")
+	}
+	clock.t = clock.t.Add(time.Hour)
+	if b.Allow(4) {
+		t.Error("Allow(4) over the burst = true, want false")
+	}
+	if !b.Allow(3) {
+		t.Error("Allow(3) of a full bucket = false, want true")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b := New(10, 1)
+	b.now = clock.now
+
+	if wait, ok := b.Reserve(1, 0); !ok || wait != 0 {
+		t.Errorf("Reserve() of a full bucket = %v, %v, want 0, true", wait, ok)
+	}
+
+	// Reservations queue up behind each other
+	if wait, ok := b.Reserve(1, time.Second); !ok || wait != 100*time.Millisecond {
+		t.Errorf("Reserve() = %v, %v, want 100ms, true", wait, ok)
+	}
+	if wait, ok := b.Reserve(1, time.Second); !ok || wait != 200*time.Millisecond {
+		t.Errorf("Reserve() = %v, %v, want 200ms, true", wait, ok)
+	}
+
+	// Nothing is taken when the wait is too long
+	if _, ok := b.Reserve(1, 250*time.Millisecond); ok {
+		t.Error("Reserve() over maxWait = true, want false")
+	}
+	clock.t = clock.t.Add(300 * time.Millisecond)
+	if wait, ok := b.Reserve(1, 0); !ok || wait != 0 {
+		t.Errorf("Reserve() once refilled = %v, %v, want 0, true", wait, ok)
+	}
+}
+
+func TestBucketUnlimited(t *testing.T) {
+	var nilBucket *Bucket
+	for _, b := range []*Bucket{nilBucket, {}, New(0, 0)} {
+		for i := 0; i < 100; i++ {
+			if !b.Allow(1) {
+				t.Fatal("Allow() of an unlimited bucket = false")
+			}
+		}
+	}
+}
+
+func TestNewBurst(t *testing.T) {
+	if b := New(10, 0); b.burst != 10 {
+		t.Errorf("New(10, 0) burst = %v, want 10", b.burst)
+	}
+	if b := New(0.5, 0); b.burst != 1 {
+		t.Errorf("New(0.5, 0) burst = %v, want 1", b.burst)
+	}
+}
+
+func TestDelayRefund(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b := New(10, 2)
+	b.now = clock.now
+
+	if d := b.Delay(2); d != 0 {
+		t.Errorf("Delay() of a full bucket = %v, want 0", d)
+	}
+	if d := b.Delay(3); d != 100*time.Millisecond {
+		t.Errorf("Delay(3) = %v, want 100ms", d)
+	}
+
+	// Delay takes nothing, Refund gives back what Reserve took
+	if !b.Allow(2) {
+		t.Fatal("Allow(2) of a full bucket = false, want true")
+	}
+	b.Refund(2)
+	if !b.Allow(2) {
+		t.Error("Allow(2) after Refund = false, want true")
+	}
+
+	// Refunds never exceed the burst
+	b.Refund(5)
+	if b.Allow(3) {
+		t.Error("Allow(3) over the burst after Refund = true, want false")
+	}
+}
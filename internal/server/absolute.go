@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// Actions of the abs_pointer command
+const (
+	absActionMove = "move"
+	absActionTap  = "tap"
+)
+
+// errNoAbsolute is returned by abs_pointer when no absolute device exists.
+var errNoAbsolute = errors.New("absolute pointer device not available")
+
+// SetAbsolute attaches the absolute pointer device used by abs_pointer.
+// Its mode and coordinate space are read from the devices.absolute config.
+func (s *Server) SetAbsolute(absolute uinput.DeviceInterface) {
+	s.absolute = absolute
+}
+
+// handleAbsPointer moves to or taps at screen coordinates.
+func (s *Server) handleAbsPointer(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	abs, err := s.absoluteDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	var p protocol.AbsPointerPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid abs pointer payload: %w", err)
+	}
+
+	x, err := absCoordinate(p.X, abs.Width, p.Normalized)
+	if err != nil {
+		return fmt.Errorf("x: %w", err)
+	}
+	y, err := absCoordinate(p.Y, abs.Height, p.Normalized)
+	if err != nil {
+		return fmt.Errorf("y: %w", err)
+	}
+
+	action := p.Action
+	if action == "" {
+		action = absActionMove
+	}
+
+	log.Debug("absolute pointer", "action", action, "x", x, "y", y, "profile", abs.Profile)
+
+	dev := abs.Device
+	touchscreen := abs.Profile == uinput.ProfileTouchscreen
+
+	switch action {
+	case absActionMove:
+		if touchscreen {
+			return fmt.Errorf("a touchscreen can only tap")
+		}
+		return writeAbs(dev, uinput.NewAbsEvent(uinput.AbsX, x), uinput.NewAbsEvent(uinput.AbsY, y), uinput.NewSynEvent())
+
+	case absActionTap:
+		if touchscreen {
+			// Touch down at the position, then lift
+			if err := writeAbs(dev,
+				uinput.NewAbsEvent(uinput.AbsX, x),
+				uinput.NewAbsEvent(uinput.AbsY, y),
+				uinput.NewKeyEvent(uinput.BtnTouch, true),
+				uinput.NewSynEvent(),
+			); err != nil {
+				return err
+			}
+			return writeAbs(dev, uinput.NewKeyEvent(uinput.BtnTouch, false), uinput.NewSynEvent())
+		}
+
+		button, err := tabletButton(p.Button)
+		if err != nil {
+			return err
+		}
+
+		// Move first so the click lands on the new position
+		if err := writeAbs(dev, uinput.NewAbsEvent(uinput.AbsX, x), uinput.NewAbsEvent(uinput.AbsY, y), uinput.NewSynEvent()); err != nil {
+			return err
+		}
+		if err := writeKey(dev, button, true); err != nil {
+			return fmt.Errorf("button press %d: %w", button, err)
+		}
+		if err := writeKey(dev, button, false); err != nil {
+			return fmt.Errorf("button release %d: %w", button, err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown abs pointer action: %s", p.Action)
+	}
+}
+
+// absoluteTablet returns the absolute pointer that absolute mouse moves go
+// to: the configured one in tablet mode, unless the command targets a named
+// device. It returns nil when moves must be emulated on the relative pointer.
+func (s *Server) absoluteTablet(ctx context.Context) *NamedDevice {
+	if targetFromCtx(ctx) != nil || s.absolute == nil {
+		return nil
+	}
+
+	abs, err := s.absoluteDevice(ctx)
+	if err != nil || abs.Profile != uinput.ProfileTablet {
+		return nil
+	}
+	return abs
+}
+
+// moveAbsolute moves an absolute pointer to pixel coordinates.
+func moveAbsolute(abs *NamedDevice, x, y int32) error {
+	ax, err := absCoordinate(float64(x), abs.Width, false)
+	if err != nil {
+		return fmt.Errorf("x: %w", err)
+	}
+	ay, err := absCoordinate(float64(y), abs.Height, false)
+	if err != nil {
+		return fmt.Errorf("y: %w", err)
+	}
+	return writeAbs(abs.Device, uinput.NewAbsEvent(uinput.AbsX, ax), uinput.NewAbsEvent(uinput.AbsY, ay), uinput.NewSynEvent())
+}
+
+// writeAbs writes events to an absolute pointer device.
+func writeAbs(dev uinput.DeviceInterface, events ...*uinput.InputEvent) error {
+	for _, ev := range events {
+		if err := dev.WriteEvent(ev); err != nil {
+			return fmt.Errorf("absolute pointer: %w", err)
+		}
+	}
+	return nil
+}
+
+// absCoordinate converts a pixel or normalized coordinate into an axis
+// value within 0..size-1.
+func absCoordinate(v float64, size int32, normalized bool) (int32, error) {
+	if math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid coordinate")
+	}
+
+	if normalized {
+		if v < 0 || v > 1 {
+			return 0, fmt.Errorf("normalized coordinate %g out of range 0-1", v)
+		}
+		return int32(math.Round(v * float64(size-1))), nil
+	}
+
+	if v < 0 || v > float64(size-1) {
+		return 0, fmt.Errorf("coordinate %g out of range 0-%d", v, size-1)
+	}
+	return int32(math.Round(v)), nil
+}
+
+// tabletButton resolves a button name to one of the tablet's buttons.
+func tabletButton(name string) (uint16, error) {
+	if name == "" {
+		return uinput.BtnLeft, nil
+	}
+
+	button, ok := uinput.LookupButton(name)
+	if !ok || (button != uinput.BtnLeft && button != uinput.BtnRight && button != uinput.BtnMiddle) {
+		return 0, fmt.Errorf("unsupported tablet button: %s", name)
+	}
+	return button, nil
+}
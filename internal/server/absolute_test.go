@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/config"
+	layoutMocks "github.com/bnema/uinputd-go/internal/layouts/mocks"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleAbsPointer(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		payload       protocol.AbsPointerPayload
+		expected      [][3]int32
+		expectedError bool
+	}{
+		{
+			name:    "tablet move in pixels",
+			mode:    "tablet",
+			payload: protocol.AbsPointerPayload{X: 100, Y: 200},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, 100},
+				{uinput.EvAbs, uinput.AbsY, 200},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "tablet normalized tap with right button",
+			mode:    "tablet",
+			payload: protocol.AbsPointerPayload{X: 0.5, Y: 1, Normalized: true, Action: "tap", Button: "right"},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, 960},
+				{uinput.EvAbs, uinput.AbsY, 1079},
+				{uinput.EvSyn, uinput.SynReport, 0},
+				{uinput.EvKey, uinput.BtnRight, uinput.KeyPress},
+				{uinput.EvSyn, uinput.SynReport, 0},
+				{uinput.EvKey, uinput.BtnRight, uinput.KeyRelease},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "touchscreen tap",
+			mode:    "touchscreen",
+			payload: protocol.AbsPointerPayload{X: 10, Y: 20, Action: "tap"},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, 10},
+				{uinput.EvAbs, uinput.AbsY, 20},
+				{uinput.EvKey, uinput.BtnTouch, uinput.KeyPress},
+				{uinput.EvSyn, uinput.SynReport, 0},
+				{uinput.EvKey, uinput.BtnTouch, uinput.KeyRelease},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "touchscreen cannot move",
+			mode:          "touchscreen",
+			payload:       protocol.AbsPointerPayload{X: 10, Y: 20},
+			expectedError: true,
+		},
+		{
+			name:          "pixel out of range",
+			mode:          "tablet",
+			payload:       protocol.AbsPointerPayload{X: 1920, Y: 0},
+			expectedError: true,
+		},
+		{
+			name:          "normalized out of range",
+			mode:          "tablet",
+			payload:       protocol.AbsPointerPayload{X: 0.5, Y: 1.5, Normalized: true},
+			expectedError: true,
+		},
+		{
+			name:          "unsupported tablet button",
+			mode:          "tablet",
+			payload:       protocol.AbsPointerPayload{Action: "tap", Button: "side"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown action",
+			mode:          "tablet",
+			payload:       protocol.AbsPointerPayload{Action: "drag"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			absolute := uinputMocks.NewMockDeviceInterface(t)
+			absolute.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+				events = append(events, *args.Get(0).(*uinput.InputEvent))
+			}).Return(nil).Maybe()
+
+			server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+			server.cfg.Devices.Absolute = config.AbsoluteConfig{
+				Enabled: true,
+				Mode:    tt.mode,
+				Width:   1920,
+				Height:  1080,
+			}
+			server.SetAbsolute(absolute)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleAbsPointer(context.Background(), payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, events)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, eventCodes(events))
+		})
+	}
+}
+
+func TestHandleAbsPointerWithoutDevice(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+
+	err := server.handleAbsPointer(context.Background(), json.RawMessage(`{"x":1,"y":1}`))
+	assert.ErrorIs(t, err, errNoAbsolute)
+}
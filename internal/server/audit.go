@@ -0,0 +1,72 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/bnema/uinputd-go/internal/audit"
+	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// auditPayload holds the payload fields the audit log records. Commands
+// without them record none.
+type auditPayload struct {
+	Text     string `json:"text"`
+	Layout   string `json:"layout"`
+	Chord    string `json:"chord"`
+	Keycode  uint16 `json:"keycode"`
+	Modifier string `json:"modifier"`
+}
+
+// openAudit opens the configured audit log.
+func openAudit(cfg config.AuditConfig) (*audit.Log, error) {
+	return audit.Open(audit.Options{
+		Path:         cfg.Path,
+		KeyPath:      cfg.KeyPath,
+		Text:         cfg.Text,
+		PreviewChars: cfg.PreviewChars,
+		MaxSize:      int64(cfg.MaxSizeMB) << 20,
+		MaxBackups:   cfg.MaxBackups,
+	})
+}
+
+// recordAudit appends a handled command and its outcome to the audit log,
+// if enabled. Failures are logged but do not fail the command.
+func (s *Server) recordAudit(ctx context.Context, cc *clientConn, cmd *protocol.Command, resp *protocol.Response) {
+	if s.audit == nil {
+		return
+	}
+
+	var p auditPayload
+	_ = json.Unmarshal(cmd.Payload, &p)
+
+	entry := audit.Entry{
+		Command: string(cmd.Type),
+		ID:      cmd.ID,
+		Device:  cmd.Device,
+		Layout:  cmp.Or(resp.Layout, p.Layout),
+		Keys:    p.Chord,
+		Text:    p.Text,
+		Success: resp.Success,
+		Code:    resp.Code,
+		Error:   resp.Error,
+	}
+	if p.Keycode != 0 {
+		entry.Keys = strconv.Itoa(int(p.Keycode))
+		if p.Modifier != "" {
+			entry.Keys = p.Modifier + "+" + entry.Keys
+		}
+	}
+	if cred := cc.peer; cred != nil {
+		entry.UID, entry.GID = &cred.UID, &cred.GID
+		entry.PID, entry.Exe = cred.PID, cred.Exe
+	}
+
+	if err := s.audit.Record(entry); err != nil {
+		logger.LogFromCtx(ctx).Warn("failed to write audit log", "error", err)
+	}
+}
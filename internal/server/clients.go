@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// clientList tracks the open connections for status commands. The zero
+// value is an empty list.
+type clientList struct {
+	mu         sync.Mutex
+	conns      []*clientConn // In connection order
+	lastActive time.Time     // When a connection was last accepted or closed
+}
+
+func (l *clientList) add(cc *clientConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conns = append(l.conns, cc)
+}
+
+func (l *clientList) remove(cc *clientConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.conns, cc); i >= 0 {
+		l.conns = slices.Delete(l.conns, i, i+1)
+	}
+	l.lastActive = time.Now()
+}
+
+// touch records a connection accepted but not yet added, so that the
+// server is not considered idle meanwhile.
+func (l *clientList) touch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastActive = time.Now()
+}
+
+func (l *clientList) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+// info describes the open connections, oldest first.
+func (l *clientList) info() []protocol.ClientInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clients := make([]protocol.ClientInfo, len(l.conns))
+	for i, cc := range l.conns {
+		clients[i] = protocol.ClientInfo{
+			Peer:        peerInfo(cc.peer),
+			ConnectedAt: cc.connectedAt,
+			Commands:    cc.commands.Load(),
+		}
+	}
+	return clients
+}
+
+// idleTime returns how long the server has been without clients and jobs,
+// or 0 if it is in use.
+func (s *Server) idleTime(now time.Time) time.Duration {
+	if len(s.runningJobs()) > 0 {
+		return 0
+	}
+
+	s.clients.mu.Lock()
+	defer s.clients.mu.Unlock()
+
+	if len(s.clients.conns) > 0 {
+		return 0
+	}
+	since := s.started
+	if s.clients.lastActive.After(since) {
+		since = s.clients.lastActive
+	}
+	return now.Sub(since)
+}
+
+// exitWhenIdle calls stop once the server has been idle for timeout. With
+// socket activation, connections made after that wait in the socket's
+// backlog until systemd starts the daemon again.
+func (s *Server) exitWhenIdle(ctx context.Context, stop context.CancelFunc, timeout time.Duration) {
+	ticker := time.NewTicker(min(max(timeout/10, 10*time.Millisecond), time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if s.idleTime(now) >= timeout {
+				logger.LogFromCtx(ctx).Info("idle, exiting until the next connection", "idle_exit_ms", s.cfg.Socket.IdleExitMs)
+				stop()
+				return
+			}
+		}
+	}
+}
+
+// identify reads the identity of the client at the other end of conn for
+// the policy, rate limits, audit log and status commands, and returns ctx
+// with a logger carrying it.
+func (s *Server) identify(ctx context.Context, conn net.Conn, cc *clientConn) context.Context {
+	log := logger.LogFromCtx(ctx)
+
+	cred, err := peer.FromConn(conn)
+	if err != nil {
+		log.Warn("failed to identify client", "error", err)
+	}
+	cc.peer = cred
+	if s.policy != nil {
+		cc.rule = s.policy.Match(cred)
+	}
+
+	log = log.With(peerAttrs(cred)...)
+	if cred != nil {
+		log.Debug("client connected", "gid", cred.GID, "cmdline", cred.Cmdline)
+	} else {
+		log.Debug("client connected")
+	}
+	return logger.WithLogger(ctx, log)
+}
+
+// peerAttrs returns the log attributes identifying a client.
+func peerAttrs(cred *peer.Cred) []any {
+	if cred == nil {
+		return []any{"peer", "unknown"}
+	}
+	return []any{"pid", cred.PID, "uid", cred.UID, "exe", cred.Exe}
+}
+
+// peerInfo converts a client identity for status commands.
+func peerInfo(cred *peer.Cred) *protocol.PeerInfo {
+	if cred == nil {
+		return nil
+	}
+	return &protocol.PeerInfo{
+		PID:     cred.PID,
+		UID:     cred.UID,
+		GID:     cred.GID,
+		Exe:     cred.Exe,
+		Cmdline: cred.Cmdline,
+	}
+}
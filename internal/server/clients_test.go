@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// newIdleServer returns a server started as if by socket activation, with
+// the given idle timeout, and the channel Start returns on.
+func newIdleServer(t *testing.T, idleExitMs int) (*Server, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.cfg.Socket.IdleExitMs = idleExitMs
+	server.listener = listener
+	server.activated = true
+	server.started = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	return server, done
+}
+
+func TestExitWhenIdle(t *testing.T) {
+	_, done := newIdleServer(t, 50)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not exit when idle")
+	}
+}
+
+func TestExitWhenIdleWaitsForClients(t *testing.T) {
+	server, done := newIdleServer(t, 50)
+
+	conn, err := net.Dial("unix", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("server exited with a client connected")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	conn.Close()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not exit once the client left")
+	}
+}
+
+func TestStartWaitsForDevicesToSettle(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.cfg.Devices.SettleMs = 200
+	server.listener = listener
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	go server.Start(ctx)
+
+	// The connection waits in the backlog until the devices settled
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return server.clients.len() > 0 }, 2*time.Second, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestIdleTime(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.started = time.Now().Add(-time.Minute)
+	now := time.Now()
+
+	assert.InDelta(t, time.Minute, server.idleTime(now), float64(time.Second))
+
+	cc := &clientConn{}
+	server.clients.add(cc)
+	assert.Zero(t, server.idleTime(now))
+
+	server.clients.remove(cc)
+	assert.Less(t, server.idleTime(time.Now()), time.Second)
+
+	_, j := server.startJob(context.Background(), cc, "type")
+	assert.Zero(t, server.idleTime(time.Now().Add(time.Hour)))
+	j.end()
+}
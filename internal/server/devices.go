@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// NamedDevice is an additional virtual device that commands target by
+// name with the command's "device" field.
+type NamedDevice struct {
+	Name    string
+	Profile uinput.Profile
+	Device  uinput.DeviceInterface
+
+	// Coordinate space of tablet and touchscreen profiles
+	Width  int32
+	Height int32
+}
+
+// commandProfiles lists the device profiles each device-specific command
+// can target. Commands missing from the map (ping) accept any device.
+var commandProfiles = map[protocol.CommandType][]uinput.Profile{
+	protocol.CommandType_Type:          {uinput.ProfileKeyboard},
+	protocol.CommandType_Stream:        {uinput.ProfileKeyboard},
+	protocol.CommandType_Key:           {uinput.ProfileKeyboard},
+	protocol.CommandType_KeyDown:       {uinput.ProfileKeyboard},
+	protocol.CommandType_KeyUp:         {uinput.ProfileKeyboard},
+	protocol.CommandType_StreamOpen:    {uinput.ProfileKeyboard},
+	protocol.CommandType_StreamChunk:   {uinput.ProfileKeyboard},
+	protocol.CommandType_StreamFlush:   {uinput.ProfileKeyboard},
+	protocol.CommandType_StreamClose:   {uinput.ProfileKeyboard},
+	protocol.CommandType_MouseMove:     {uinput.ProfilePointer},
+	protocol.CommandType_MouseClick:    {uinput.ProfilePointer},
+	protocol.CommandType_MouseDown:     {uinput.ProfilePointer},
+	protocol.CommandType_MouseUp:       {uinput.ProfilePointer},
+	protocol.CommandType_MouseScroll:   {uinput.ProfilePointer},
+	protocol.CommandType_AbsPointer:    {uinput.ProfileTablet, uinput.ProfileTouchscreen},
+	protocol.CommandType_GamepadButton: {uinput.ProfileGamepad},
+	protocol.CommandType_GamepadAxis:   {uinput.ProfileGamepad},
+}
+
+// AddDevice registers a named device. It must be called before Start.
+func (s *Server) AddDevice(d NamedDevice) error {
+	if d.Name == "" {
+		return errors.New("device name is required")
+	}
+	if d.Device == nil {
+		return fmt.Errorf("device %s: no device", d.Name)
+	}
+	if _, exists := s.named[d.Name]; exists {
+		return fmt.Errorf("device %s: duplicate name", d.Name)
+	}
+
+	if s.named == nil {
+		s.named = make(map[string]*NamedDevice)
+	}
+	s.named[d.Name] = &d
+	return nil
+}
+
+// targetKey is the context key of the device a command targets.
+type targetKey struct{}
+
+// routeCommand resolves the device named by cmd and returns ctx carrying
+// it. Commands without a device name use the built-in devices.
+func (s *Server) routeCommand(ctx context.Context, cmd *protocol.Command) (context.Context, error) {
+	if cmd.Device == "" {
+		return ctx, nil
+	}
+
+	target, ok := s.named[cmd.Device]
+	if !ok {
+		return nil, fmt.Errorf("unknown device: %s", cmd.Device)
+	}
+
+	if profiles, ok := commandProfiles[cmd.Type]; ok && !slices.Contains(profiles, target.Profile) {
+		return nil, fmt.Errorf("device %s (%s) does not support %s", target.Name, target.Profile, cmd.Type)
+	}
+
+	return context.WithValue(ctx, targetKey{}, target), nil
+}
+
+// targetFromCtx returns the named device a command targets, if any.
+func targetFromCtx(ctx context.Context) *NamedDevice {
+	target, _ := ctx.Value(targetKey{}).(*NamedDevice)
+	return target
+}
+
+// keyboard returns the keyboard a command acts on.
+func (s *Server) keyboard(ctx context.Context) uinput.DeviceInterface {
+	if target := targetFromCtx(ctx); target != nil {
+		return target.Device
+	}
+	return s.device
+}
+
+// pointerDevice returns the pointer a command acts on.
+func (s *Server) pointerDevice(ctx context.Context) (uinput.DeviceInterface, error) {
+	if target := targetFromCtx(ctx); target != nil {
+		return target.Device, nil
+	}
+	if s.pointer == nil {
+		return nil, errNoPointer
+	}
+	return s.pointer, nil
+}
+
+// absoluteDevice returns the absolute pointer a command acts on, with its
+// profile and coordinate space.
+func (s *Server) absoluteDevice(ctx context.Context) (*NamedDevice, error) {
+	if target := targetFromCtx(ctx); target != nil {
+		return target, nil
+	}
+	if s.absolute == nil {
+		return nil, errNoAbsolute
+	}
+
+	abs := s.cfg.Devices.Absolute
+	return &NamedDevice{
+		Profile: uinput.Profile(abs.Mode),
+		Device:  s.absolute,
+		Width:   abs.Width,
+		Height:  abs.Height,
+	}, nil
+}
+
+// settle waits for libinput and the compositor to open devices just
+// created: events written to a device before then are dropped.
+func (s *Server) settle(ctx context.Context) error {
+	return sleep(ctx, time.Duration(s.cfg.Devices.SettleMs)*time.Millisecond)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	layoutMocks "github.com/bnema/uinputd-go/internal/layouts/mocks"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAddDevice(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+	device := uinputMocks.NewMockDeviceInterface(t)
+
+	assert.NoError(t, server.AddDevice(NamedDevice{Name: "macros", Profile: uinput.ProfileKeyboard, Device: device}))
+	assert.Error(t, server.AddDevice(NamedDevice{Name: "macros", Profile: uinput.ProfileKeyboard, Device: device}), "duplicate name")
+	assert.Error(t, server.AddDevice(NamedDevice{Profile: uinput.ProfileKeyboard, Device: device}), "missing name")
+	assert.Error(t, server.AddDevice(NamedDevice{Name: "empty", Profile: uinput.ProfileKeyboard}), "missing device")
+}
+
+func TestHandleCommandRouting(t *testing.T) {
+	tests := []struct {
+		name          string
+		cmd           protocol.Command
+		setupMocks    func(builtin, macros, pad *uinputMocks.MockDeviceInterface)
+		expectedError bool
+	}{
+		{
+			name: "no device uses the built-in keyboard",
+			cmd:  protocol.Command{Type: protocol.CommandType_Key, Payload: json.RawMessage(`{"keycode":28}`)},
+			setupMocks: func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {
+				builtin.On("SendKey", mock.Anything, uint16(28)).Return(nil)
+			},
+		},
+		{
+			name: "named keyboard",
+			cmd:  protocol.Command{Device: "macros", Type: protocol.CommandType_Key, Payload: json.RawMessage(`{"keycode":28}`)},
+			setupMocks: func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {
+				macros.On("SendKey", mock.Anything, uint16(28)).Return(nil)
+			},
+		},
+		{
+			name: "named gamepad",
+			cmd:  protocol.Command{Device: "pad", Type: protocol.CommandType_GamepadButton, Payload: json.RawMessage(`{"button":"south"}`)},
+			setupMocks: func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {
+				pad.On("WriteEvent", mock.Anything).Return(nil).Times(4)
+			},
+		},
+		{
+			name:          "unknown device",
+			cmd:           protocol.Command{Device: "nope", Type: protocol.CommandType_Key, Payload: json.RawMessage(`{"keycode":28}`)},
+			setupMocks:    func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {},
+			expectedError: true,
+		},
+		{
+			name:          "command not supported by the device profile",
+			cmd:           protocol.Command{Device: "pad", Type: protocol.CommandType_Key, Payload: json.RawMessage(`{"keycode":28}`)},
+			setupMocks:    func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {},
+			expectedError: true,
+		},
+		{
+			name:       "ping accepts any device",
+			cmd:        protocol.Command{Device: "pad", Type: protocol.CommandType_Ping, Payload: json.RawMessage(`{}`)},
+			setupMocks: func(builtin, macros, pad *uinputMocks.MockDeviceInterface) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builtin := uinputMocks.NewMockDeviceInterface(t)
+			macros := uinputMocks.NewMockDeviceInterface(t)
+			pad := uinputMocks.NewMockDeviceInterface(t)
+			tt.setupMocks(builtin, macros, pad)
+
+			server := newTestServer(builtin, layoutMocks.NewMockRegistryInterface(t))
+			assert.NoError(t, server.AddDevice(NamedDevice{Name: "macros", Profile: uinput.ProfileKeyboard, Device: macros}))
+			assert.NoError(t, server.AddDevice(NamedDevice{Name: "pad", Profile: uinput.ProfileGamepad, Device: pad}))
+
+			err := server.handleCommand(context.Background(), &clientConn{}, &tt.cmd)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNamedTabletUsesItsOwnRange(t *testing.T) {
+	var events []uinput.InputEvent
+	tablet := uinputMocks.NewMockDeviceInterface(t)
+	tablet.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, *args.Get(0).(*uinput.InputEvent))
+	}).Return(nil)
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+	assert.NoError(t, server.AddDevice(NamedDevice{Name: "wacom", Profile: uinput.ProfileTablet, Device: tablet, Width: 101, Height: 51}))
+
+	cmd := &protocol.Command{
+		Device:  "wacom",
+		Type:    protocol.CommandType_AbsPointer,
+		Payload: json.RawMessage(`{"x":1,"y":1,"normalized":true}`),
+	}
+	assert.NoError(t, server.handleCommand(context.Background(), &clientConn{}, cmd))
+
+	assert.Equal(t, [][3]int32{
+		{uinput.EvAbs, uinput.AbsX, 100},
+		{uinput.EvAbs, uinput.AbsY, 50},
+		{uinput.EvSyn, uinput.SynReport, 0},
+	}, eventCodes(events))
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// unicodeEntryKey starts GTK/IBus Unicode code point entry.
+var unicodeEntryKey = layouts.KeySequence{Keycode: uinput.KeyU, Modifier: layouts.ModCtrl | layouts.ModShift}
+
+// checkFallback returns an error if strategy is not a known fallback
+// strategy. An empty strategy means the default.
+func checkFallback(strategy string) error {
+	switch strategy {
+	case "", protocol.Fallback_None, protocol.Fallback_UnicodeHex:
+		return nil
+	default:
+		return fmt.Errorf("unknown fallback strategy: %s", strategy)
+	}
+}
+
+// charTyper types the characters of one typing command or stream session
+// and keeps track of how each of them was typed.
+type charTyper struct {
+	s        *Server
+	layout   layouts.Layout
+	fallback string
+	start    time.Time
+	pos      int // Index of the next character
+	result   protocol.TypeResult
+}
+
+// newCharTyper creates a typer for the layout. An empty fallback selects
+// the configured strategy.
+func (s *Server) newCharTyper(layout layouts.Layout, fallback string) (*charTyper, error) {
+	if err := checkFallback(fallback); err != nil {
+		return nil, err
+	}
+	if fallback == "" {
+		fallback = s.cfg.Fallback
+	}
+
+	return &charTyper{s: s, layout: layout, fallback: fallback, start: time.Now()}, nil
+}
+
+// resolve returns the keystrokes typing char and how they are produced
+// (one of the protocol.PlanMethod_* values). For characters the layout
+// cannot type, err tells why.
+func (t *charTyper) resolve(ctx context.Context, char rune) ([]layouts.KeySequence, string, error) {
+	sequence, err := t.layout.CharToKeySequence(ctx, char)
+	if err == nil {
+		return sequence, protocol.PlanMethod_Layout, nil
+	}
+
+	if t.fallback == protocol.Fallback_UnicodeHex && errors.Is(err, layouts.ErrUseFallback) {
+		sequence, ferr := unicodeHexSequence(ctx, t.layout, char)
+		if ferr != nil {
+			return nil, protocol.PlanMethod_Dropped, fmt.Errorf("unicode fallback: %w", ferr)
+		}
+		return sequence, protocol.PlanMethod_Fallback, err
+	}
+
+	return nil, protocol.PlanMethod_Dropped, err
+}
+
+// canType reports whether char can be typed with the layout or the
+// fallback strategy.
+func (t *charTyper) canType(ctx context.Context, char rune) bool {
+	_, method, _ := t.resolve(ctx, char)
+	return method != protocol.PlanMethod_Dropped
+}
+
+// planChar returns the keystrokes typeChar would send for char, without
+// typing anything.
+func (t *charTyper) planChar(ctx context.Context, char rune) protocol.PlanStep {
+	step := protocol.PlanStep{Char: string(char), Position: t.pos}
+	t.pos++
+
+	sequence, method, err := t.resolve(ctx, char)
+	step.Method = method
+	if err != nil {
+		step.Error = err.Error()
+	}
+	for _, key := range sequence {
+		step.Keys = append(step.Keys, protocol.PlannedKey{
+			Keycode:   key.Keycode,
+			Modifiers: key.Modifier.Names(),
+			Chord:     key.String(),
+		})
+	}
+	return step
+}
+
+// checkStrict returns an error if any character of text would be dropped,
+// before anything is typed. The characters are listed in the response.
+// Characters matching skip are not typed by the caller and not checked.
+func (t *charTyper) checkStrict(ctx context.Context, text string, skip func(rune) bool) error {
+	var dropped []protocol.CharReport
+
+	pos := 0
+	for _, char := range text {
+		if (skip == nil || !skip(char)) && !t.canType(ctx, char) {
+			dropped = append(dropped, protocol.CharReport{Char: string(char), Position: pos})
+		}
+		pos++
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	t.result.Dropped = dropped
+	t.report(ctx)
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Code = protocol.ErrorCode_UnsupportedChars
+	}
+	return fmt.Errorf("strict mode: %d characters not supported by layout %s", len(dropped), t.layout.Name())
+}
+
+// typeChar types char with the layout. Characters the layout cannot type
+// are entered with the fallback strategy when the layout allows it, and
+// dropped otherwise.
+//
+// Once ctx is cancelled, no new character is started, and a paused job
+// waits before its next character; the keystrokes of a character are
+// always sent in full, so that no dead key or modifier is left pending.
+func (t *charTyper) typeChar(ctx context.Context, char rune) error {
+	if err := checkpoint(ctx, t.pos); err != nil {
+		return err
+	}
+
+	report := protocol.CharReport{Char: string(char), Position: t.pos}
+	t.pos++
+	defer progress(ctx, t.pos)
+
+	log := logger.LogFromCtx(ctx)
+
+	sequence, method, err := t.resolve(ctx, char)
+	switch method {
+	case protocol.PlanMethod_Dropped:
+		log.Warn("character not supported", "char", string(char), "error", err)
+		t.result.Dropped = append(t.result.Dropped, report)
+		return nil
+	case protocol.PlanMethod_Fallback:
+		log.Debug("character typed with fallback", "char", string(char), "strategy", t.fallback)
+		t.result.Fallback = append(t.result.Fallback, report)
+	}
+
+	if err := t.s.sendKeySequence(context.WithoutCancel(ctx), sequence); err != nil {
+		return fmt.Errorf("failed to send key: %w", err)
+	}
+	t.result.Typed++
+	return nil
+}
+
+// report adds the result so far to the command's response.
+func (t *charTyper) report(ctx context.Context) {
+	if resp := responseFromCtx(ctx); resp != nil {
+		result := t.result
+		result.ElapsedMs = time.Since(t.start).Milliseconds()
+		resp.Result = &result
+	}
+}
+
+// unicodeHexSequence returns the keystrokes entering char with the
+// GTK/IBus Unicode input method: Ctrl+Shift+U, the code point in
+// hexadecimal, then space to commit. The hex digits are typed with the
+// layout so that they come out right on any keymap.
+func unicodeHexSequence(ctx context.Context, layout layouts.Layout, char rune) ([]layouts.KeySequence, error) {
+	sequence := []layouts.KeySequence{unicodeEntryKey}
+
+	for _, digit := range strconv.FormatInt(int64(char), 16) + " " {
+		keys, err := layout.CharToKeySequence(ctx, digit)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, keys...)
+	}
+
+	return sequence, nil
+}
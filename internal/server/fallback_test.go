@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// recordKeys records the keys tapped with SendKey and the keys pressed with
+// raw events (chords) on a mock device.
+func recordKeys(device *uinputMocks.MockDeviceInterface) (tapped, pressed *[]uint16) {
+	tapped, pressed = &[]uint16{}, &[]uint16{}
+
+	device.On("SendKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*tapped = append(*tapped, args.Get(1).(uint16))
+	}).Return(nil).Maybe()
+	device.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		ev := args.Get(0).(*uinput.InputEvent)
+		if ev.Type == uinput.EvKey && ev.Value == uinput.KeyPress {
+			*pressed = append(*pressed, ev.Code)
+		}
+	}).Return(nil).Maybe()
+
+	return tapped, pressed
+}
+
+func TestHandleTypeFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		payload  protocol.TypePayload
+		tapped   []uint16
+		pressed  []uint16
+		expected *protocol.TypeResult
+	}{
+		{
+			name:    "dropped without fallback",
+			payload: protocol.TypePayload{Text: "a→b"},
+			tapped:  []uint16{uinput.KeyA, uinput.KeyB},
+			expected: &protocol.TypeResult{
+				Typed:   2,
+				Dropped: []protocol.CharReport{{Char: "→", Position: 1}},
+			},
+		},
+		{
+			name:    "unicode hex entry from payload",
+			payload: protocol.TypePayload{Text: "a→b", Fallback: protocol.Fallback_UnicodeHex},
+			// a, then 2192 and space to commit, then b
+			tapped:  []uint16{uinput.KeyA, uinput.Key2, uinput.Key1, uinput.Key9, uinput.Key2, uinput.KeySpace, uinput.KeyB},
+			pressed: []uint16{uinput.KeyLeftCtrl, uinput.KeyLeftShift, uinput.KeyU},
+			expected: &protocol.TypeResult{
+				Typed:    3,
+				Fallback: []protocol.CharReport{{Char: "→", Position: 1}},
+			},
+		},
+		{
+			name:    "unicode hex entry from config",
+			config:  protocol.Fallback_UnicodeHex,
+			payload: protocol.TypePayload{Text: "é"},
+			tapped:  []uint16{uinput.KeyE, uinput.Key9, uinput.KeySpace},
+			pressed: []uint16{uinput.KeyLeftCtrl, uinput.KeyLeftShift, uinput.KeyU},
+			expected: &protocol.TypeResult{
+				Typed:    1,
+				Fallback: []protocol.CharReport{{Char: "é", Position: 0}},
+			},
+		},
+		{
+			name:    "payload overrides config",
+			config:  protocol.Fallback_UnicodeHex,
+			payload: protocol.TypePayload{Text: "é", Fallback: protocol.Fallback_None},
+			expected: &protocol.TypeResult{
+				Typed:   0,
+				Dropped: []protocol.CharReport{{Char: "é", Position: 0}},
+			},
+		},
+		{
+			name:    "control characters are dropped",
+			payload: protocol.TypePayload{Text: "\x07a", Fallback: protocol.Fallback_UnicodeHex},
+			tapped:  []uint16{uinput.KeyA},
+			expected: &protocol.TypeResult{
+				Typed:   1,
+				Dropped: []protocol.CharReport{{Char: "\x07", Position: 0}},
+			},
+		},
+		{
+			name:     "every character typed",
+			payload:  protocol.TypePayload{Text: "ab", Fallback: protocol.Fallback_UnicodeHex},
+			tapped:   []uint16{uinput.KeyA, uinput.KeyB},
+			expected: &protocol.TypeResult{Typed: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := uinputMocks.NewMockDeviceInterface(t)
+			tapped, pressed := recordKeys(device)
+
+			server := newTestServer(device, layouts.NewRegistry())
+			server.cfg.Fallback = tt.config
+
+			resp := &protocol.Response{}
+			payload, _ := json.Marshal(tt.payload)
+			err := server.handleType(withResponse(context.Background(), resp), payload)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.tapped, nilIfEmpty(*tapped))
+			assert.Equal(t, tt.pressed, nilIfEmpty(*pressed))
+			if assert.NotNil(t, resp.Result) {
+				resp.Result.ElapsedMs = 0
+			}
+			assert.Equal(t, tt.expected, resp.Result)
+		})
+	}
+}
+
+func TestHandleTypeUnknownFallback(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	payload, _ := json.Marshal(protocol.TypePayload{Text: "a", Fallback: "telepathy"})
+	err := server.handleType(context.Background(), payload)
+
+	assert.ErrorContains(t, err, "unknown fallback strategy")
+}
+
+func TestHandleStreamFallbackPositions(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+
+	resp := &protocol.Response{}
+	payload, _ := json.Marshal(protocol.StreamPayload{Text: "a  → é", CharDelay: -1, DelayMs: -1})
+	err := server.handleStream(withResponse(context.Background(), resp), payload)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp.Result) {
+		resp.Result.ElapsedMs = 0
+	}
+	// "a", then a single space for each run of whitespace
+	assert.Equal(t, &protocol.TypeResult{
+		Typed:   3,
+		Dropped: []protocol.CharReport{{Char: "→", Position: 3}, {Char: "é", Position: 5}},
+	}, resp.Result)
+}
+
+// nilIfEmpty returns nil for an empty slice, for comparisons with unset
+// expectations.
+func nilIfEmpty(keys []uint16) []uint16 {
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
+
+func TestHandleStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(*Server, context.Context) error
+		dropped  []protocol.CharReport
+		expected []uint16
+	}{
+		{
+			name: "type rejects unsupported characters",
+			handle: func(s *Server, ctx context.Context) error {
+				payload, _ := json.Marshal(protocol.TypePayload{Text: "a→b\x07", Strict: true})
+				return s.handleType(ctx, payload)
+			},
+			dropped: []protocol.CharReport{{Char: "→", Position: 1}, {Char: "\x07", Position: 3}},
+		},
+		{
+			name: "type accepts characters entered by fallback",
+			handle: func(s *Server, ctx context.Context) error {
+				payload, _ := json.Marshal(protocol.TypePayload{Text: "é", Strict: true, Fallback: protocol.Fallback_UnicodeHex})
+				return s.handleType(ctx, payload)
+			},
+			expected: []uint16{uinput.KeyE, uinput.Key9, uinput.KeySpace},
+		},
+		{
+			name: "stream rejects unsupported characters",
+			handle: func(s *Server, ctx context.Context) error {
+				payload, _ := json.Marshal(protocol.StreamPayload{Text: "a é", Strict: true, CharDelay: -1, DelayMs: -1})
+				return s.handleStream(ctx, payload)
+			},
+			dropped: []protocol.CharReport{{Char: "é", Position: 2}},
+		},
+		{
+			name: "stream ignores collapsed whitespace",
+			handle: func(s *Server, ctx context.Context) error {
+				payload, _ := json.Marshal(protocol.StreamPayload{Text: "a\u00a0b", Strict: true, CharDelay: -1, DelayMs: -1})
+				return s.handleStream(ctx, payload)
+			},
+			expected: []uint16{uinput.KeyA, uinput.KeySpace, uinput.KeyB},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := uinputMocks.NewMockDeviceInterface(t)
+			tapped, _ := recordKeys(device)
+
+			server := newTestServer(device, layouts.NewRegistry())
+
+			resp := &protocol.Response{}
+			err := tt.handle(server, withResponse(context.Background(), resp))
+
+			assert.Equal(t, tt.expected, nilIfEmpty(*tapped))
+			if tt.dropped == nil {
+				assert.NoError(t, err)
+				assert.Empty(t, resp.Code)
+				return
+			}
+
+			assert.ErrorContains(t, err, "strict mode")
+			assert.Equal(t, protocol.ErrorCode_UnsupportedChars, resp.Code)
+			if assert.NotNil(t, resp.Result) {
+				assert.Equal(t, 0, resp.Result.Typed)
+				assert.Equal(t, tt.dropped, resp.Result.Dropped)
+			}
+		})
+	}
+}
+
+func TestHandlePlan(t *testing.T) {
+	// No expectations: planning must not touch the device
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	resp := &protocol.Response{}
+	payload, _ := json.Marshal(protocol.PlanPayload{Text: "aô→\x07", Layout: "fr", Fallback: protocol.Fallback_UnicodeHex})
+	err := server.handlePlan(withResponse(context.Background(), resp), payload)
+
+	if !assert.NoError(t, err) || !assert.Len(t, resp.Plan, 4) {
+		return
+	}
+	assert.Equal(t, "fr", resp.Layout)
+
+	// AZERTY: a is on the Q key, ô is the circumflex dead key then o
+	assert.Equal(t, protocol.PlanStep{
+		Char: "a", Position: 0, Method: protocol.PlanMethod_Layout,
+		Keys: []protocol.PlannedKey{{Keycode: uinput.KeyQ, Chord: "q"}},
+	}, resp.Plan[0])
+	assert.Equal(t, protocol.PlanStep{
+		Char: "ô", Position: 1, Method: protocol.PlanMethod_Layout,
+		Keys: []protocol.PlannedKey{{Keycode: uinput.KeyLeftBrace, Chord: "leftbrace"}, {Keycode: uinput.KeyO, Chord: "o"}},
+	}, resp.Plan[1])
+
+	fallback := resp.Plan[2]
+	assert.Equal(t, protocol.PlanMethod_Fallback, fallback.Method)
+	assert.NotEmpty(t, fallback.Error)
+	if assert.NotEmpty(t, fallback.Keys) {
+		assert.Equal(t, protocol.PlannedKey{Keycode: uinput.KeyU, Modifiers: []string{"ctrl", "shift"}, Chord: "ctrl+shift+u"}, fallback.Keys[0])
+		// Digits need Shift on AZERTY
+		assert.Equal(t, "shift+2", fallback.Keys[1].Chord)
+	}
+
+	assert.Equal(t, protocol.PlanMethod_Dropped, resp.Plan[3].Method)
+	assert.Empty(t, resp.Plan[3].Keys)
+}
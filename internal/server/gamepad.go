@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// Actions of the gamepad_button command
+const (
+	gamepadActionTap     = "tap"
+	gamepadActionPress   = "press"
+	gamepadActionRelease = "release"
+)
+
+// errNoGamepad is returned by gamepad commands when gamepads are disabled.
+var errNoGamepad = errors.New("gamepad device not available")
+
+// GamepadFactory creates the virtual gamepad device.
+type GamepadFactory func(ctx context.Context) (uinput.DeviceInterface, error)
+
+// SetGamepadFactory enables gamepad commands. The gamepad is created with
+// newGamepad on the first gamepad command and closed with the server, so
+// games only see a controller once one is actually used.
+func (s *Server) SetGamepadFactory(newGamepad GamepadFactory) {
+	s.gamepad.factory = newGamepad
+}
+
+// gamepadDevice returns the gamepad a command acts on, creating the
+// built-in gamepad on first use and waiting for it to settle.
+func (s *Server) gamepadDevice(ctx context.Context) (uinput.DeviceInterface, error) {
+	if target := targetFromCtx(ctx); target != nil {
+		return target.Device, nil
+	}
+
+	s.gamepad.mu.Lock()
+	defer s.gamepad.mu.Unlock()
+
+	if s.gamepad.device != nil {
+		return s.gamepad.device, nil
+	}
+	if s.gamepad.factory == nil {
+		return nil, errNoGamepad
+	}
+
+	logger.LogFromCtx(ctx).Info("creating gamepad on demand")
+
+	device, err := s.gamepad.factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gamepad: %w", err)
+	}
+	s.gamepad.device = device
+
+	// Commands racing the creation wait too, behind the lock
+	if err := s.settle(ctx); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// currentGamepad returns the gamepad if it has been created.
+func (s *Server) currentGamepad() uinput.DeviceInterface {
+	s.gamepad.mu.Lock()
+	defer s.gamepad.mu.Unlock()
+	return s.gamepad.device
+}
+
+// closeGamepad destroys the gamepad if it has been created.
+func (s *Server) closeGamepad() error {
+	s.gamepad.mu.Lock()
+	defer s.gamepad.mu.Unlock()
+
+	if s.gamepad.device == nil {
+		return nil
+	}
+	err := s.gamepad.device.Close()
+	s.gamepad.device = nil
+	return err
+}
+
+// handleGamepadButton taps, presses or releases a gamepad button.
+func (s *Server) handleGamepadButton(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	var p protocol.GamepadButtonPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid gamepad button payload: %w", err)
+	}
+
+	button, ok := uinput.LookupGamepadButton(p.Button)
+	if !ok {
+		return fmt.Errorf("unknown gamepad button: %s", p.Button)
+	}
+
+	action := p.Action
+	if action == "" {
+		action = gamepadActionTap
+	}
+	if action != gamepadActionTap && action != gamepadActionPress && action != gamepadActionRelease {
+		return fmt.Errorf("unknown gamepad button action: %s", p.Action)
+	}
+
+	gamepad, err := s.gamepadDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("gamepad button", "button", button, "action", action)
+
+	hk := heldKey{dev: gamepad, code: button}
+	switch action {
+	case gamepadActionPress:
+		if cc.holding(hk) {
+			return nil // Already held by this client
+		}
+		if err := s.pressHeld(hk); err != nil {
+			return err
+		}
+		cc.hold(hk)
+
+	case gamepadActionRelease:
+		if !cc.holding(hk) {
+			return fmt.Errorf("gamepad button %s is not held", p.Button)
+		}
+		cc.unhold(hk)
+		return s.releaseHeld(hk)
+
+	default:
+		if err := writeKey(gamepad, button, true); err != nil {
+			return fmt.Errorf("button press %d: %w", button, err)
+		}
+		if err := writeKey(gamepad, button, false); err != nil {
+			return fmt.Errorf("button release %d: %w", button, err)
+		}
+	}
+
+	return nil
+}
+
+// handleGamepadAxis sets one or more axes in a single report.
+func (s *Server) handleGamepadAxis(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	var p protocol.GamepadAxisPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid gamepad axis payload: %w", err)
+	}
+
+	if len(p.Axes) == 0 {
+		return fmt.Errorf("at least one axis is required")
+	}
+
+	// Validate everything before touching the device
+	events := make([]*uinput.InputEvent, 0, len(p.Axes)+1)
+	for name, value := range p.Axes {
+		axis, ok := uinput.LookupGamepadAxis(name)
+		if !ok {
+			return fmt.Errorf("unknown gamepad axis: %s", name)
+		}
+		if value < axis.Min || value > axis.Max {
+			return fmt.Errorf("gamepad axis %s value %d out of range %d to %d", name, value, axis.Min, axis.Max)
+		}
+		events = append(events, uinput.NewAbsEvent(axis.Code, value))
+	}
+
+	// Deterministic event order regardless of map iteration
+	sort.Slice(events, func(i, j int) bool { return events[i].Code < events[j].Code })
+	events = append(events, uinput.NewSynEvent())
+
+	gamepad, err := s.gamepadDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("gamepad axes", "axes", p.Axes)
+
+	for _, ev := range events {
+		if err := gamepad.WriteEvent(ev); err != nil {
+			return fmt.Errorf("gamepad axis: %w", err)
+		}
+		if ev.Type == uinput.EvAbs {
+			if ma := (movedAxis{dev: gamepad, code: ev.Code}); !slices.Contains(cc.axes, ma) {
+				cc.axes = append(cc.axes, ma)
+			}
+		}
+	}
+
+	return nil
+}
+
+// movedAxis is a gamepad axis moved off rest, on the gamepad it belongs to.
+type movedAxis struct {
+	dev  uinput.DeviceInterface
+	code uint16
+}
+
+// resetAxes returns every axis moved by the connection to its rest value,
+// with one report per gamepad.
+func (s *Server) resetAxes(cc *clientConn) error {
+	rest := make(map[uint16]int32, len(uinput.GamepadAxes))
+	for _, axis := range uinput.GamepadAxes {
+		rest[axis.Code] = axis.Rest
+	}
+
+	var (
+		err     error
+		devices []uinput.DeviceInterface
+	)
+	for _, ma := range cc.axes {
+		if werr := ma.dev.WriteEvent(uinput.NewAbsEvent(ma.code, rest[ma.code])); werr != nil && err == nil {
+			err = werr
+		}
+		if !slices.Contains(devices, ma.dev) {
+			devices = append(devices, ma.dev)
+		}
+	}
+	cc.axes = nil
+
+	for _, dev := range devices {
+		if werr := dev.WriteEvent(uinput.NewSynEvent()); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	layoutMocks "github.com/bnema/uinputd-go/internal/layouts/mocks"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newGamepadTestServer creates a test server whose gamepad factory returns
+// a mock recording every event. created counts factory calls.
+func newGamepadTestServer(t *testing.T, events *[]uinput.InputEvent, created *int) *Server {
+	gamepad := uinputMocks.NewMockDeviceInterface(t)
+	gamepad.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		*events = append(*events, *args.Get(0).(*uinput.InputEvent))
+	}).Return(nil).Maybe()
+	gamepad.On("Close").Return(nil).Maybe()
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+	server.SetGamepadFactory(func(ctx context.Context) (uinput.DeviceInterface, error) {
+		*created++
+		return gamepad, nil
+	})
+	return server
+}
+
+func TestHandleGamepadButton(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       protocol.GamepadButtonPayload
+		expected      [][3]int32
+		expectedError bool
+	}{
+		{
+			name:    "tap",
+			payload: protocol.GamepadButtonPayload{Button: "south"},
+			expected: [][3]int32{
+				{uinput.EvKey, uinput.BtnSouth, uinput.KeyPress},
+				{uinput.EvSyn, uinput.SynReport, 0},
+				{uinput.EvKey, uinput.BtnSouth, uinput.KeyRelease},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "press by alias",
+			payload: protocol.GamepadButtonPayload{Button: "RB", Action: "press"},
+			expected: [][3]int32{
+				{uinput.EvKey, uinput.BtnTR, uinput.KeyPress},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "release without press",
+			payload:       protocol.GamepadButtonPayload{Button: "start", Action: "release"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown button",
+			payload:       protocol.GamepadButtonPayload{Button: "turbo"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown action",
+			payload:       protocol.GamepadButtonPayload{Button: "south", Action: "mash"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			var created int
+			server := newGamepadTestServer(t, &events, &created)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleGamepadButton(context.Background(), &clientConn{}, payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, events)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, eventCodes(events))
+		})
+	}
+}
+
+func TestHandleGamepadAxis(t *testing.T) {
+	tests := []struct {
+		name          string
+		axes          map[string]int32
+		expected      [][3]int32
+		expectedError bool
+	}{
+		{
+			name: "stick and trigger in one report",
+			axes: map[string]int32{"right_trigger": 255, "left_x": -32768},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, -32768},
+				{uinput.EvAbs, uinput.AbsRZ, 255},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name: "dpad",
+			axes: map[string]int32{"dpad_y": -1},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsHat0Y, -1},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "out of range",
+			axes:          map[string]int32{"left_trigger": 256},
+			expectedError: true,
+		},
+		{
+			name:          "unknown axis",
+			axes:          map[string]int32{"throttle": 1},
+			expectedError: true,
+		},
+		{
+			name:          "no axes",
+			axes:          map[string]int32{},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			var created int
+			server := newGamepadTestServer(t, &events, &created)
+
+			payloadBytes, _ := json.Marshal(protocol.GamepadAxisPayload{Axes: tt.axes})
+			err := server.handleGamepadAxis(context.Background(), &clientConn{}, payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, events)
+				assert.Zero(t, created, "invalid commands must not create the gamepad")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, eventCodes(events))
+		})
+	}
+}
+
+func TestGamepadResetOnDisconnect(t *testing.T) {
+	var events []uinput.InputEvent
+	var created int
+	server := newGamepadTestServer(t, &events, &created)
+	cc := &clientConn{}
+	ctx := context.Background()
+
+	press, _ := json.Marshal(protocol.GamepadButtonPayload{Button: "east", Action: "press"})
+	axis, _ := json.Marshal(protocol.GamepadAxisPayload{Axes: map[string]int32{"left_y": 1000}})
+
+	assert.NoError(t, server.handleGamepadButton(ctx, cc, press))
+	assert.NoError(t, server.handleGamepadAxis(ctx, cc, axis))
+	assert.NoError(t, server.handleGamepadAxis(ctx, cc, axis))
+	assert.Equal(t, 1, created, "gamepad must be created once")
+
+	events = nil
+	server.closeConn(ctx, cc)
+
+	assert.Equal(t, [][3]int32{
+		{uinput.EvKey, uinput.BtnEast, uinput.KeyRelease},
+		{uinput.EvSyn, uinput.SynReport, 0},
+		{uinput.EvAbs, uinput.AbsY, 0},
+		{uinput.EvSyn, uinput.SynReport, 0},
+	}, eventCodes(events))
+
+	assert.NoError(t, server.Close())
+	assert.Nil(t, server.currentGamepad())
+}
+
+func TestCloseConnResetsNamedGamepadAxes(t *testing.T) {
+	var events, padEvents []uinput.InputEvent
+	var created int
+	server := newGamepadTestServer(t, &events, &created)
+
+	pad := uinputMocks.NewMockDeviceInterface(t)
+	pad.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		padEvents = append(padEvents, *args.Get(0).(*uinput.InputEvent))
+	}).Return(nil)
+	assert.NoError(t, server.AddDevice(NamedDevice{Name: "pad", Profile: uinput.ProfileGamepad, Device: pad}))
+
+	cc := &clientConn{}
+	ctx := context.Background()
+	axis, _ := json.Marshal(protocol.GamepadAxisPayload{Axes: map[string]int32{"left_x": 500}})
+	assert.NoError(t, server.handleCommand(ctx, cc, &protocol.Command{Device: "pad", Type: protocol.CommandType_GamepadAxis, Payload: axis}))
+
+	padEvents = nil
+	server.closeConn(ctx, cc)
+
+	// The axis returns to rest on the named gamepad; the built-in one is
+	// neither created nor written to
+	assert.Equal(t, [][3]int32{
+		{uinput.EvAbs, uinput.AbsX, 0},
+		{uinput.EvSyn, uinput.SynReport, 0},
+	}, eventCodes(padEvents))
+	assert.Zero(t, created)
+	assert.Empty(t, events)
+}
+
+func TestGamepadSettlesOnCreation(t *testing.T) {
+	var events []uinput.InputEvent
+	var created int
+	server := newGamepadTestServer(t, &events, &created)
+	server.cfg.Devices.SettleMs = 100
+
+	// Only the command creating the gamepad waits
+	payloadBytes, _ := json.Marshal(protocol.GamepadButtonPayload{Button: "south"})
+	for _, wait := range []bool{true, false} {
+		start := time.Now()
+		assert.NoError(t, server.handleGamepadButton(context.Background(), &clientConn{}, payloadBytes))
+		assert.Equal(t, wait, time.Since(start) >= 100*time.Millisecond)
+	}
+	assert.Equal(t, 1, created)
+	assert.Len(t, events, 8)
+}
+
+func TestHandleGamepadUnavailable(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+
+	payloadBytes, _ := json.Marshal(protocol.GamepadButtonPayload{Button: "south"})
+	err := server.handleGamepadButton(context.Background(), &clientConn{}, payloadBytes)
+	assert.ErrorIs(t, err, errNoGamepad)
+
+	// A failing factory is reported and retried on the next command
+	server.SetGamepadFactory(func(ctx context.Context) (uinput.DeviceInterface, error) {
+		return nil, errors.New("no /dev/uinput")
+	})
+	err = server.handleGamepadButton(context.Background(), &clientConn{}, payloadBytes)
+	assert.ErrorContains(t, err, "no /dev/uinput")
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
+	"unicode"
 
 	"github.com/bnema/uinputd-go/internal/layouts"
 	"github.com/bnema/uinputd-go/internal/logger"
@@ -13,11 +13,43 @@ import (
 	"github.com/bnema/uinputd-go/internal/uinput"
 )
 
-// handleCommand routes commands to appropriate handlers.
-func (s *Server) handleCommand(ctx context.Context, cmd *protocol.Command) error {
+// handleCommand routes commands to appropriate handlers and devices.
+func (s *Server) handleCommand(ctx context.Context, cc *clientConn, cmd *protocol.Command) error {
 	log := logger.LogFromCtx(ctx)
 	log.Info("handling command", "type", cmd.Type)
 
+	ctx, err := s.authorize(ctx, cc, cmd)
+	if err != nil {
+		return err
+	}
+	if err := s.limiter.limitCommand(ctx, cc.peer, cc.rule, keystrokes(cmd)); err != nil {
+		return err
+	}
+
+	// Route to the named device, if any
+	ctx, err = s.routeCommand(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	ctx = withPriority(ctx, cmd.Priority)
+
+	// Typing commands run as jobs, which can be cancelled while queued
+	if cmd.Type == protocol.CommandType_Type || cmd.Type == protocol.CommandType_Stream {
+		var j *job
+		ctx, j = s.startJob(ctx, cc, cmd.Type)
+		defer j.end()
+	}
+
+	// Commands driving devices wait for their turn
+	if queuedCommands[cmd.Type] {
+		var leave func()
+		ctx, leave, err = s.queue.enter(ctx)
+		if err != nil {
+			return err
+		}
+		defer leave()
+	}
+
 	switch cmd.Type {
 	case protocol.CommandType_Type:
 		return s.handleType(ctx, cmd.Payload)
@@ -27,6 +59,48 @@ func (s *Server) handleCommand(ctx context.Context, cmd *protocol.Command) error
 		return s.handleKey(ctx, cmd.Payload)
 	case protocol.CommandType_Ping:
 		return s.handlePing(ctx)
+	case protocol.CommandType_Plan:
+		return s.handlePlan(ctx, cmd.Payload)
+	case protocol.CommandType_Status:
+		return s.handleStatus(ctx)
+	case protocol.CommandType_Capabilities:
+		return s.handleCapabilities(ctx)
+	case protocol.CommandType_Jobs:
+		return s.handleJobs(ctx)
+	case protocol.CommandType_Cancel:
+		return s.handleCancel(ctx, cmd.Payload)
+	case protocol.CommandType_Pause:
+		return s.handlePause(ctx, cmd.Payload)
+	case protocol.CommandType_Resume:
+		return s.handleResume(ctx, cmd.Payload)
+	case protocol.CommandType_KeyDown:
+		return s.handleKeyDown(ctx, cc, cmd.Payload)
+	case protocol.CommandType_KeyUp:
+		return s.handleKeyUp(ctx, cc, cmd.Payload)
+	case protocol.CommandType_StreamOpen:
+		return s.handleStreamOpen(ctx, cc, cmd.Payload)
+	case protocol.CommandType_StreamChunk:
+		return s.handleStreamChunk(ctx, cc, cmd.Payload)
+	case protocol.CommandType_StreamFlush:
+		return s.handleStreamFlush(ctx, cc)
+	case protocol.CommandType_StreamClose:
+		return s.handleStreamClose(ctx, cc)
+	case protocol.CommandType_MouseMove:
+		return s.handleMouseMove(ctx, cmd.Payload)
+	case protocol.CommandType_MouseClick:
+		return s.handleMouseClick(ctx, cmd.Payload)
+	case protocol.CommandType_MouseDown:
+		return s.handleMouseDown(ctx, cc, cmd.Payload)
+	case protocol.CommandType_MouseUp:
+		return s.handleMouseUp(ctx, cc, cmd.Payload)
+	case protocol.CommandType_MouseScroll:
+		return s.handleMouseScroll(ctx, cmd.Payload)
+	case protocol.CommandType_AbsPointer:
+		return s.handleAbsPointer(ctx, cmd.Payload)
+	case protocol.CommandType_GamepadButton:
+		return s.handleGamepadButton(ctx, cc, cmd.Payload)
+	case protocol.CommandType_GamepadAxis:
+		return s.handleGamepadAxis(ctx, cc, cmd.Payload)
 	default:
 		return fmt.Errorf("unknown command type: %s", cmd.Type)
 	}
@@ -41,40 +115,70 @@ func (s *Server) handleType(ctx context.Context, payload json.RawMessage) error
 		return fmt.Errorf("invalid type payload: %w", err)
 	}
 
-	// Get layout (use config default if not specified)
-	layoutName := p.Layout
-	if layoutName == "" {
-		layoutName = s.cfg.Layout
+	// Get layout (detected or config default if not specified)
+	layout, layoutName, err := s.resolveLayout(ctx, p.Layout)
+	if err != nil {
+		return fmt.Errorf("layout error: %w", err)
 	}
 
-	layout, err := s.registry.Get(layoutName)
+	typer, err := s.newCharTyper(layout, p.Fallback)
 	if err != nil {
-		return fmt.Errorf("layout error: %w", err)
+		return err
+	}
+
+	if p.Strict {
+		if err := typer.checkStrict(ctx, p.Text, nil); err != nil {
+			return err
+		}
 	}
 
 	log.Info("typing text", "length", len(p.Text), "layout", layoutName)
+	addJobText(ctx, p.Text)
+
+	// Report what was typed, even if typing is cancelled
+	defer typer.report(ctx)
 
 	// Type each character
 	for _, char := range p.Text {
-		sequence, err := layout.CharToKeySequence(ctx, char)
-		if err != nil {
-			log.Warn("character not supported", "char", string(char), "error", err)
-			continue // Skip unsupported characters
+		if err := typer.typeChar(ctx, char); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
 
-		// Send each keystroke in the sequence
-		// For simple characters, sequence has one element
-		// For dead key combinations, sequence has multiple elements (e.g., circumflex + vowel)
-		for _, key := range sequence {
-			shift := (key.Modifier & layouts.ModShift) != 0
-			altGr := (key.Modifier & layouts.ModAltGr) != 0
+// handlePlan reports the keystrokes a type command would send for the
+// text, without touching any device.
+func (s *Server) handlePlan(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
 
-			if err := s.sendKeyWithModifiers(ctx, key.Keycode, shift, altGr); err != nil {
-				return fmt.Errorf("failed to send key: %w", err)
-			}
-		}
+	var p protocol.PlanPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid plan payload: %w", err)
+	}
+
+	// Get layout (detected or config default if not specified)
+	layout, layoutName, err := s.resolveLayout(ctx, p.Layout)
+	if err != nil {
+		return fmt.Errorf("layout error: %w", err)
+	}
+
+	typer, err := s.newCharTyper(layout, p.Fallback)
+	if err != nil {
+		return err
+	}
+
+	log.Info("planning text", "length", len(p.Text), "layout", layoutName)
+
+	plan := make([]protocol.PlanStep, 0, len(p.Text))
+	for _, char := range p.Text {
+		plan = append(plan, typer.planChar(ctx, char))
 	}
 
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Plan = plan
+	}
 	return nil
 }
 
@@ -87,15 +191,22 @@ func (s *Server) handleStream(ctx context.Context, payload json.RawMessage) erro
 		return fmt.Errorf("invalid stream payload: %w", err)
 	}
 
-	// Get layout (use config default if not specified)
-	layoutName := p.Layout
-	if layoutName == "" {
-		layoutName = s.cfg.Layout
+	// Get layout (detected or config default if not specified)
+	layout, layoutName, err := s.resolveLayout(ctx, p.Layout)
+	if err != nil {
+		return fmt.Errorf("layout error: %w", err)
 	}
 
-	layout, err := s.registry.Get(layoutName)
+	typer, err := s.newCharTyper(layout, p.Fallback)
 	if err != nil {
-		return fmt.Errorf("layout error: %w", err)
+		return err
+	}
+
+	// Whitespace is collapsed into single spaces between words
+	if p.Strict {
+		if err := typer.checkStrict(ctx, p.Text, unicode.IsSpace); err != nil {
+			return err
+		}
 	}
 
 	// Get delays (use config defaults if not specified)
@@ -110,57 +221,152 @@ func (s *Server) handleStream(ctx context.Context, payload json.RawMessage) erro
 	}
 
 	log.Info("streaming text", "length", len(p.Text), "layout", layoutName, "char_delay_ms", charDelay.Milliseconds(), "word_delay_ms", wordDelay.Milliseconds())
+	addJobText(ctx, p.Text)
 
-	// Split text into words for word-level delays
-	words := strings.Fields(p.Text)
-
-	for i, word := range words {
-		// Type each character in the word
-		for _, char := range word {
-			sequence, err := layout.CharToKeySequence(ctx, char)
-			if err != nil {
-				log.Warn("character not supported", "char", string(char), "error", err)
-				continue // Skip unsupported characters
-			}
-
-			// Send each keystroke in the sequence
-			for _, key := range sequence {
-				shift := (key.Modifier & layouts.ModShift) != 0
-				altGr := (key.Modifier & layouts.ModAltGr) != 0
+	// Report what was typed, even if typing is cancelled
+	defer typer.report(ctx)
 
-				if err := s.sendKeyWithModifiers(ctx, key.Keycode, shift, altGr); err != nil {
-					return fmt.Errorf("failed to send key: %w", err)
-				}
-			}
+	// Type words separated by a single space, with word-level delays.
+	// Whitespace is not typed itself, but still counts for positions.
+	typedWord, spacePending := false, false
+	for _, char := range p.Text {
+		if unicode.IsSpace(char) {
+			typer.pos++
+			progress(ctx, typer.pos)
+			spacePending = typedWord
+			continue
+		}
 
-			// Delay between characters
-			if charDelay > 0 {
-				time.Sleep(charDelay)
+		if spacePending {
+			if err := checkpoint(ctx, typer.pos); err != nil {
+				return err
 			}
-		}
 
-		// Add space between words (except after last word)
-		if i < len(words)-1 {
 			// Type space character
-			sequence, err := layout.CharToKeySequence(ctx, ' ')
-			if err == nil {
-				for _, key := range sequence {
-					shift := (key.Modifier & layouts.ModShift) != 0
-					altGr := (key.Modifier & layouts.ModAltGr) != 0
-
-					if err := s.sendKeyWithModifiers(ctx, key.Keycode, shift, altGr); err != nil {
-						return fmt.Errorf("failed to send space: %w", err)
-					}
+			if sequence, err := layout.CharToKeySequence(ctx, ' '); err == nil {
+				if err := s.sendKeySequence(context.WithoutCancel(ctx), sequence); err != nil {
+					return fmt.Errorf("failed to send space: %w", err)
 				}
+				typer.result.Typed++
 			}
 
 			// Delay between words
-			if wordDelay > 0 {
-				time.Sleep(wordDelay)
+			if err := sleep(ctx, wordDelay); err != nil {
+				return err
 			}
+			spacePending = false
+		}
+
+		if err := typer.typeChar(ctx, char); err != nil {
+			return err
 		}
+		typedWord = true
+
+		// Delay between characters
+		if err := sleep(ctx, charDelay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleStreamOpen opens an incremental streaming session on the connection.
+func (s *Server) handleStreamOpen(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	if cc.stream != nil {
+		return fmt.Errorf("stream session already open")
+	}
+
+	var p protocol.StreamOpenPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid stream_open payload: %w", err)
+	}
+
+	// Get layout (detected or config default if not specified)
+	layout, layoutName, err := s.resolveLayout(ctx, p.Layout)
+	if err != nil {
+		return fmt.Errorf("layout error: %w", err)
+	}
+
+	// Get delays (use config defaults if not specified)
+	charDelay := time.Duration(p.CharDelay) * time.Millisecond
+	if p.CharDelay == 0 {
+		charDelay = time.Duration(s.cfg.Performance.CharDelayMs) * time.Millisecond
+	}
+
+	wordDelay := time.Duration(p.DelayMs) * time.Millisecond
+	if p.DelayMs == 0 {
+		wordDelay = time.Duration(s.cfg.Performance.StreamDelayMs) * time.Millisecond
+	}
+
+	typer, err := s.newCharTyper(layout, p.Fallback)
+	if err != nil {
+		return err
+	}
+
+	// The session is a job until it is closed
+	jobCtx, j := s.startJob(ctx, cc, protocol.CommandType_StreamOpen)
+
+	log.Info("stream session opened", "layout", layoutName, "job", j.id, "char_delay_ms", charDelay.Milliseconds(), "word_delay_ms", wordDelay.Milliseconds())
+
+	cc.stream = s.newStreamSession(jobCtx, typer, charDelay, wordDelay)
+	cc.stream.job = j
+	return nil
+}
+
+// handleStreamChunk queues a chunk of text on the open session.
+func (s *Server) handleStreamChunk(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	if cc.stream == nil {
+		return fmt.Errorf("no stream session open")
+	}
+
+	var p protocol.StreamChunkPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid stream_chunk payload: %w", err)
+	}
+
+	// Report failures of earlier chunks instead of queueing more text
+	if err := cc.stream.Err(); err != nil {
+		return fmt.Errorf("stream session failed: %w", err)
+	}
+
+	logger.LogFromCtx(ctx).Debug("stream chunk received", "length", len(p.Text))
+	cc.stream.enqueue(p.Text)
+	return nil
+}
+
+// handleStreamFlush waits until every queued chunk has been typed.
+func (s *Server) handleStreamFlush(ctx context.Context, cc *clientConn) error {
+	if cc.stream == nil {
+		return fmt.Errorf("no stream session open")
 	}
 
+	if err := cc.stream.flush(); err != nil {
+		return fmt.Errorf("stream session failed: %w", err)
+	}
+	cc.stream.typer.report(ctx)
+	return nil
+}
+
+// handleStreamClose flushes and ends the open session.
+func (s *Server) handleStreamClose(ctx context.Context, cc *clientConn) error {
+	log := logger.LogFromCtx(ctx)
+
+	if cc.stream == nil {
+		return fmt.Errorf("no stream session open")
+	}
+
+	ss := cc.stream
+	err := ss.close()
+	cc.stream = nil
+	log.Info("stream session closed")
+
+	if err != nil {
+		return fmt.Errorf("stream session failed: %w", err)
+	}
+	ss.typer.report(ctx)
 	return nil
 }
 
@@ -173,6 +379,20 @@ func (s *Server) handleKey(ctx context.Context, payload json.RawMessage) error {
 		return fmt.Errorf("invalid key payload: %w", err)
 	}
 
+	if p.Chord != "" {
+		chord, err := uinput.ParseChord(p.Chord)
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkChord(ctx, chord.Keycodes()...); err != nil {
+			return err
+		}
+
+		log.Info("sending chord", "chord", p.Chord)
+		return s.sendChord(ctx, chord)
+	}
+
 	log.Info("sending key", "keycode", p.Keycode, "modifier", p.Modifier)
 
 	// Parse modifier
@@ -188,13 +408,98 @@ func (s *Server) handleKey(ctx context.Context, payload json.RawMessage) error {
 		modKeycode = uinput.KeyRightAlt
 	case "":
 		// No modifier, send key directly
-		return s.device.SendKey(ctx, p.Keycode)
+		if err := s.checkChord(ctx, p.Keycode); err != nil {
+			return err
+		}
+		return s.keyboard(ctx).SendKey(ctx, p.Keycode)
 	default:
 		return fmt.Errorf("unknown modifier: %s", p.Modifier)
 	}
 
 	// Send key with modifier
-	return s.device.SendKeyWithModifier(ctx, modKeycode, p.Keycode)
+	if err := s.checkChord(ctx, modKeycode, p.Keycode); err != nil {
+		return err
+	}
+	return s.keyboard(ctx).SendKeyWithModifier(ctx, modKeycode, p.Keycode)
+}
+
+// handleKeyDown presses and holds keys until keyup or disconnect.
+func (s *Server) handleKeyDown(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	keycodes, err := parseKeyHold(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkChord(ctx, keycodes...); err != nil {
+		return err
+	}
+
+	log.Info("holding keys", "keys", keycodes)
+
+	for _, keycode := range keycodes {
+		hk := heldKey{dev: s.keyboard(ctx), code: keycode}
+		if cc.holding(hk) {
+			continue // Already held by this client
+		}
+		if err := s.pressHeld(hk); err != nil {
+			return err
+		}
+		cc.hold(hk)
+	}
+
+	return nil
+}
+
+// handleKeyUp releases keys previously held with keydown.
+func (s *Server) handleKeyUp(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	keycodes, err := parseKeyHold(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, keycode := range keycodes {
+		if !cc.holding(heldKey{dev: s.keyboard(ctx), code: keycode}) {
+			return fmt.Errorf("key %d is not held", keycode)
+		}
+	}
+
+	log.Info("releasing keys", "keys", keycodes)
+
+	// Release in reverse order so chords unwind correctly
+	for i := len(keycodes) - 1; i >= 0; i-- {
+		hk := heldKey{dev: s.keyboard(ctx), code: keycodes[i]}
+		cc.unhold(hk)
+		if err := s.releaseHeld(hk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseKeyHold decodes a keydown/keyup payload into keycodes in press order.
+func parseKeyHold(payload json.RawMessage) ([]uint16, error) {
+	var p protocol.KeyHoldPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("invalid key hold payload: %w", err)
+	}
+
+	if p.Chord != "" {
+		chord, err := uinput.ParseChord(p.Chord)
+		if err != nil {
+			return nil, err
+		}
+		return chord.Keycodes(), nil
+	}
+
+	if p.Keycode == 0 {
+		return nil, fmt.Errorf("keycode or chord is required")
+	}
+	return []uint16{p.Keycode}, nil
 }
 
 // handlePing responds to health check.
@@ -204,76 +509,124 @@ func (s *Server) handlePing(ctx context.Context) error {
 	return nil
 }
 
+// sendKeySequence sends every keystroke of a layout key sequence.
+// Keystrokes with Ctrl or Alt are sent as chords. The whole sequence is
+// checked against the policy's denied chords first, with the keys held on
+// the keyboard, so that a character is either typed in full or not at all.
+func (s *Server) sendKeySequence(ctx context.Context, sequence []layouts.KeySequence) error {
+	for _, key := range sequence {
+		if err := s.checkChord(ctx, keyChord(key).Keycodes()...); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range sequence {
+		if key.Modifier&(layouts.ModCtrl|layouts.ModAlt) != 0 {
+			if err := s.sendChord(ctx, keyChord(key)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		shift := (key.Modifier & layouts.ModShift) != 0
+		altGr := (key.Modifier & layouts.ModAltGr) != 0
+
+		if err := s.sendKeyWithModifiers(ctx, key.Keycode, shift, altGr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifierKeycodes maps layout modifiers to the keys pressing them, in
+// press order.
+var modifierKeycodes = []struct {
+	mod     layouts.Modifier
+	keycode uint16
+}{
+	{layouts.ModCtrl, uinput.KeyLeftCtrl},
+	{layouts.ModAlt, uinput.KeyLeftAlt},
+	{layouts.ModShift, uinput.KeyLeftShift},
+	{layouts.ModAltGr, uinput.KeyRightAlt},
+}
+
+// keyChord returns the chord pressing a keystroke with its modifiers.
+func keyChord(key layouts.KeySequence) *uinput.Chord {
+	chord := &uinput.Chord{Keys: []uint16{key.Keycode}}
+	for _, mk := range modifierKeycodes {
+		if key.Modifier&mk.mod != 0 {
+			chord.Modifiers = append(chord.Modifiers, mk.keycode)
+		}
+	}
+	return chord
+}
+
 // sendKeyWithModifiers sends a key press with shift and/or altgr modifiers.
 func (s *Server) sendKeyWithModifiers(ctx context.Context, keycode uint16, shift, altGr bool) error {
 	if !shift && !altGr {
 		// No modifiers, simple key press
-		return s.device.SendKey(ctx, keycode)
+		return s.keyboard(ctx).SendKey(ctx, keycode)
 	}
 
 	if shift && !altGr {
 		// Shift only
-		return s.device.SendKeyWithModifier(ctx, uinput.KeyLeftShift, keycode)
+		return s.keyboard(ctx).SendKeyWithModifier(ctx, uinput.KeyLeftShift, keycode)
 	}
 
 	if altGr && !shift {
 		// AltGr only
-		return s.device.SendKeyWithModifier(ctx, uinput.KeyRightAlt, keycode)
+		return s.keyboard(ctx).SendKeyWithModifier(ctx, uinput.KeyRightAlt, keycode)
 	}
 
 	// Both Shift + AltGr (e.g., for some special characters)
 	return s.sendKeyWithBothModifiers(ctx, keycode)
 }
 
-// sendKeyWithBothModifiers sends a key with both Shift and AltGr pressed.
-func (s *Server) sendKeyWithBothModifiers(ctx context.Context, keycode uint16) error {
-	// Press Shift
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(uinput.KeyLeftShift, true)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
-		return err
+// sendChord presses every key of the chord in order, then releases them in
+// reverse order. If a write fails midway, keys already pressed are released
+// so that nothing stays stuck on the virtual keyboard.
+func (s *Server) sendChord(ctx context.Context, chord *uinput.Chord) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
 	}
 
-	// Press AltGr
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(uinput.KeyRightAlt, true)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
-		return err
-	}
+	keycodes := chord.Keycodes()
+	pressed := make([]uint16, 0, len(keycodes))
 
-	// Press key
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(keycode, true)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
-		return err
+	var err error
+	for _, keycode := range keycodes {
+		if err = writeKey(s.keyboard(ctx), keycode, true); err != nil {
+			err = fmt.Errorf("key press %d: %w", keycode, err)
+			break
+		}
+		pressed = append(pressed, keycode)
 	}
 
-	// Release key
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(keycode, false)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
-		return err
+	// Release in reverse order, even after a failed press
+	for i := len(pressed) - 1; i >= 0; i-- {
+		if rerr := writeKey(s.keyboard(ctx), pressed[i], false); rerr != nil && err == nil {
+			err = fmt.Errorf("key release %d: %w", pressed[i], rerr)
+		}
 	}
 
-	// Release AltGr
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(uinput.KeyRightAlt, false)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
-		return err
-	}
+	return err
+}
 
-	// Release Shift
-	if err := s.device.WriteEvent(uinput.NewKeyEvent(uinput.KeyLeftShift, false)); err != nil {
-		return err
-	}
-	if err := s.device.WriteEvent(uinput.NewSynEvent()); err != nil {
+// writeKey writes a single key or button press or release followed by a
+// sync event.
+func writeKey(dev uinput.DeviceInterface, keycode uint16, pressed bool) error {
+	if err := dev.WriteEvent(uinput.NewKeyEvent(keycode, pressed)); err != nil {
 		return err
 	}
+	return dev.WriteEvent(uinput.NewSynEvent())
+}
 
-	return nil
+// sendKeyWithBothModifiers sends a key with both Shift and AltGr pressed.
+func (s *Server) sendKeyWithBothModifiers(ctx context.Context, keycode uint16) error {
+	return s.sendChord(ctx, &uinput.Chord{
+		Modifiers: []uint16{uinput.KeyLeftShift, uinput.KeyRightAlt},
+		Keys:      []uint16{keycode},
+	})
 }
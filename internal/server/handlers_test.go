@@ -313,6 +313,27 @@ func TestHandleKey(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name: "chord",
+			payload: protocol.KeyPayload{
+				Chord: "ctrl+shift+t",
+			},
+			setupMocks: func(device *uinputMocks.MockDeviceInterface) {
+				// 3 presses + 3 releases, each followed by a sync
+				device.On("WriteEvent", mock.Anything).Return(nil).Times(12)
+			},
+			expectedError: false,
+		},
+		{
+			name: "chord with unknown key",
+			payload: protocol.KeyPayload{
+				Chord: "ctrl+nosuchkey",
+			},
+			setupMocks: func(device *uinputMocks.MockDeviceInterface) {
+				// No expectations - should error before calling device
+			},
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -416,7 +437,7 @@ func TestHandleCommand(t *testing.T) {
 				}`),
 			}
 
-			err := server.handleCommand(context.Background(), cmd)
+			err := server.handleCommand(context.Background(), &clientConn{}, cmd)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// heldKey identifies a key or button held down on a given device.
+type heldKey struct {
+	dev  uinput.DeviceInterface
+	code uint16
+}
+
+// heldKeys tracks keys and buttons held down across all clients.
+// Presses are reference counted so that a key held by two clients is only
+// released on the device once neither of them holds it.
+type heldKeys struct {
+	mu     sync.Mutex
+	counts map[heldKey]int
+}
+
+// pressHeld records a hold on hk and presses it on its device if no other
+// client already holds it.
+func (s *Server) pressHeld(hk heldKey) error {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+
+	if s.held.counts == nil {
+		s.held.counts = make(map[heldKey]int)
+	}
+
+	if s.held.counts[hk] == 0 {
+		if err := writeKey(hk.dev, hk.code, true); err != nil {
+			return fmt.Errorf("key press %d: %w", hk.code, err)
+		}
+	}
+	s.held.counts[hk]++
+	return nil
+}
+
+// releaseHeld drops a hold on hk and releases it on its device once no
+// client holds it anymore.
+func (s *Server) releaseHeld(hk heldKey) error {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+
+	switch s.held.counts[hk] {
+	case 0:
+		return nil
+	case 1:
+		delete(s.held.counts, hk)
+		if err := writeKey(hk.dev, hk.code, false); err != nil {
+			return fmt.Errorf("key release %d: %w", hk.code, err)
+		}
+	default:
+		s.held.counts[hk]--
+	}
+	return nil
+}
+
+// hold records that the connection holds hk.
+func (cc *clientConn) hold(hk heldKey) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.held = append(cc.held, hk)
+}
+
+// holding reports whether the connection holds hk.
+func (cc *clientConn) holding(hk heldKey) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for _, k := range cc.held {
+		if k == hk {
+			return true
+		}
+	}
+	return false
+}
+
+// unhold removes hk from the keys held by the connection.
+func (cc *clientConn) unhold(hk heldKey) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for i, k := range cc.held {
+		if k == hk {
+			cc.held = append(cc.held[:i], cc.held[i+1:]...)
+			return
+		}
+	}
+}
+
+// heldCodes returns the codes held by the connection, in press order.
+func (cc *clientConn) heldCodes() []uint16 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	codes := make([]uint16, len(cc.held))
+	for i, k := range cc.held {
+		codes[i] = k.code
+	}
+	return codes
+}
+
+// releaseAll releases every key and button still held by the connection,
+// most recently pressed first.
+func (s *Server) releaseAll(cc *clientConn) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	var err error
+	for i := len(cc.held) - 1; i >= 0; i-- {
+		if rerr := s.releaseHeld(cc.held[i]); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	cc.held = nil
+	return err
+}
+
+// heldOn returns the codes held down on dev by any client.
+func (s *Server) heldOn(dev uinput.DeviceInterface) []uint16 {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+
+	var codes []uint16
+	for hk := range s.held.counts {
+		if hk.dev == dev {
+			codes = append(codes, hk.code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// errJobCancelled is the cause of the context of a cancelled job.
+var errJobCancelled = errors.New("job cancelled")
+
+// errPauseTimeout cancels jobs paused for longer than the
+// jobs.pause_timeout_ms config.
+var errPauseTimeout = fmt.Errorf("%w: paused for too long", errJobCancelled)
+
+// job is a running typing command: type, stream or a stream session.
+type job struct {
+	seq       uint64 // Start order
+	id        string
+	cmdType   protocol.CommandType
+	startedAt time.Time
+	cc        *clientConn // Connection that started the job
+	list      *jobList
+	cancel    context.CancelCauseFunc
+
+	// Characters of the text typed (or dropped) so far, and in total.
+	// Stream sessions count the characters of the chunks queued so far.
+	offset atomic.Int64
+	total  atomic.Int64
+
+	mu           sync.Mutex
+	queued       bool // Waiting for the devices
+	paused       bool
+	pausedAt     time.Time
+	resumed      chan struct{} // Closed when the paused job is resumed
+	pauseTimeout time.Duration // Zero waits forever
+}
+
+// jobList tracks the jobs of every connection, so that any connection can
+// cancel, pause or resume them.
+type jobList struct {
+	mu   sync.Mutex
+	next uint64
+	jobs map[string]*job
+}
+
+// jobKey is the context key of the job a command runs as.
+type jobKey struct{}
+
+// jobFromCtx returns the job the command runs as, if any.
+func jobFromCtx(ctx context.Context) *job {
+	j, _ := ctx.Value(jobKey{}).(*job)
+	return j
+}
+
+// startJob registers a job for a command of cc and returns the context
+// the job must run with. The job ID is added to the response. The job
+// must be ended once it is over.
+func (s *Server) startJob(ctx context.Context, cc *clientConn, cmdType protocol.CommandType) (context.Context, *job) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s.jobs.mu.Lock()
+	s.jobs.next++
+	j := &job{
+		seq:          s.jobs.next,
+		id:           strconv.FormatUint(s.jobs.next, 10),
+		cmdType:      cmdType,
+		startedAt:    time.Now(),
+		cc:           cc,
+		list:         &s.jobs,
+		cancel:       cancel,
+		pauseTimeout: time.Duration(s.cfg.Jobs.PauseTimeoutMs) * time.Millisecond,
+	}
+	if s.jobs.jobs == nil {
+		s.jobs.jobs = make(map[string]*job)
+	}
+	s.jobs.jobs[j.id] = j
+	s.jobs.mu.Unlock()
+
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Job = j.id
+	}
+	logger.LogFromCtx(ctx).Debug("job started", "job", j.id)
+
+	return context.WithValue(ctx, jobKey{}, j), j
+}
+
+// end unregisters the job and releases its context. It may be called more
+// than once.
+func (j *job) end() {
+	j.list.mu.Lock()
+	delete(j.list.jobs, j.id)
+	j.list.mu.Unlock()
+
+	j.cancel(nil)
+}
+
+// runningJobs returns the running jobs in start order.
+func (s *Server) runningJobs() []*job {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	jobs := make([]*job, 0, len(s.jobs.jobs))
+	for _, j := range s.jobs.jobs {
+		jobs = append(jobs, j)
+	}
+	slices.SortFunc(jobs, func(a, b *job) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	return jobs
+}
+
+// findJob returns the running job with the given ID.
+func (s *Server) findJob(id string) (*job, error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	j, ok := s.jobs.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown job: %s", id)
+	}
+	return j, nil
+}
+
+// info returns the job's description and progress.
+func (j *job) info() protocol.JobInfo {
+	info := protocol.JobInfo{
+		ID:        j.id,
+		Type:      j.cmdType,
+		StartedAt: j.startedAt,
+		State:     protocol.JobState_Running,
+		Offset:    int(j.offset.Load()),
+		Total:     int(j.total.Load()),
+	}
+	if j.cc != nil {
+		info.Peer = peerInfo(j.cc.peer)
+	}
+	if info.Total > 0 {
+		info.Percent = float64(info.Offset) * 100 / float64(info.Total)
+	}
+	j.mu.Lock()
+	switch {
+	case j.paused:
+		info.State = protocol.JobState_Paused
+	case j.queued:
+		info.State = protocol.JobState_Queued
+	}
+	j.mu.Unlock()
+	return info
+}
+
+// addJobText adds the characters of text to the total of the job in ctx.
+func addJobText(ctx context.Context, text string) {
+	if j := jobFromCtx(ctx); j != nil {
+		j.total.Add(int64(utf8.RuneCountInString(text)))
+	}
+}
+
+// progress records that the job in ctx is done with the characters before
+// offset.
+func progress(ctx context.Context, offset int) {
+	if j := jobFromCtx(ctx); j != nil {
+		j.offset.Store(int64(offset))
+	}
+}
+
+// checkpoint is called before typing the character at offset: it records
+// the progress of the job in ctx, waits while the job is paused, and
+// returns the cause of its cancellation, if any.
+func checkpoint(ctx context.Context, offset int) error {
+	progress(ctx, offset)
+	if j := jobFromCtx(ctx); j != nil {
+		if err := j.waitResumed(ctx); err != nil {
+			return err
+		}
+	}
+	return context.Cause(ctx)
+}
+
+// pause pauses the job before its next character. It reports whether the
+// job was running.
+func (j *job) pause() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.paused {
+		return false
+	}
+	j.paused, j.pausedAt = true, time.Now()
+	j.resumed = make(chan struct{})
+	return true
+}
+
+// resume resumes the paused job. It reports whether the job was paused.
+func (j *job) resume() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.paused {
+		return false
+	}
+	j.paused = false
+	close(j.resumed)
+	return true
+}
+
+// setQueued records whether the job waits for the devices.
+func (j *job) setQueued(queued bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.queued = queued
+}
+
+// isPaused reports whether the job is paused.
+func (j *job) isPaused() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.paused
+}
+
+// waitResumed blocks while the job is paused. The devices are given back
+// to other commands meanwhile, and the job waits for its turn again once
+// resumed. Jobs paused for longer than their pause timeout are cancelled.
+func (j *job) waitResumed(ctx context.Context) (err error) {
+	j.mu.Lock()
+	paused, resumed := j.paused, j.resumed
+	deadline := j.pausedAt.Add(j.pauseTimeout)
+	j.mu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	if tn := turnFromCtx(ctx); tn != nil {
+		tn.giveBack()
+		defer func() {
+			if err == nil {
+				err = tn.take(ctx)
+			}
+		}()
+	}
+
+	var expired <-chan time.Time
+	if j.pauseTimeout > 0 {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-expired:
+		logger.LogFromCtx(ctx).Warn("paused job timed out", "job", j.id, "timeout", j.pauseTimeout)
+		j.cancel(errPauseTimeout)
+		j.end()
+		return errPauseTimeout
+	}
+}
+
+// handleJobs lists the running jobs.
+func (s *Server) handleJobs(ctx context.Context) error {
+	jobs := s.runningJobs()
+
+	if resp := responseFromCtx(ctx); resp != nil {
+		for _, j := range jobs {
+			resp.Jobs = append(resp.Jobs, j.info())
+		}
+	}
+	return nil
+}
+
+// handleCancel aborts a job, or every job. Typing stops before the next
+// character, within the job's turn, and every character releases the
+// modifiers it pressed, so no modifier or dead key is left pressed. Keys the
+// job's client holds with key_down are left alone.
+func (s *Server) handleCancel(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	var p protocol.CancelPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid cancel payload: %w", err)
+	}
+
+	var jobs []*job
+	switch {
+	case p.All:
+		jobs = s.runningJobs()
+	case p.Job != "":
+		j, err := s.findJob(p.Job)
+		if err != nil {
+			return err
+		}
+		jobs = []*job{j}
+	default:
+		return fmt.Errorf("job or all is required")
+	}
+
+	resp := responseFromCtx(ctx)
+	for _, j := range jobs {
+		log.Info("cancelling job", "job", j.id, "type", j.cmdType)
+		if resp != nil {
+			resp.Jobs = append(resp.Jobs, j.info())
+		}
+
+		j.cancel(errJobCancelled)
+		j.end()
+	}
+
+	return nil
+}
+
+// handlePause pauses a job before its next character.
+func (s *Server) handlePause(ctx context.Context, payload json.RawMessage) error {
+	j, err := s.jobFromPayload(payload)
+	if err != nil {
+		return err
+	}
+
+	if j.pause() {
+		logger.LogFromCtx(ctx).Info("job paused", "job", j.id, "offset", j.offset.Load())
+	}
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Jobs = []protocol.JobInfo{j.info()}
+	}
+	return nil
+}
+
+// handleResume resumes a paused job where it left off.
+func (s *Server) handleResume(ctx context.Context, payload json.RawMessage) error {
+	j, err := s.jobFromPayload(payload)
+	if err != nil {
+		return err
+	}
+
+	if j.resume() {
+		logger.LogFromCtx(ctx).Info("job resumed", "job", j.id, "offset", j.offset.Load())
+	}
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Jobs = []protocol.JobInfo{j.info()}
+	}
+	return nil
+}
+
+// jobFromPayload returns the job a pause or resume command addresses.
+func (s *Server) jobFromPayload(payload json.RawMessage) (*job, error) {
+	var p protocol.JobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("invalid job payload: %w", err)
+	}
+	if p.Job == "" {
+		return nil, fmt.Errorf("job is required")
+	}
+	return s.findJob(p.Job)
+}
+
+// sleep waits for d, or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
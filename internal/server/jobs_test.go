@@ -0,0 +1,312 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// waitJob waits until a job is running and returns it.
+func waitJob(t *testing.T, s *Server) protocol.JobInfo {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if jobs := s.runningJobs(); len(jobs) > 0 {
+			return jobs[0].info()
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no job started")
+	return protocol.JobInfo{}
+}
+
+// cancelJob sends a cancel command and returns the cancelled jobs.
+func cancelJob(t *testing.T, s *Server, p protocol.CancelPayload) []protocol.JobInfo {
+	t.Helper()
+
+	resp := &protocol.Response{}
+	payload, _ := json.Marshal(p)
+	assert.NoError(t, s.handleCancel(withResponse(context.Background(), resp), payload))
+	return resp.Jobs
+}
+
+func TestCancelStream(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+
+	resp := &protocol.Response{}
+	done := make(chan error)
+	go func() {
+		payload, _ := json.Marshal(protocol.StreamPayload{Text: "abcdef", CharDelay: 5000, DelayMs: -1})
+		done <- server.handleCommand(withResponse(context.Background(), resp), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Stream, Payload: payload})
+	}()
+
+	info := waitJob(t, server)
+	assert.Equal(t, protocol.CommandType_Stream, info.Type)
+
+	start := time.Now()
+	cancelled := cancelJob(t, server, protocol.CancelPayload{Job: info.ID})
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, errJobCancelled)
+	case <-time.After(time.Second):
+		t.Fatal("stream not cancelled")
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	if assert.Len(t, cancelled, 1) {
+		assert.Equal(t, info.ID, cancelled[0].ID)
+	}
+
+	// Only the first character was typed before the delay was interrupted
+	assert.Equal(t, []uint16{uinput.KeyA}, *tapped)
+	assert.Equal(t, info.ID, resp.Job)
+	if assert.NotNil(t, resp.Result) {
+		assert.Equal(t, 1, resp.Result.Typed)
+	}
+	assert.Empty(t, server.runningJobs())
+}
+
+func TestCancelKeepsHeldKeys(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+
+	var released []uint16
+	device.On("WriteEvent", mock.MatchedBy(func(ev *uinput.InputEvent) bool {
+		return ev.Type == uinput.EvKey && ev.Value == uinput.KeyRelease
+	})).Run(func(args mock.Arguments) {
+		released = append(released, args.Get(0).(*uinput.InputEvent).Code)
+	}).Return(nil).Maybe()
+	recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	cc := &clientConn{}
+
+	payload, _ := json.Marshal(protocol.KeyHoldPayload{Chord: "shift"})
+	assert.NoError(t, server.handleKeyDown(context.Background(), cc, payload))
+
+	// A stream session is a job until it is closed
+	payload, _ = json.Marshal(protocol.StreamOpenPayload{CharDelay: 5000})
+	assert.NoError(t, server.handleStreamOpen(context.Background(), cc, payload))
+	cc.stream.enqueue("ab")
+
+	cancelled := cancelJob(t, server, protocol.CancelPayload{All: true})
+
+	assert.Len(t, cancelled, 1)
+	assert.Empty(t, released)
+	assert.Equal(t, []uint16{uinput.KeyLeftShift}, cc.heldCodes())
+
+	err := server.handleStreamFlush(context.Background(), cc)
+	assert.ErrorIs(t, err, errJobCancelled)
+	err = server.handleStreamClose(context.Background(), cc)
+	assert.ErrorIs(t, err, errJobCancelled)
+}
+
+func TestHandleCancelErrors(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	payload, _ := json.Marshal(protocol.CancelPayload{})
+	assert.ErrorContains(t, server.handleCancel(context.Background(), payload), "job or all is required")
+
+	payload, _ = json.Marshal(protocol.CancelPayload{Job: "42"})
+	assert.ErrorContains(t, server.handleCancel(context.Background(), payload), "unknown job: 42")
+
+	// Nothing to cancel is not an error
+	assert.Empty(t, cancelJob(t, server, protocol.CancelPayload{All: true}))
+}
+
+// jobCommand sends a pause or resume command and returns the job's info.
+func jobCommand(t *testing.T, handle func(context.Context, json.RawMessage) error, id string) protocol.JobInfo {
+	t.Helper()
+
+	resp := &protocol.Response{}
+	payload, _ := json.Marshal(protocol.JobPayload{Job: id})
+	assert.NoError(t, handle(withResponse(context.Background(), resp), payload))
+	if assert.Len(t, resp.Jobs, 1) {
+		return resp.Jobs[0]
+	}
+	return protocol.JobInfo{}
+}
+
+func TestPauseResumeStreamSession(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	cc := &clientConn{}
+
+	payload, _ := json.Marshal(protocol.StreamOpenPayload{CharDelay: 1})
+	assert.NoError(t, server.handleStreamOpen(context.Background(), cc, payload))
+	id := waitJob(t, server).ID
+
+	info := jobCommand(t, server.handlePause, id)
+	assert.Equal(t, protocol.JobState_Paused, info.State)
+
+	cc.stream.enqueue("ab")
+	time.Sleep(20 * time.Millisecond)
+
+	// Nothing is typed while paused, but the progress is reported
+	resp := &protocol.Response{}
+	assert.NoError(t, server.handleStatus(withResponse(context.Background(), resp)))
+	if assert.NotNil(t, resp.Status) && assert.Len(t, resp.Status.Jobs, 1) {
+		job := resp.Status.Jobs[0]
+		assert.Equal(t, protocol.JobState_Paused, job.State)
+		assert.Equal(t, 0, job.Offset)
+		assert.Equal(t, 2, job.Total)
+		assert.Equal(t, 0.0, job.Percent)
+	}
+
+	info = jobCommand(t, server.handleResume, id)
+	assert.Equal(t, protocol.JobState_Running, info.State)
+
+	assert.NoError(t, server.handleStreamFlush(context.Background(), cc))
+	assert.Equal(t, []uint16{uinput.KeyA, uinput.KeyB}, *tapped)
+
+	info = waitJob(t, server)
+	assert.Equal(t, 2, info.Offset)
+	assert.Equal(t, 100.0, info.Percent)
+
+	// Pausing or resuming twice is not an error
+	jobCommand(t, server.handleResume, id)
+	jobCommand(t, server.handlePause, id)
+	jobCommand(t, server.handlePause, id)
+	jobCommand(t, server.handleResume, id)
+
+	assert.NoError(t, server.handleStreamClose(context.Background(), cc))
+	assert.Empty(t, server.runningJobs())
+}
+
+func TestPauseTimeout(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.cfg.Jobs.PauseTimeoutMs = 10
+	cc := &clientConn{}
+
+	payload, _ := json.Marshal(protocol.StreamOpenPayload{})
+	assert.NoError(t, server.handleStreamOpen(context.Background(), cc, payload))
+	jobCommand(t, server.handlePause, waitJob(t, server).ID)
+
+	cc.stream.enqueue("ab")
+
+	err := server.handleStreamFlush(context.Background(), cc)
+	assert.ErrorIs(t, err, errPauseTimeout)
+	assert.ErrorIs(t, err, errJobCancelled)
+	assert.Empty(t, server.runningJobs())
+}
+
+func TestPausedStreamSurvivesDisconnect(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	cc := &clientConn{}
+
+	payload, _ := json.Marshal(protocol.StreamOpenPayload{CharDelay: 1})
+	assert.NoError(t, server.handleStreamOpen(context.Background(), cc, payload))
+	id := waitJob(t, server).ID
+	jobCommand(t, server.handlePause, id)
+	cc.stream.enqueue("ab")
+
+	server.closeConn(context.Background(), cc)
+	assert.Nil(t, cc.stream)
+	assert.Equal(t, id, waitJob(t, server).ID)
+
+	// Another connection resumes the job, which types the rest and ends
+	jobCommand(t, server.handleResume, id)
+
+	deadline := time.Now().Add(time.Second)
+	for len(server.runningJobs()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Empty(t, server.runningJobs())
+	assert.Equal(t, []uint16{uinput.KeyA, uinput.KeyB}, *tapped)
+}
+
+func TestCloseConnWaitsForRunningJob(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+
+	// Keystrokes of every client, in the order they reach the device
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	device.On("SendKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf("tap %d", args.Get(1)))
+	}).Return(nil)
+	device.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		if ev := args.Get(0).(*uinput.InputEvent); ev.Type == uinput.EvKey {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, fmt.Sprintf("key %d %d", ev.Code, ev.Value))
+		}
+	}).Return(nil)
+
+	server := newTestServer(device, layouts.NewRegistry())
+
+	holder := &clientConn{}
+	payload, _ := json.Marshal(protocol.KeyHoldPayload{Chord: "shift"})
+	assert.NoError(t, server.handleCommand(context.Background(), holder, &protocol.Command{Type: protocol.CommandType_KeyDown, Payload: payload}))
+
+	done := make(chan error)
+	go func() {
+		payload, _ := json.Marshal(protocol.StreamPayload{Text: "abc", CharDelay: 20, DelayMs: -1})
+		done <- server.handleCommand(context.Background(), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Stream, Payload: payload})
+	}()
+	waitJob(t, server)
+
+	// The holder disconnects while the other client types: its release
+	// waits for the typing to finish
+	closed := make(chan struct{})
+	go func() {
+		server.closeConn(context.Background(), holder)
+		close(closed)
+	}()
+	waitQueued(t, &server.queue, 1)
+
+	assert.NoError(t, <-done)
+	<-closed
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("key %d 1", uinput.KeyLeftShift),
+		fmt.Sprintf("tap %d", uinput.KeyA),
+		fmt.Sprintf("tap %d", uinput.KeyB),
+		fmt.Sprintf("tap %d", uinput.KeyC),
+		fmt.Sprintf("key %d 0", uinput.KeyLeftShift),
+	}, events)
+}
+
+func TestHandlePauseErrors(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	payload, _ := json.Marshal(protocol.JobPayload{})
+	assert.ErrorContains(t, server.handlePause(context.Background(), payload), "job is required")
+
+	payload, _ = json.Marshal(protocol.JobPayload{Job: "42"})
+	assert.ErrorContains(t, server.handlePause(context.Background(), payload), "unknown job: 42")
+	assert.ErrorContains(t, server.handleResume(context.Background(), payload), "unknown job: 42")
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errJobCancelled)
+
+	start := time.Now()
+	assert.ErrorIs(t, sleep(ctx, time.Minute), errJobCancelled)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.NoError(t, sleep(context.Background(), time.Millisecond))
+	assert.NoError(t, sleep(ctx, 0))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bnema/uinputd-go/internal/detect"
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// layoutDetector reports the keymap active in the desktop session.
+type layoutDetector interface {
+	Detect(ctx context.Context) (detect.Keymap, error)
+}
+
+// resolveLayout returns the layout a typing command uses: the one it
+// names, else the layout detected in the desktop session, else the
+// configured default. The resolved name is reported in the response, and
+// checked against the command's policy rule.
+func (s *Server) resolveLayout(ctx context.Context, name string) (layouts.Layout, string, error) {
+	if name == "" {
+		name = s.detectLayout(ctx)
+	}
+	if err := checkLayout(ctx, name); err != nil {
+		return nil, "", err
+	}
+
+	layout, err := s.registry.Get(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Layout = name
+	}
+	return layout, name, nil
+}
+
+// detectLayout returns the name of the session's active layout, or the
+// configured default if detection is disabled, fails, or finds a layout
+// the registry cannot provide.
+func (s *Server) detectLayout(ctx context.Context) string {
+	if s.detector == nil {
+		return s.cfg.Layout
+	}
+
+	log := logger.LogFromCtx(ctx)
+
+	km, err := s.detector.Detect(ctx)
+	if err != nil {
+		log.Debug("layout detection failed, using default", "layout", s.cfg.Layout, "error", err)
+		return s.cfg.Layout
+	}
+
+	name := km.RegistryName()
+	if _, err := s.registry.Get(name); err != nil {
+		log.Warn("detected layout unavailable, using default", "detected", km.String(), "layout", s.cfg.Layout, "error", err)
+		return s.cfg.Layout
+	}
+
+	log.Debug("layout detected", "layout", name)
+	return name
+}
+
+// responseKey is the context key of the response being built for a command.
+type responseKey struct{}
+
+// withResponse returns ctx carrying the response of the current command,
+// so that handlers can report details beyond success or failure.
+func withResponse(ctx context.Context, resp *protocol.Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, resp)
+}
+
+// responseFromCtx returns the response of the current command, if any.
+func responseFromCtx(ctx context.Context) *protocol.Response {
+	resp, _ := ctx.Value(responseKey{}).(*protocol.Response)
+	return resp
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/detect"
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeDetector reports a fixed keymap or error.
+type fakeDetector struct {
+	km  detect.Keymap
+	err error
+}
+
+func (d *fakeDetector) Detect(ctx context.Context) (detect.Keymap, error) {
+	return d.km, d.err
+}
+
+func TestResolveLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		detector layoutDetector
+		expected string
+	}{
+		{name: "explicit layout", layout: "de", detector: &fakeDetector{km: detect.Keymap{Layout: "fr"}}, expected: "de"},
+		{name: "config default without detector", expected: "us"},
+		{name: "detected layout", detector: &fakeDetector{km: detect.Keymap{Layout: "fr"}}, expected: "fr"},
+		{name: "detected gb maps to uk", detector: &fakeDetector{km: detect.Keymap{Layout: "gb"}}, expected: "uk"},
+		{name: "detection failure falls back", detector: &fakeDetector{err: errors.New("no session")}, expected: "us"},
+		{name: "unavailable detected layout falls back", detector: &fakeDetector{km: detect.Keymap{Layout: "missing"}}, expected: "us"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := layouts.NewRegistry()
+			registry.SetXKBDir(t.TempDir())
+
+			server := newTestServer(uinputMocks.NewMockDeviceInterface(t), registry)
+			server.detector = tt.detector
+
+			resp := &protocol.Response{}
+			layout, name, err := server.resolveLayout(withResponse(context.Background(), resp), tt.layout)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.expected, name)
+			assert.Equal(t, tt.expected, layout.Name())
+			assert.Equal(t, tt.expected, resp.Layout, "resolved layout reported in response")
+		})
+	}
+}
+
+func TestHandleTypeReportsDetectedLayout(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	device.On("SendKey", mock.Anything, mock.Anything).Return(nil)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	server.detector = &fakeDetector{km: detect.Keymap{Layout: "fr"}}
+
+	payload, _ := json.Marshal(protocol.TypePayload{Text: "a"})
+	resp := &protocol.Response{}
+	assert.NoError(t, server.handleType(withResponse(context.Background(), resp), payload))
+
+	assert.Equal(t, "fr", resp.Layout)
+	device.AssertCalled(t, "SendKey", mock.Anything, uint16(16)) // a is on KeyQ in AZERTY
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// defaultBufferSize is used when performance.buffer_size is not set.
+const defaultBufferSize = 4096
+
+// errMessageTooLarge rejects commands larger than
+// performance.max_message_size. The connection is closed afterwards, since
+// the rest of the command cannot be told apart from the next one.
+var errMessageTooLarge = errors.New("message too large")
+
+// messageReader reads the commands of a connection, failing with
+// errMessageTooLarge once a command exceeds its size limit. The decoder
+// reading from it may buffer the start of the next command, so a command
+// is bounded by twice the limit at worst.
+type messageReader struct {
+	r         *bufio.Reader
+	max       int64 // 0 for no limit
+	remaining int64
+}
+
+// newMessageReader reads from conn through a buffer of size bytes.
+func newMessageReader(conn net.Conn, size int, max int) *messageReader {
+	return &messageReader{
+		r:   bufio.NewReaderSize(conn, bufferSize(size)),
+		max: int64(max),
+	}
+}
+
+// next resets the size limit before decoding the next command.
+func (m *messageReader) next() {
+	m.remaining = m.max
+}
+
+func (m *messageReader) Read(p []byte) (int, error) {
+	if m.max <= 0 {
+		return m.r.Read(p)
+	}
+	if m.remaining <= 0 {
+		return 0, fmt.Errorf("%w (max %d bytes)", errMessageTooLarge, m.max)
+	}
+	if int64(len(p)) > m.remaining {
+		p = p[:m.remaining]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	return n, err
+}
+
+// responseWriter writes the responses of a connection through a buffer,
+// each of them within the write timeout.
+type responseWriter struct {
+	conn    net.Conn
+	w       *bufio.Writer
+	enc     *json.Encoder
+	timeout time.Duration // 0 for no timeout
+}
+
+// newResponseWriter writes to conn through a buffer of size bytes.
+func newResponseWriter(conn net.Conn, size int, timeout time.Duration) *responseWriter {
+	w := bufio.NewWriterSize(conn, bufferSize(size))
+	return &responseWriter{
+		conn:    conn,
+		w:       w,
+		enc:     json.NewEncoder(w),
+		timeout: timeout,
+	}
+}
+
+// send writes a response to the client.
+func (rw *responseWriter) send(resp *protocol.Response) error {
+	if rw.timeout > 0 {
+		if err := rw.conn.SetWriteDeadline(time.Now().Add(rw.timeout)); err != nil {
+			return err
+		}
+	}
+	if err := rw.enc.Encode(resp); err != nil {
+		return err
+	}
+	return rw.w.Flush()
+}
+
+// bufferSize returns the configured buffer size, or the default one.
+func bufferSize(size int) int {
+	if size <= 0 {
+		return defaultBufferSize
+	}
+	return size
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageReader(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	defer conn.Close()
+
+	go func() {
+		enc := json.NewEncoder(client)
+		enc.Encode(protocol.Command{Type: protocol.CommandType_Ping})
+		enc.Encode(protocol.Command{Type: protocol.CommandType_Ping})
+		enc.Encode(protocol.Command{Type: protocol.CommandType_Type, Payload: json.RawMessage(`"` + strings.Repeat("a", 500) + `"`)})
+	}()
+
+	reader := newMessageReader(conn, 16, 100)
+	decoder := json.NewDecoder(reader)
+
+	// The limit applies to each command, not to the connection
+	for range 2 {
+		reader.next()
+		var cmd protocol.Command
+		assert.NoError(t, decoder.Decode(&cmd))
+		assert.Equal(t, protocol.CommandType_Ping, cmd.Type)
+	}
+
+	reader.next()
+	var cmd protocol.Command
+	err := decoder.Decode(&cmd)
+	assert.ErrorIs(t, err, errMessageTooLarge)
+	assert.ErrorContains(t, err, "max 100 bytes")
+}
+
+func TestBufferSize(t *testing.T) {
+	assert.Equal(t, defaultBufferSize, bufferSize(0))
+	assert.Equal(t, 512, bufferSize(512))
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// homeDistance is the relative move used to push the pointer against the
+// top-left corner before an emulated absolute move. Compositors clamp the
+// pointer to the screen, so any value larger than the desktop works.
+const homeDistance = 1 << 16
+
+// errNoPointer is returned by mouse commands when no pointer device exists.
+var errNoPointer = errors.New("pointer device not available")
+
+// SetPointer attaches the virtual pointer device used by mouse commands.
+// Without a pointer, mouse commands fail with an error.
+func (s *Server) SetPointer(pointer uinput.DeviceInterface) {
+	s.pointer = pointer
+}
+
+// handleMouseMove moves the pointer relatively or to absolute coordinates.
+// Absolute moves go to the absolute pointer when it is enabled in tablet
+// mode, even without the relative pointer, and are otherwise emulated on
+// the relative pointer.
+func (s *Server) handleMouseMove(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	var p protocol.MouseMovePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid mouse move payload: %w", err)
+	}
+
+	if p.Absolute {
+		if p.X < 0 || p.Y < 0 {
+			return fmt.Errorf("absolute coordinates must not be negative")
+		}
+		if abs := s.absoluteTablet(ctx); abs != nil {
+			log.Debug("moving absolute pointer to", "x", p.X, "y", p.Y)
+			return moveAbsolute(abs, p.X, p.Y)
+		}
+	}
+
+	pointer, err := s.pointerDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	if p.Absolute {
+		log.Debug("moving pointer to", "x", p.X, "y", p.Y)
+		if err := movePointer(pointer, -homeDistance, -homeDistance); err != nil {
+			return fmt.Errorf("home pointer: %w", err)
+		}
+	} else {
+		log.Debug("moving pointer by", "dx", p.X, "dy", p.Y)
+	}
+
+	return movePointer(pointer, p.X, p.Y)
+}
+
+// handleMouseClick clicks a button one or more times.
+func (s *Server) handleMouseClick(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	pointer, err := s.pointerDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	button, count, err := parseMouseButton(payload)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("clicking button", "button", button, "count", count)
+
+	for i := 0; i < count; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := writeKey(pointer, button, true); err != nil {
+			return fmt.Errorf("button press %d: %w", button, err)
+		}
+		if err := writeKey(pointer, button, false); err != nil {
+			return fmt.Errorf("button release %d: %w", button, err)
+		}
+	}
+
+	return nil
+}
+
+// handleMouseDown presses and holds a button until mouse_up or disconnect.
+func (s *Server) handleMouseDown(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	pointer, err := s.pointerDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	button, _, err := parseMouseButton(payload)
+	if err != nil {
+		return err
+	}
+
+	hk := heldKey{dev: pointer, code: button}
+	if cc.holding(hk) {
+		return nil // Already held by this client
+	}
+
+	log.Info("holding button", "button", button)
+
+	if err := s.pressHeld(hk); err != nil {
+		return err
+	}
+	cc.hold(hk)
+	return nil
+}
+
+// handleMouseUp releases a button previously held with mouse_down.
+func (s *Server) handleMouseUp(ctx context.Context, cc *clientConn, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	pointer, err := s.pointerDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	button, _, err := parseMouseButton(payload)
+	if err != nil {
+		return err
+	}
+
+	hk := heldKey{dev: pointer, code: button}
+	if !cc.holding(hk) {
+		return fmt.Errorf("button %d is not held", button)
+	}
+
+	log.Info("releasing button", "button", button)
+
+	cc.unhold(hk)
+	return s.releaseHeld(hk)
+}
+
+// handleMouseScroll scrolls the vertical and horizontal wheels.
+// Both the legacy and the high-resolution wheel axes are reported, so that
+// clients that only understand detents still see full notches.
+func (s *Server) handleMouseScroll(ctx context.Context, payload json.RawMessage) error {
+	log := logger.LogFromCtx(ctx)
+
+	pointer, err := s.pointerDevice(ctx)
+	if err != nil {
+		return err
+	}
+
+	var p protocol.MouseScrollPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid mouse scroll payload: %w", err)
+	}
+
+	if p.Vertical == 0 && p.Horizontal == 0 {
+		return fmt.Errorf("vertical or horizontal scroll amount is required")
+	}
+
+	log.Debug("scrolling", "vertical", p.Vertical, "horizontal", p.Horizontal, "hi_res", p.HiRes)
+
+	events := make([]*uinput.InputEvent, 0, 5)
+	events = append(events, wheelEvents(uinput.RelWheel, uinput.RelWheelHiRes, p.Vertical, p.HiRes)...)
+	events = append(events, wheelEvents(uinput.RelHWheel, uinput.RelHWheelHiRes, p.Horizontal, p.HiRes)...)
+	events = append(events, uinput.NewSynEvent())
+
+	for _, ev := range events {
+		if err := pointer.WriteEvent(ev); err != nil {
+			return fmt.Errorf("scroll: %w", err)
+		}
+	}
+	return nil
+}
+
+// wheelEvents returns the events for one wheel. Without hiRes, amount is
+// in detents; with hiRes it is in 1/120 of a detent and the legacy axis
+// only reports the whole detents it contains.
+func wheelEvents(axis, hiResAxis uint16, amount int32, hiRes bool) []*uinput.InputEvent {
+	if amount == 0 {
+		return nil
+	}
+
+	detents, units := amount, amount*uinput.WheelHiResUnit
+	if hiRes {
+		detents, units = amount/uinput.WheelHiResUnit, amount
+	}
+
+	events := []*uinput.InputEvent{uinput.NewRelEvent(hiResAxis, units)}
+	if detents != 0 {
+		events = append(events, uinput.NewRelEvent(axis, detents))
+	}
+	return events
+}
+
+// movePointer writes a relative motion followed by a sync event.
+func movePointer(pointer uinput.DeviceInterface, dx, dy int32) error {
+	if dx != 0 {
+		if err := pointer.WriteEvent(uinput.NewRelEvent(uinput.RelX, dx)); err != nil {
+			return err
+		}
+	}
+	if dy != 0 {
+		if err := pointer.WriteEvent(uinput.NewRelEvent(uinput.RelY, dy)); err != nil {
+			return err
+		}
+	}
+	return pointer.WriteEvent(uinput.NewSynEvent())
+}
+
+// parseMouseButton decodes a mouse button payload into a button code and
+// a click count (at least 1).
+func parseMouseButton(payload json.RawMessage) (uint16, int, error) {
+	var p protocol.MouseButtonPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return 0, 0, fmt.Errorf("invalid mouse button payload: %w", err)
+	}
+
+	name := p.Button
+	if name == "" {
+		name = "left"
+	}
+	button, ok := uinput.LookupButton(name)
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown mouse button: %s", p.Button)
+	}
+
+	if p.Count < 0 {
+		return 0, 0, fmt.Errorf("click count must not be negative")
+	}
+	count := p.Count
+	if count == 0 {
+		count = 1
+	}
+
+	return button, count, nil
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/config"
+	layoutMocks "github.com/bnema/uinputd-go/internal/layouts/mocks"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newPointerTestServer creates a test server with a mocked pointer that
+// records every event written to it.
+func newPointerTestServer(t *testing.T, events *[]uinput.InputEvent) *Server {
+	pointer := uinputMocks.NewMockDeviceInterface(t)
+	pointer.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+		*events = append(*events, *args.Get(0).(*uinput.InputEvent))
+	}).Return(nil).Maybe()
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+	server.SetPointer(pointer)
+	return server
+}
+
+// eventCodes reduces events to (type, code, value) triples for comparison.
+func eventCodes(events []uinput.InputEvent) [][3]int32 {
+	codes := make([][3]int32, len(events))
+	for i, ev := range events {
+		codes[i] = [3]int32{int32(ev.Type), int32(ev.Code), ev.Value}
+	}
+	return codes
+}
+
+func TestHandleMouseMove(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       protocol.MouseMovePayload
+		expected      [][3]int32
+		expectedError bool
+	}{
+		{
+			name:    "relative move",
+			payload: protocol.MouseMovePayload{X: 10, Y: -5},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelX, 10},
+				{uinput.EvRel, uinput.RelY, -5},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "vertical only",
+			payload: protocol.MouseMovePayload{Y: 3},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelY, 3},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "absolute move homes first",
+			payload: protocol.MouseMovePayload{X: 100, Y: 200, Absolute: true},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelX, -homeDistance},
+				{uinput.EvRel, uinput.RelY, -homeDistance},
+				{uinput.EvSyn, uinput.SynReport, 0},
+				{uinput.EvRel, uinput.RelX, 100},
+				{uinput.EvRel, uinput.RelY, 200},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "negative absolute coordinates",
+			payload:       protocol.MouseMovePayload{X: -1, Y: 0, Absolute: true},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			server := newPointerTestServer(t, &events)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleMouseMove(context.Background(), payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, events)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, eventCodes(events))
+		})
+	}
+}
+
+func TestHandleMouseMoveAbsoluteDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		payload       protocol.MouseMovePayload
+		noPointer     bool // devices.pointer: false
+		expected      [][3]int32
+		emulated      bool
+		expectedError bool
+	}{
+		{
+			name:    "tablet",
+			mode:    "tablet",
+			payload: protocol.MouseMovePayload{X: 100, Y: 200, Absolute: true},
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, 100},
+				{uinput.EvAbs, uinput.AbsY, 200},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:      "tablet without pointer",
+			mode:      "tablet",
+			payload:   protocol.MouseMovePayload{X: 100, Y: 200, Absolute: true},
+			noPointer: true,
+			expected: [][3]int32{
+				{uinput.EvAbs, uinput.AbsX, 100},
+				{uinput.EvAbs, uinput.AbsY, 200},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "relative move without pointer",
+			mode:          "tablet",
+			payload:       protocol.MouseMovePayload{X: 10, Y: 5},
+			noPointer:     true,
+			expectedError: true,
+		},
+		{
+			name:          "outside the tablet",
+			mode:          "tablet",
+			payload:       protocol.MouseMovePayload{X: 1920, Y: 0, Absolute: true},
+			expectedError: true,
+		},
+		{
+			name:     "touchscreen is emulated",
+			mode:     "touchscreen",
+			payload:  protocol.MouseMovePayload{X: 100, Y: 200, Absolute: true},
+			emulated: true,
+		},
+		{
+			name:     "relative move",
+			mode:     "tablet",
+			payload:  protocol.MouseMovePayload{X: 10, Y: 5},
+			emulated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events, absEvents []uinput.InputEvent
+			server := newPointerTestServer(t, &events)
+			if tt.noPointer {
+				server.SetPointer(nil)
+			}
+
+			absolute := uinputMocks.NewMockDeviceInterface(t)
+			absolute.On("WriteEvent", mock.Anything).Run(func(args mock.Arguments) {
+				absEvents = append(absEvents, *args.Get(0).(*uinput.InputEvent))
+			}).Return(nil).Maybe()
+			server.cfg.Devices.Absolute = config.AbsoluteConfig{
+				Enabled: true,
+				Mode:    tt.mode,
+				Width:   1920,
+				Height:  1080,
+			}
+			server.SetAbsolute(absolute)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleMouseMove(context.Background(), payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, events)
+				assert.Empty(t, absEvents)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.emulated {
+				assert.NotEmpty(t, events)
+				assert.Empty(t, absEvents)
+				return
+			}
+			assert.Empty(t, events)
+			assert.Equal(t, tt.expected, eventCodes(absEvents))
+		})
+	}
+}
+
+func TestHandleMouseClick(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       protocol.MouseButtonPayload
+		button        uint16
+		clicks        int
+		expectedError bool
+	}{
+		{name: "default left click", payload: protocol.MouseButtonPayload{}, button: uinput.BtnLeft, clicks: 1},
+		{name: "right double click", payload: protocol.MouseButtonPayload{Button: "right", Count: 2}, button: uinput.BtnRight, clicks: 2},
+		{name: "unknown button", payload: protocol.MouseButtonPayload{Button: "wheel"}, expectedError: true},
+		{name: "negative count", payload: protocol.MouseButtonPayload{Count: -1}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			server := newPointerTestServer(t, &events)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleMouseClick(context.Background(), payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var expected [][3]int32
+			for i := 0; i < tt.clicks; i++ {
+				expected = append(expected,
+					[3]int32{uinput.EvKey, int32(tt.button), uinput.KeyPress},
+					[3]int32{uinput.EvSyn, uinput.SynReport, 0},
+					[3]int32{uinput.EvKey, int32(tt.button), uinput.KeyRelease},
+					[3]int32{uinput.EvSyn, uinput.SynReport, 0},
+				)
+			}
+			assert.Equal(t, expected, eventCodes(events))
+		})
+	}
+}
+
+func TestHandleMouseScroll(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       protocol.MouseScrollPayload
+		expected      [][3]int32
+		expectedError bool
+	}{
+		{
+			name:    "detents",
+			payload: protocol.MouseScrollPayload{Vertical: -2},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelWheelHiRes, -240},
+				{uinput.EvRel, uinput.RelWheel, -2},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "hi-res partial detent",
+			payload: protocol.MouseScrollPayload{Vertical: 60, HiRes: true},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelWheelHiRes, 60},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:    "hi-res both wheels",
+			payload: protocol.MouseScrollPayload{Vertical: 240, Horizontal: -120, HiRes: true},
+			expected: [][3]int32{
+				{uinput.EvRel, uinput.RelWheelHiRes, 240},
+				{uinput.EvRel, uinput.RelWheel, 2},
+				{uinput.EvRel, uinput.RelHWheelHiRes, -120},
+				{uinput.EvRel, uinput.RelHWheel, -1},
+				{uinput.EvSyn, uinput.SynReport, 0},
+			},
+		},
+		{
+			name:          "nothing to scroll",
+			payload:       protocol.MouseScrollPayload{},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []uinput.InputEvent
+			server := newPointerTestServer(t, &events)
+
+			payloadBytes, _ := json.Marshal(tt.payload)
+			err := server.handleMouseScroll(context.Background(), payloadBytes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, eventCodes(events))
+		})
+	}
+}
+
+func TestHandleMouseDownUp(t *testing.T) {
+	var events []uinput.InputEvent
+	server := newPointerTestServer(t, &events)
+	cc := &clientConn{}
+	ctx := context.Background()
+
+	left, _ := json.Marshal(protocol.MouseButtonPayload{Button: "left"})
+
+	// Releasing a button that is not held fails
+	assert.Error(t, server.handleMouseUp(ctx, cc, left))
+
+	assert.NoError(t, server.handleMouseDown(ctx, cc, left))
+	assert.Len(t, cc.held, 1)
+
+	// Disconnect releases the held button
+	server.closeConn(ctx, cc)
+	assert.Empty(t, cc.held)
+
+	assert.Equal(t, [][3]int32{
+		{uinput.EvKey, uinput.BtnLeft, uinput.KeyPress},
+		{uinput.EvSyn, uinput.SynReport, 0},
+		{uinput.EvKey, uinput.BtnLeft, uinput.KeyRelease},
+		{uinput.EvSyn, uinput.SynReport, 0},
+	}, eventCodes(events))
+}
+
+func TestHandleMouseWithoutPointer(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layoutMocks.NewMockRegistryInterface(t))
+
+	cmd := &protocol.Command{
+		Type:    protocol.CommandType_MouseClick,
+		Payload: json.RawMessage(`{}`),
+	}
+	err := server.handleCommand(context.Background(), &clientConn{}, cmd)
+	assert.ErrorIs(t, err, errNoPointer)
+}
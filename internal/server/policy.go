@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/policy"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// errPermissionDenied rejects commands the policy does not allow the
+// client.
+var errPermissionDenied = errors.New("permission denied")
+
+// authorize checks the command against the policy rule of the connection
+// and returns ctx carrying the rule, for the checks handlers make on
+// layouts and chords. The rule's rate is enforced by the rate limiter. Everything is allowed without a policy file.
+// Denials are logged with the client's identity, which the connection's
+// logger carries.
+func (s *Server) authorize(ctx context.Context, cc *clientConn, cmd *protocol.Command) (context.Context, error) {
+	if s.policy == nil {
+		return ctx, nil
+	}
+
+	rule := cc.rule
+	switch {
+	case rule == nil:
+		return ctx, deny(ctx, "no policy rule matches the client")
+	case !rule.AllowsCommand(string(cmd.Type)):
+		return ctx, deny(ctx, fmt.Sprintf("rule %s does not allow %s commands", rule.Name, cmd.Type))
+	}
+
+	return context.WithValue(ctx, ruleKey{}, rule), nil
+}
+
+// checkLayout rejects layouts the command's policy rule does not allow.
+func checkLayout(ctx context.Context, name string) error {
+	rule := ruleFromCtx(ctx)
+	if rule == nil || rule.AllowsLayout(name) {
+		return nil
+	}
+	return deny(ctx, fmt.Sprintf("rule %s does not allow layout %s", rule.Name, name))
+}
+
+// checkChord rejects pressing codes on the command's keyboard if, with the
+// keys already held there by any client, they form a chord the command's
+// policy rule denies.
+func (s *Server) checkChord(ctx context.Context, codes ...uint16) error {
+	rule := ruleFromCtx(ctx)
+	if rule == nil {
+		return nil
+	}
+
+	keys := append(s.heldOn(s.keyboard(ctx)), codes...)
+	if chord, denied := rule.DeniedChord(keys); denied {
+		return deny(ctx, fmt.Sprintf("rule %s denies chord %s", rule.Name, chord))
+	}
+	return nil
+}
+
+// deny logs a denied command and returns the error reported to the client.
+func deny(ctx context.Context, reason string) error {
+	logger.LogFromCtx(ctx).Warn("command denied", "reason", reason)
+	return fmt.Errorf("%w: %s", errPermissionDenied, reason)
+}
+
+// ruleKey is the context key of the policy rule applying to a command.
+type ruleKey struct{}
+
+// ruleFromCtx returns the policy rule applying to the current command, or
+// nil without a policy file.
+func ruleFromCtx(ctx context.Context) *policy.Rule {
+	rule, _ := ctx.Value(ruleKey{}).(*policy.Rule)
+	return rule
+}
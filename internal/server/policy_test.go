@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/policy"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+deny_chords: [ctrl+alt+f*]
+rules:
+  - name: typist
+    match: { uid: 1000 }
+    commands: [type, key, keydown, keyup]
+    layouts: [us]
+    deny_chords: [super+l, ctrl+shift+u]
+  - name: slow
+    match: { uid: 1001 }
+    max_commands_per_second: 1
+`
+
+// newPolicyServer returns a test server enforcing testPolicy, and a
+// connection of a client with the uid.
+func newPolicyServer(t *testing.T, device uinput.DeviceInterface, uid uint32) (*Server, *clientConn) {
+	t.Helper()
+
+	p, err := policy.Parse("test.yaml", []byte(testPolicy))
+	assert.NoError(t, err)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	server.policy = p
+	server.limiter, _ = newRateLimiter(config.RateLimitConfig{}, p)
+
+	cred := &peer.Cred{UID: uid}
+	return server, &clientConn{peer: cred, rule: p.Match(cred)}
+}
+
+func TestAuthorize(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	typeA, _ := json.Marshal(protocol.TypePayload{Text: "a", Layout: "us"})
+	typeFr, _ := json.Marshal(protocol.TypePayload{Text: "a", Layout: "fr"})
+	mouse, _ := json.Marshal(protocol.MouseMovePayload{X: 1})
+
+	tests := []struct {
+		name   string
+		uid    uint32
+		cmd    protocol.Command
+		denied bool
+	}{
+		{"allowed command", 1000, protocol.Command{Type: protocol.CommandType_Type, Payload: typeA}, false},
+		{"command not allowed", 1000, protocol.Command{Type: protocol.CommandType_MouseMove, Payload: mouse}, true},
+		{"layout not allowed", 1000, protocol.Command{Type: protocol.CommandType_Type, Payload: typeFr}, true},
+		{"no matching rule", 0, protocol.Command{Type: protocol.CommandType_Ping}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*tapped = nil
+			server, cc := newPolicyServer(t, device, tt.uid)
+
+			err := server.handleCommand(context.Background(), cc, &tt.cmd)
+			if tt.denied {
+				assert.ErrorIs(t, err, errPermissionDenied)
+				assert.Empty(t, *tapped)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutPolicy(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	ctx, err := server.authorize(context.Background(), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Ping})
+	assert.NoError(t, err)
+	assert.Nil(t, ruleFromCtx(ctx))
+}
+
+func TestPolicyRateLimit(t *testing.T) {
+	server, cc := newPolicyServer(t, uinputMocks.NewMockDeviceInterface(t), 1001)
+	ping := &protocol.Command{Type: protocol.CommandType_Ping}
+
+	assert.NoError(t, server.handleCommand(context.Background(), cc, ping))
+	err := server.handleCommand(context.Background(), cc, ping)
+	assert.ErrorIs(t, err, errRateLimited)
+	assert.ErrorContains(t, err, "1 commands per second (rule slow)")
+
+	assert.Equal(t, []protocol.RateLimitStatus{
+		{Rule: "slow", CommandsPerSecond: 1, Commands: 1, Rejected: 1},
+	}, server.limiter.status())
+}
+
+func TestPolicyRateLimitDelay(t *testing.T) {
+	p, err := policy.Parse("test.yaml", []byte(testPolicy))
+	assert.NoError(t, err)
+
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.policy = p
+	server.limiter, _ = newRateLimiter(config.RateLimitConfig{
+		Action:     rateLimitDelay,
+		MaxDelayMs: 2000,
+		Global:     config.RateLimits{CommandsPerSecond: 100},
+	}, p)
+
+	// The rule's rate follows the configured action, within the global one
+	cred := &peer.Cred{UID: 1001}
+	cc := &clientConn{peer: cred, rule: p.Match(cred)}
+	ping := &protocol.Command{Type: protocol.CommandType_Ping}
+	assert.NoError(t, server.handleCommand(context.Background(), cc, ping))
+	start := time.Now()
+	assert.NoError(t, server.handleCommand(context.Background(), cc, ping))
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	status := server.limiter.status()
+	assert.Len(t, status, 3, "global, rule and uid scopes")
+	assert.Equal(t, uint64(2), status[0].Commands)
+	assert.Equal(t, "slow", status[1].Rule)
+	assert.Equal(t, uint64(1), status[1].Delayed)
+}
+
+func TestPolicyDeniedChords(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	_, pressed := recordKeys(device)
+	server, cc := newPolicyServer(t, device, 1000)
+
+	key := func(cc *clientConn, cmdType protocol.CommandType, payload any) error {
+		data, _ := json.Marshal(payload)
+		return server.handleCommand(context.Background(), cc, &protocol.Command{Type: cmdType, Payload: data})
+	}
+
+	// Chords, and keys with a modifier
+	assert.ErrorIs(t, key(cc, protocol.CommandType_Key, protocol.KeyPayload{Chord: "ctrl+alt+f2"}), errPermissionDenied)
+	assert.ErrorIs(t, key(cc, protocol.CommandType_KeyDown, protocol.KeyHoldPayload{Chord: "rctrl+altgr+f3"}), errPermissionDenied)
+	assert.NoError(t, key(cc, protocol.CommandType_Key, protocol.KeyPayload{Chord: "ctrl+f2"}))
+	assert.Equal(t, []uint16{uinput.KeyLeftCtrl, uinput.KeyF2}, *pressed)
+
+	// Modifiers held by another client count too
+	*pressed = nil
+	other := &clientConn{peer: cc.peer, rule: cc.rule}
+	assert.NoError(t, key(other, protocol.CommandType_KeyDown, protocol.KeyHoldPayload{Chord: "ctrl+alt"}))
+	assert.ErrorIs(t, key(cc, protocol.CommandType_Key, protocol.KeyPayload{Keycode: uinput.KeyF1}), errPermissionDenied)
+	assert.ErrorIs(t, key(cc, protocol.CommandType_Key, protocol.KeyPayload{Keycode: uinput.KeyF1, Modifier: "shift"}), errPermissionDenied)
+	assert.Equal(t, []uint16{uinput.KeyLeftCtrl, uinput.KeyLeftAlt}, *pressed)
+
+	assert.NoError(t, key(other, protocol.CommandType_KeyUp, protocol.KeyHoldPayload{Chord: "ctrl+alt"}))
+	assert.NoError(t, key(cc, protocol.CommandType_Key, protocol.KeyPayload{Keycode: uinput.KeyF1}))
+}
+
+func TestPolicyDeniedChordsWhileTyping(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, pressed := recordKeys(device)
+	server, cc := newPolicyServer(t, device, 1000)
+
+	command := func(cmdType protocol.CommandType, payload any) error {
+		data, _ := json.Marshal(payload)
+		return server.handleCommand(context.Background(), cc, &protocol.Command{Type: cmdType, Payload: data})
+	}
+
+	// A held modifier and a typed key make the denied super+l
+	assert.NoError(t, command(protocol.CommandType_KeyDown, protocol.KeyHoldPayload{Chord: "super"}))
+	err := command(protocol.CommandType_Type, protocol.TypePayload{Text: "al", Layout: "us"})
+	assert.ErrorIs(t, err, errPermissionDenied)
+	assert.Equal(t, []uint16{uinput.KeyA}, *tapped)
+	assert.Equal(t, []uint16{uinput.KeyLeftMeta}, *pressed)
+
+	assert.NoError(t, command(protocol.CommandType_KeyUp, protocol.KeyHoldPayload{Chord: "super"}))
+	*tapped = nil
+	assert.NoError(t, command(protocol.CommandType_Type, protocol.TypePayload{Text: "l", Layout: "us"}))
+	assert.Equal(t, []uint16{uinput.KeyL}, *tapped)
+}
+
+func TestPolicyDeniedChordsInFallback(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, pressed := recordKeys(device)
+	server, cc := newPolicyServer(t, device, 1000)
+
+	// The unicode_hex fallback starts with the denied ctrl+shift+u
+	payload, _ := json.Marshal(protocol.TypePayload{Text: "→", Layout: "us", Fallback: protocol.Fallback_UnicodeHex})
+	err := server.handleCommand(context.Background(), cc, &protocol.Command{Type: protocol.CommandType_Type, Payload: payload})
+	assert.ErrorIs(t, err, errPermissionDenied)
+	assert.Empty(t, *tapped)
+	assert.Empty(t, *pressed)
+}
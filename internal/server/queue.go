@@ -0,0 +1,250 @@
+package server
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// errQueueFull rejects commands while performance.max_concurrent_cmds
+// commands are already waiting for the devices.
+var errQueueFull = errors.New("command queue full")
+
+// queuedCommands are the commands driving devices. They run one at a time,
+// so that the keystrokes of concurrent commands never interleave.
+var queuedCommands = map[protocol.CommandType]bool{
+	protocol.CommandType_Type:          true,
+	protocol.CommandType_Stream:        true,
+	protocol.CommandType_Key:           true,
+	protocol.CommandType_KeyDown:       true,
+	protocol.CommandType_KeyUp:         true,
+	protocol.CommandType_MouseMove:     true,
+	protocol.CommandType_MouseClick:    true,
+	protocol.CommandType_MouseDown:     true,
+	protocol.CommandType_MouseUp:       true,
+	protocol.CommandType_MouseScroll:   true,
+	protocol.CommandType_AbsPointer:    true,
+	protocol.CommandType_GamepadButton: true,
+	protocol.CommandType_GamepadAxis:   true,
+}
+
+// cmdQueue serializes the commands driving devices. The devices go to one
+// command at a time: when released, to the waiting command with the
+// highest priority, then the oldest. Stream sessions take a turn per
+// chunk. The zero value is an empty queue without limit.
+type cmdQueue struct {
+	max int // Maximum number of waiting commands, 0 for no limit
+
+	mu      sync.Mutex
+	next    uint64
+	waiting ticketHeap
+	busy    bool // A command holds the devices
+}
+
+// ticket is a command waiting for, or holding, the devices.
+type ticket struct {
+	q        *cmdQueue
+	priority int
+	seq      uint64 // Arrival order
+	index    int    // Position in the heap, -1 once granted
+	granted  chan struct{}
+	once     sync.Once
+}
+
+// release hands the devices to the next command. It may be called more
+// than once.
+func (t *ticket) release() {
+	t.once.Do(t.q.handOff)
+}
+
+// ticketHeap orders waiting tickets by priority, then arrival.
+type ticketHeap []*ticket
+
+func (h ticketHeap) Len() int { return len(h) }
+
+func (h ticketHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h ticketHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *ticketHeap) Push(x any) {
+	t := x.(*ticket)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *ticketHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	t.index = -1
+	return t
+}
+
+// acquire waits until the devices are handed to the caller, who must
+// release the ticket once done with them. Free devices are granted at
+// once; only commands waiting behind another count against the limit.
+func (q *cmdQueue) acquire(ctx context.Context, priority int) (*ticket, error) {
+	return q.acquireAt(ctx, priority, 0)
+}
+
+// place reserves a place in arrival order, for acquireAt.
+func (q *cmdQueue) place() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.next++
+	return q.next
+}
+
+// acquireAt is acquire for a place reserved with place, or a new one if
+// seq is 0. Commands with a reserved place were already admitted, so they
+// are never refused for a full queue.
+func (q *cmdQueue) acquireAt(ctx context.Context, priority int, seq uint64) (*ticket, error) {
+	q.mu.Lock()
+	admitted := seq != 0
+	if !admitted {
+		q.next++
+		seq = q.next
+	}
+	t := &ticket{
+		q:        q,
+		priority: priority,
+		seq:      seq,
+		index:    -1,
+		granted:  make(chan struct{}),
+	}
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return t, nil
+	}
+	if !admitted && q.max > 0 && q.waiting.Len() >= q.max {
+		q.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d commands waiting", errQueueFull, q.max)
+	}
+	heap.Push(&q.waiting, t)
+	q.mu.Unlock()
+
+	select {
+	case <-t.granted:
+		return t, nil
+	case <-ctx.Done():
+	}
+
+	// Leave the queue, or give the devices back if they were just granted
+	q.mu.Lock()
+	waiting := t.index >= 0
+	if waiting {
+		heap.Remove(&q.waiting, t.index)
+	}
+	q.mu.Unlock()
+	if !waiting {
+		t.release()
+	}
+	return nil, context.Cause(ctx)
+}
+
+// handOff grants the devices to the next waiting command, if any.
+func (q *cmdQueue) handOff() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting.Len() == 0 {
+		q.busy = false
+		return
+	}
+	t := heap.Pop(&q.waiting).(*ticket)
+	close(t.granted)
+}
+
+// cleanupPriority is the priority of the releases and resets undoing what
+// a disconnected client left held, so that they run before the commands
+// already waiting.
+const cleanupPriority = math.MaxInt
+
+// enterCleanup waits for the devices to undo what a disconnected client
+// left behind, and returns a function giving them back. The releases are
+// never refused for a full queue, and wait even once ctx is cancelled, so
+// that nothing stays held.
+func (q *cmdQueue) enterCleanup(ctx context.Context) func() {
+	t, _ := q.acquireAt(context.WithoutCancel(ctx), cleanupPriority, q.place())
+	return t.release
+}
+
+// turn is a command's hold on the devices. A paused job gives its turn
+// back, and waits in the queue again once resumed, in its original place.
+type turn struct {
+	q        *cmdQueue
+	priority int
+	seq      uint64  // Place in arrival order, 0 until first taken
+	t        *ticket // Nil while given back
+}
+
+// turnKey is the context key of the turn a command holds.
+type turnKey struct{}
+
+// priorityKey is the context key of the priority of a command.
+type priorityKey struct{}
+
+// withPriority returns ctx carrying the priority of the command. Stream
+// sessions queue their chunks with the priority of their stream_open.
+func withPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// enter waits for the devices with the priority in ctx. It returns the
+// context the command must run with, and a function giving the devices
+// back.
+func (q *cmdQueue) enter(ctx context.Context) (context.Context, func(), error) {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+
+	tn := &turn{q: q, priority: priority}
+	if err := tn.take(ctx); err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, turnKey{}, tn), tn.giveBack, nil
+}
+
+// turnFromCtx returns the turn the command holds, if any.
+func turnFromCtx(ctx context.Context) *turn {
+	tn, _ := ctx.Value(turnKey{}).(*turn)
+	return tn
+}
+
+// take waits for the devices. The job of the command, if any, is reported
+// as queued meanwhile. Taking the turn again keeps the command's place, and
+// is never refused for a full queue.
+func (tn *turn) take(ctx context.Context) error {
+	if j := jobFromCtx(ctx); j != nil {
+		j.setQueued(true)
+		defer j.setQueued(false)
+	}
+
+	t, err := tn.q.acquireAt(ctx, tn.priority, tn.seq)
+	if err != nil {
+		return err
+	}
+	tn.t, tn.seq = t, t.seq
+	return nil
+}
+
+// giveBack hands the devices to the next command.
+func (tn *turn) giveBack() {
+	if tn.t != nil {
+		tn.t.release()
+		tn.t = nil
+	}
+}
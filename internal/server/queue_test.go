@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitQueued waits until n commands wait in the queue.
+func waitQueued(t *testing.T, q *cmdQueue, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		waiting := q.waiting.Len()
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued commands", n)
+}
+
+func TestQueueOrder(t *testing.T) {
+	q := &cmdQueue{}
+	ctx := context.Background()
+
+	// Commands queue up behind the one holding the devices
+	holder, err := q.acquire(ctx, 0)
+	assert.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	for i, cmd := range []struct {
+		name     string
+		priority int
+	}{
+		{"first", 0},
+		{"second", 0},
+		{"urgent", 10},
+		{"third", 0},
+		{"important", 5},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := q.acquire(ctx, cmd.priority)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			order = append(order, cmd.name)
+			mu.Unlock()
+			tk.release()
+		}()
+		waitQueued(t, q, i+1)
+	}
+
+	holder.release()
+	wg.Wait()
+
+	assert.Equal(t, []string{"urgent", "important", "first", "second", "third"}, order)
+}
+
+func TestQueueFull(t *testing.T) {
+	q := &cmdQueue{max: 1}
+	ctx := context.Background()
+
+	holder, err := q.acquire(ctx, 0)
+	assert.NoError(t, err)
+
+	got := make(chan error)
+	go func() {
+		tk, err := q.acquire(ctx, 0)
+		if err == nil {
+			tk.release()
+		}
+		got <- err
+	}()
+	waitQueued(t, q, 1)
+
+	_, err = q.acquire(ctx, 0)
+	assert.ErrorIs(t, err, errQueueFull)
+
+	holder.release()
+	assert.NoError(t, <-got)
+}
+
+func TestQueueLeave(t *testing.T) {
+	q := &cmdQueue{}
+
+	holder, err := q.acquire(context.Background(), 0)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	got := make(chan error)
+	go func() {
+		_, err := q.acquire(ctx, 0)
+		got <- err
+	}()
+	waitQueued(t, q, 1)
+
+	cancel(errJobCancelled)
+	assert.ErrorIs(t, <-got, errJobCancelled)
+	waitQueued(t, q, 0)
+
+	// The devices go to the next command once released
+	holder.release()
+	tk, err := q.acquire(context.Background(), 0)
+	if assert.NoError(t, err) {
+		tk.release()
+	}
+}
+
+func TestQueuedJob(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+
+	holder, err := server.queue.acquire(context.Background(), 0)
+	assert.NoError(t, err)
+	defer holder.release()
+
+	done := make(chan error)
+	go func() {
+		payload, _ := json.Marshal(protocol.TypePayload{Text: "ab"})
+		done <- server.handleCommand(context.Background(), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Type, Payload: payload})
+	}()
+	waitQueued(t, &server.queue, 1)
+
+	info := waitJob(t, server)
+	assert.Equal(t, protocol.JobState_Queued, info.State)
+
+	// Queued jobs can be cancelled before typing anything
+	cancelJob(t, server, protocol.CancelPayload{Job: info.ID})
+	assert.ErrorIs(t, <-done, errJobCancelled)
+	assert.Empty(t, *tapped)
+	waitQueued(t, &server.queue, 0)
+}
+
+func TestPausedJobGivesBackDevices(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	cc := &clientConn{}
+
+	payload, _ := json.Marshal(protocol.StreamOpenPayload{CharDelay: 1})
+	assert.NoError(t, server.handleStreamOpen(context.Background(), cc, payload))
+	id := waitJob(t, server).ID
+	jobCommand(t, server.handlePause, id)
+	cc.stream.enqueue("ab")
+	time.Sleep(20 * time.Millisecond) // Let the session take its turn and wait
+
+	// Another client's key runs while the session is paused mid-chunk
+	payload, _ = json.Marshal(protocol.KeyPayload{Keycode: uinput.KeyEnter})
+	assert.NoError(t, server.handleCommand(context.Background(), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Key, Payload: payload}))
+
+	jobCommand(t, server.handleResume, id)
+	assert.NoError(t, server.handleStreamClose(context.Background(), cc))
+	assert.Equal(t, []uint16{uinput.KeyEnter, uinput.KeyA, uinput.KeyB}, *tapped)
+}
+
+func TestResumeInFullQueue(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	server.queue.max = 1
+
+	done := make(chan error)
+	go func() {
+		payload, _ := json.Marshal(protocol.StreamPayload{Text: "abc", CharDelay: 10, DelayMs: -1})
+		done <- server.handleCommand(context.Background(), &clientConn{}, &protocol.Command{Type: protocol.CommandType_Stream, Payload: payload})
+	}()
+	id := waitJob(t, server).ID
+	jobCommand(t, server.handlePause, id)
+
+	// Another command takes the devices while the job is paused, and a
+	// later one fills the queue
+	holder, err := server.queue.acquire(context.Background(), 0)
+	assert.NoError(t, err)
+
+	typedBefore := make(chan int)
+	go func() {
+		tk, err := server.queue.acquire(context.Background(), 0)
+		if assert.NoError(t, err) {
+			typedBefore <- len(*tapped)
+			tk.release()
+		}
+	}()
+	waitQueued(t, &server.queue, 1)
+
+	// The resumed job waits again in its original place, ahead of the
+	// later command, and is not refused for the full queue
+	jobCommand(t, server.handleResume, id)
+	waitQueued(t, &server.queue, 2)
+	holder.release()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, 3, <-typedBefore)
+	assert.Equal(t, []uint16{uinput.KeyA, uinput.KeyB, uinput.KeyC}, *tapped)
+}
+
+func TestQueueFullHandOff(t *testing.T) {
+	// With max 1, a command is only rejected if another one already waits
+	// behind the holder, never while the devices are handed over
+	q := &cmdQueue{max: 1}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				tk, err := q.acquire(ctx, 0)
+				if !assert.NoError(t, err) {
+					return
+				}
+				tk.release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	assert.False(t, q.busy)
+	assert.Zero(t, q.waiting.Len())
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/policy"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/ratelimit"
+)
+
+// Actions on commands over a rate limit.
+const (
+	rateLimitReject = "reject"
+	rateLimitDelay  = "delay"
+)
+
+// errRateLimited rejects commands over a rate limit.
+var errRateLimited = errors.New("rate limit exceeded")
+
+// rateLimiter enforces performance.rate_limit and the rates of policy
+// rules: every command and keystroke takes a token from the daemon-wide
+// buckets, from those of its client's UID and from those of its client's
+// policy rule. A command's keystrokes are all taken before it runs.
+type rateLimiter struct {
+	maxDelay time.Duration // 0 rejects over-limit commands at once
+
+	limited   bool // performance.rate_limit sets limits
+	perUID    config.RateLimits
+	uidLimits map[uint32]config.RateLimits // Overrides of perUID
+	global    *rateScope
+
+	mu    sync.Mutex
+	uids  map[uint32]*rateScope
+	rules map[*policy.Rule]*rateScope
+}
+
+// rateScope holds the buckets of the daemon, of a client UID or of a
+// policy rule, and counts what they let through and held back.
+type rateScope struct {
+	uid    *uint32 // Nil for the daemon-wide and rule scopes
+	rule   string  // Name of the policy rule, if a rule scope
+	limits config.RateLimits
+
+	commands   *ratelimit.Bucket
+	keystrokes *ratelimit.Bucket
+
+	passedCmds atomic.Uint64
+	passedKeys atomic.Uint64
+	delayed    atomic.Uint64
+	rejected   atomic.Uint64
+}
+
+// newRateLimiter returns the limiter of the configuration and of the
+// policy's rules, or nil if neither sets a limit. The policy may be nil.
+func newRateLimiter(cfg config.RateLimitConfig, pol *policy.Policy) (*rateLimiter, error) {
+	var maxDelay time.Duration
+	switch cfg.Action {
+	case "", rateLimitReject:
+	case rateLimitDelay:
+		maxDelay = time.Duration(cfg.MaxDelayMs) * time.Millisecond
+	default:
+		return nil, fmt.Errorf("unknown rate limit action: %s", cfg.Action)
+	}
+
+	limited := func(l config.RateLimits) bool {
+		return l.CommandsPerSecond > 0 || l.KeystrokesPerSecond > 0
+	}
+	enabled := limited(cfg.Global) || limited(cfg.PerUID)
+
+	uidLimits := make(map[uint32]config.RateLimits, len(cfg.UIDs))
+	for _, u := range cfg.UIDs {
+		uidLimits[u.UID] = u.RateLimits
+		enabled = enabled || limited(u.RateLimits)
+	}
+	if !enabled && (pol == nil || !pol.RateLimited()) {
+		return nil, nil
+	}
+
+	return &rateLimiter{
+		maxDelay:  maxDelay,
+		limited:   enabled,
+		perUID:    cfg.PerUID,
+		uidLimits: uidLimits,
+		global:    newRateScope(cfg.Global),
+		uids:      make(map[uint32]*rateScope),
+		rules:     make(map[*policy.Rule]*rateScope),
+	}, nil
+}
+
+func newRateScope(limits config.RateLimits) *rateScope {
+	return &rateScope{
+		limits:     limits,
+		commands:   ratelimit.New(limits.CommandsPerSecond, limits.CommandsBurst),
+		keystrokes: ratelimit.New(limits.KeystrokesPerSecond, limits.KeystrokesBurst),
+	}
+}
+
+// scopes returns the scopes a client's commands count against: its UID's
+// if it could be identified, its policy rule's if the rule limits the
+// rate, and the daemon's.
+func (rl *rateLimiter) scopes(cred *peer.Cred, rule *policy.Rule) []*rateScope {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var scopes []*rateScope
+	if rl.limited && cred != nil {
+		scope, ok := rl.uids[cred.UID]
+		if !ok {
+			limits, ok := rl.uidLimits[cred.UID]
+			if !ok {
+				limits = rl.perUID
+			}
+			scope = newRateScope(limits)
+			scope.uid = &cred.UID
+			rl.uids[cred.UID] = scope
+		}
+		scopes = append(scopes, scope)
+	}
+	if rule != nil && rule.MaxCommandsPerSecond() > 0 {
+		scope, ok := rl.rules[rule]
+		if !ok {
+			scope = newRateScope(config.RateLimits{CommandsPerSecond: rule.MaxCommandsPerSecond()})
+			scope.rule = rule.Name
+			rl.rules[rule] = scope
+		}
+		scopes = append(scopes, scope)
+	}
+	if rl.limited {
+		scopes = append(scopes, rl.global)
+	}
+	return scopes
+}
+
+// limitCommand takes a command token and the command's keystroke tokens
+// for the client before the command waits for the devices, so that a
+// throttled client never holds them. Every limit is checked before any is
+// charged: a command over one limit costs nothing. With the delay action
+// the command waits for its tokens, up to max_delay_ms. Everything is
+// allowed without rate limits.
+func (rl *rateLimiter) limitCommand(ctx context.Context, cred *peer.Cred, rule *policy.Rule, keystrokes int) error {
+	if rl == nil {
+		return nil
+	}
+
+	var takes []rateTake
+	for _, scope := range rl.scopes(cred, rule) {
+		takes = append(takes, rateTake{scope: scope, bucket: scope.commands, n: 1})
+		if keystrokes > 0 {
+			takes = append(takes, rateTake{scope: scope, bucket: scope.keystrokes, n: keystrokes, keystrokes: true})
+		}
+	}
+
+	for _, t := range takes {
+		if t.bucket.Delay(float64(t.n)) > rl.maxDelay {
+			return t.reject(ctx)
+		}
+	}
+
+	// Charge every limit, giving the tokens back if another client took
+	// them since the check
+	var wait time.Duration
+	for i, t := range takes {
+		w, ok := t.bucket.Reserve(float64(t.n), rl.maxDelay)
+		if !ok {
+			for _, taken := range takes[:i] {
+				taken.bucket.Refund(float64(taken.n))
+			}
+			return t.reject(ctx)
+		}
+		if w > 0 {
+			t.scope.delayed.Add(1)
+		}
+		wait = max(wait, w)
+	}
+
+	for _, t := range takes {
+		if t.keystrokes {
+			t.scope.passedKeys.Add(uint64(t.n))
+		} else {
+			t.scope.passedCmds.Add(1)
+		}
+	}
+	return sleep(ctx, wait)
+}
+
+// rateTake is the tokens a command takes from a bucket of a scope.
+type rateTake struct {
+	scope      *rateScope
+	bucket     *ratelimit.Bucket
+	n          int
+	keystrokes bool
+}
+
+// reject counts and reports a command over the take's limit.
+func (t rateTake) reject(ctx context.Context) error {
+	rate, unit := t.scope.limits.CommandsPerSecond, "commands"
+	if t.keystrokes {
+		rate, unit = t.scope.limits.KeystrokesPerSecond, "keystrokes"
+	}
+
+	t.scope.rejected.Add(1)
+	logger.LogFromCtx(ctx).Debug("rate limit exceeded", "scope", t.scope.name(), unit+"_per_second", rate)
+
+	// More than the bucket ever holds: waiting would not help
+	if burst := t.bucket.Burst(); float64(t.n) > burst {
+		return fmt.Errorf("%w: %d %s over the burst of %g (%s)", errRateLimited, t.n, unit, burst, t.scope.name())
+	}
+	return fmt.Errorf("%w: %g %s per second (%s)", errRateLimited, rate, unit, t.scope.name())
+}
+
+// keystrokes returns the keystrokes a command counts for: one per
+// character of typed text, and one per key.
+func keystrokes(cmd *protocol.Command) int {
+	var text string
+	switch cmd.Type {
+	case protocol.CommandType_Type:
+		var p protocol.TypePayload
+		json.Unmarshal(cmd.Payload, &p)
+		text = p.Text
+	case protocol.CommandType_Stream:
+		var p protocol.StreamPayload
+		json.Unmarshal(cmd.Payload, &p)
+		text = p.Text
+	case protocol.CommandType_StreamChunk:
+		var p protocol.StreamChunkPayload
+		json.Unmarshal(cmd.Payload, &p)
+		text = p.Text
+	case protocol.CommandType_Key:
+		return 1
+	case protocol.CommandType_KeyDown:
+		keycodes, _ := parseKeyHold(cmd.Payload)
+		return len(keycodes)
+	}
+	return utf8.RuneCountInString(text)
+}
+
+// name identifies the scope in errors and logs.
+func (scope *rateScope) name() string {
+	switch {
+	case scope.rule != "":
+		return "rule " + scope.rule
+	case scope.uid == nil:
+		return "global"
+	}
+	return "uid " + strconv.FormatUint(uint64(*scope.uid), 10)
+}
+
+// status reports the limits and counters of every scope: the daemon's
+// first if performance.rate_limit sets limits, then each policy rule and
+// each client UID seen.
+func (rl *rateLimiter) status() []protocol.RateLimitStatus {
+	if rl == nil {
+		return nil
+	}
+
+	rl.mu.Lock()
+	rules := make([]*rateScope, 0, len(rl.rules))
+	for _, scope := range rl.rules {
+		rules = append(rules, scope)
+	}
+	uids := make([]*rateScope, 0, len(rl.uids))
+	for _, scope := range rl.uids {
+		uids = append(uids, scope)
+	}
+	rl.mu.Unlock()
+
+	slices.SortFunc(rules, func(a, b *rateScope) int { return cmp.Compare(a.rule, b.rule) })
+	slices.SortFunc(uids, func(a, b *rateScope) int { return cmp.Compare(*a.uid, *b.uid) })
+
+	var scopes []*rateScope
+	if rl.limited {
+		scopes = append(scopes, rl.global)
+	}
+	scopes = append(append(scopes, rules...), uids...)
+
+	status := make([]protocol.RateLimitStatus, len(scopes))
+	for i, scope := range scopes {
+		status[i] = protocol.RateLimitStatus{
+			UID:                 scope.uid,
+			Rule:                scope.rule,
+			CommandsPerSecond:   scope.limits.CommandsPerSecond,
+			KeystrokesPerSecond: scope.limits.KeystrokesPerSecond,
+			Commands:            scope.passedCmds.Load(),
+			Keystrokes:          scope.passedKeys.Load(),
+			Delayed:             scope.delayed.Load(),
+			Rejected:            scope.rejected.Load(),
+		}
+	}
+	return status
+}
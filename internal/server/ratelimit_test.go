@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/policy"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	rl, err := newRateLimiter(config.RateLimitConfig{Action: rateLimitReject}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rl, "no limiter without limits")
+
+	rl, err = newRateLimiter(config.RateLimitConfig{
+		UIDs: []config.UIDRateLimits{{UID: 1000, RateLimits: config.RateLimits{CommandsPerSecond: 1}}},
+	}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, rl)
+
+	p, err := policy.Parse("test.yaml", []byte(testPolicy))
+	assert.NoError(t, err)
+	rl, err = newRateLimiter(config.RateLimitConfig{}, p)
+	assert.NoError(t, err)
+	assert.NotNil(t, rl, "limiter of the policy's rule rates")
+
+	_, err = newRateLimiter(config.RateLimitConfig{Action: "drop", Global: config.RateLimits{CommandsPerSecond: 1}}, nil)
+	assert.ErrorContains(t, err, "unknown rate limit action")
+}
+
+func TestRateLimitCommands(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.limiter, _ = newRateLimiter(config.RateLimitConfig{
+		Action: rateLimitReject,
+		Global: config.RateLimits{CommandsPerSecond: 4},
+		PerUID: config.RateLimits{CommandsPerSecond: 2},
+		UIDs:   []config.UIDRateLimits{{UID: 0, RateLimits: config.RateLimits{CommandsPerSecond: 100}}},
+	}, nil)
+
+	ping := &protocol.Command{Type: protocol.CommandType_Ping}
+	alice := &clientConn{peer: &peer.Cred{UID: 1000}}
+	bob := &clientConn{peer: &peer.Cred{UID: 1001}}
+	root := &clientConn{peer: &peer.Cred{UID: 0}}
+
+	// Each UID gets its own burst, within the global one
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, server.handleCommand(context.Background(), alice, ping))
+	}
+	assert.ErrorIs(t, server.handleCommand(context.Background(), alice, ping), errRateLimited)
+	assert.NoError(t, server.handleCommand(context.Background(), bob, ping))
+	assert.NoError(t, server.handleCommand(context.Background(), root, ping))
+
+	err := server.handleCommand(context.Background(), root, ping)
+	assert.ErrorIs(t, err, errRateLimited)
+	assert.ErrorContains(t, err, "4 commands per second (global)")
+
+	uid := func(u uint32) *uint32 { return &u }
+	assert.Equal(t, []protocol.RateLimitStatus{
+		{CommandsPerSecond: 4, Commands: 4, Rejected: 1},
+		// The command rejected by the global limit cost root nothing
+		{UID: uid(0), CommandsPerSecond: 100, Commands: 1},
+		{UID: uid(1000), CommandsPerSecond: 2, Commands: 2, Rejected: 1},
+		{UID: uid(1001), CommandsPerSecond: 2, Commands: 1},
+	}, server.limiter.status())
+}
+
+func TestRateLimitKeystrokes(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		burst   float64
+		err     string
+		typed   int
+		delayed uint64
+	}{
+		// Rejected before typing anything, rather than cut off midway
+		{"reject", rateLimitReject, 0, "8 keystrokes over the burst of 5 (global)", 0, 0},
+		{"reject within burst", rateLimitReject, 10, "", 8, 0},
+		{"delay", rateLimitDelay, 0, "", 8, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := uinputMocks.NewMockDeviceInterface(t)
+			tapped, _ := recordKeys(device)
+
+			server := newTestServer(device, layouts.NewRegistry())
+			server.limiter, _ = newRateLimiter(config.RateLimitConfig{
+				Action:     tt.action,
+				MaxDelayMs: 1000,
+				PerUID:     config.RateLimits{KeystrokesPerSecond: 50},
+				Global:     config.RateLimits{KeystrokesPerSecond: 5, KeystrokesBurst: tt.burst},
+			}, nil)
+
+			payload, _ := json.Marshal(protocol.TypePayload{Text: "abcdefgh", Layout: "us"})
+			start := time.Now()
+			err := server.handleCommand(context.Background(), &clientConn{peer: &peer.Cred{UID: 1000}}, &protocol.Command{Type: protocol.CommandType_Type, Payload: payload})
+
+			switch {
+			case tt.err != "":
+				assert.ErrorIs(t, err, errRateLimited)
+				assert.ErrorContains(t, err, tt.err)
+			case tt.delayed > 0:
+				assert.NoError(t, err)
+				// The 3 keystrokes over the burst are waited for at 5/s
+				assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+			default:
+				assert.NoError(t, err)
+			}
+			assert.Len(t, *tapped, tt.typed)
+
+			status := server.limiter.status()
+			assert.Equal(t, uint64(tt.typed), status[0].Keystrokes)
+			assert.Equal(t, tt.delayed, status[0].Delayed)
+			// The UID limit is only charged once every limit allows the text
+			assert.Equal(t, uint64(tt.typed), status[1].Keystrokes)
+		})
+	}
+}
+
+func TestRateLimitDelayOutsideQueue(t *testing.T) {
+	device := uinputMocks.NewMockDeviceInterface(t)
+	tapped, _ := recordKeys(device)
+
+	server := newTestServer(device, layouts.NewRegistry())
+	server.limiter, _ = newRateLimiter(config.RateLimitConfig{
+		Action:     rateLimitDelay,
+		MaxDelayMs: 1000,
+		PerUID:     config.RateLimits{KeystrokesPerSecond: 5},
+	}, nil)
+
+	// Alice waits 600ms for her keystrokes without holding the devices
+	payload, _ := json.Marshal(protocol.TypePayload{Text: "abcdefgh", Layout: "us"})
+	done := make(chan error)
+	go func() {
+		done <- server.handleCommand(context.Background(), &clientConn{peer: &peer.Cred{UID: 1000}}, &protocol.Command{Type: protocol.CommandType_Type, Payload: payload})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	key, _ := json.Marshal(protocol.KeyPayload{Keycode: uinput.KeyEnter})
+	assert.NoError(t, server.handleCommand(context.Background(), &clientConn{peer: &peer.Cred{UID: 1001}}, &protocol.Command{Type: protocol.CommandType_Key, Payload: key}))
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint16(uinput.KeyEnter), (*tapped)[0])
+	assert.Len(t, *tapped, 9)
+}
+
+func TestKeystrokes(t *testing.T) {
+	cmd := func(cmdType protocol.CommandType, payload string) *protocol.Command {
+		return &protocol.Command{Type: cmdType, Payload: json.RawMessage(payload)}
+	}
+
+	assert.Equal(t, 5, keystrokes(cmd(protocol.CommandType_Type, `{"text":"héllo"}`)))
+	assert.Equal(t, 3, keystrokes(cmd(protocol.CommandType_Stream, `{"text":"a b"}`)))
+	assert.Equal(t, 2, keystrokes(cmd(protocol.CommandType_StreamChunk, `{"text":"ab"}`)))
+	assert.Equal(t, 1, keystrokes(cmd(protocol.CommandType_Key, `{"chord":"ctrl+c"}`)))
+	assert.Equal(t, 2, keystrokes(cmd(protocol.CommandType_KeyDown, `{"chord":"shift+a"}`)))
+	assert.Equal(t, 0, keystrokes(cmd(protocol.CommandType_Ping, `{}`)))
+}
+
+func TestRateLimitDelayTooLong(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.limiter, _ = newRateLimiter(config.RateLimitConfig{
+		Action:     rateLimitDelay,
+		MaxDelayMs: 100,
+		Global:     config.RateLimits{CommandsPerSecond: 1},
+	}, nil)
+
+	ping := &protocol.Command{Type: protocol.CommandType_Ping}
+	assert.NoError(t, server.handleCommand(context.Background(), &clientConn{}, ping))
+	assert.ErrorIs(t, server.handleCommand(context.Background(), &clientConn{}, ping), errRateLimited)
+}
@@ -3,16 +3,24 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/user"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/bnema/uinputd-go/internal/activation"
+	"github.com/bnema/uinputd-go/internal/audit"
 	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/detect"
 	"github.com/bnema/uinputd-go/internal/layouts"
 	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/policy"
 	"github.com/bnema/uinputd-go/internal/protocol"
 	"github.com/bnema/uinputd-go/internal/uinput"
 	"golang.org/x/sync/errgroup"
@@ -22,55 +30,212 @@ import (
 type Server struct {
 	cfg      *config.Config
 	device   uinput.DeviceInterface
+	pointer  uinput.DeviceInterface  // Optional virtual mouse
+	absolute uinput.DeviceInterface  // Optional tablet/touchscreen
+	gamepad  gamepadState            // Optional gamepad, created on demand
+	named    map[string]*NamedDevice // Devices targeted by name
 	registry layouts.RegistryInterface
+	detector layoutDetector // Optional, detects the session's layout
 	listener net.Listener
+	held     heldKeys
+	jobs     jobList
+	queue    cmdQueue       // Serializes commands driving devices
+	clients  clientList     // Open connections
+	policy   *policy.Policy // Optional, restricts what each client may do
+	limiter  *rateLimiter   // Optional, limits commands and keystrokes per second
+	audit    *audit.Log     // Optional, records every command
+
+	// Set if systemd passed the listener, which allows exiting when idle
+	activated bool
+	// Slots of open connections, nil without socket.max_connections
+	connSlots chan struct{}
+
+	// Reported by status and capabilities commands
+	version string
+	started time.Time
+	served  atomic.Uint64 // Commands handled
 }
 
 // New creates a new server instance.
 func New(ctx context.Context, cfg *config.Config, device uinput.DeviceInterface) (*Server, error) {
 	log := logger.LogFromCtx(ctx)
 
+	if err := checkFallback(cfg.Fallback); err != nil {
+		return nil, fmt.Errorf("invalid fallback config: %w", err)
+	}
+
+	var pol *policy.Policy
+	if cfg.PolicyFile != "" {
+		p, err := policy.Load(cfg.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: %w", err)
+		}
+		pol = p
+		log.Info("policy loaded", "file", cfg.PolicyFile, "rules", p.Rules())
+	}
+
+	limiter, err := newRateLimiter(cfg.Performance.RateLimit, pol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	// Use the socket systemd passed, if socket activated
+	listener, err := activation.Listener()
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket activation: %w", err)
+	}
+	activated := listener != nil
+	if activated {
+		path := listener.Addr().String()
+		log.Info("using socket from systemd", "path", path)
+		if path != cfg.Socket.Path {
+			log.Warn("socket path differs from config", "config", cfg.Socket.Path, "hint", "set ListenStream= in uinputd.socket to socket.path")
+		}
+	} else {
+		if cfg.Socket.IdleExitMs > 0 {
+			log.Warn("socket.idle_exit_ms ignored without socket activation")
+		}
+		if listener, err = createSocket(ctx, cfg.Socket); err != nil {
+			return nil, err
+		}
+	}
+
+	srv := &Server{
+		cfg:       cfg,
+		device:    device,
+		registry:  newRegistry(ctx, cfg),
+		listener:  listener,
+		activated: activated,
+		queue:     cmdQueue{max: cfg.Performance.MaxConcurrentCmds},
+		policy:    pol,
+		limiter:   limiter,
+		version:   "dev",
+		started:   time.Now(),
+	}
+
+	if cfg.Socket.MaxConnections > 0 {
+		srv.connSlots = make(chan struct{}, cfg.Socket.MaxConnections)
+	}
+
+	if cfg.Audit.Enabled {
+		auditLog, err := openAudit(cfg.Audit)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		srv.audit = auditLog
+		log.Info("audit log enabled", "path", cfg.Audit.Path, "text", cfg.Audit.Text)
+	}
+
+	if cfg.LayoutDetect.Enabled {
+		detector, err := detect.New(cfg.LayoutDetect.Sources, time.Duration(cfg.LayoutDetect.CacheMs)*time.Millisecond)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("invalid layout detection config: %w", err)
+		}
+		srv.detector = detector
+		log.Info("layout detection enabled", "sources", cfg.LayoutDetect.Sources)
+	}
+
+	return srv, nil
+}
+
+// createSocket creates the Unix socket the daemon listens on. The socket
+// file of a previous run is replaced, unless another daemon still listens
+// on it.
+func createSocket(ctx context.Context, cfg config.SocketConfig) (net.Listener, error) {
+	log := logger.LogFromCtx(ctx)
+
+	if conn, err := net.Dial("unix", cfg.Path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("socket %s is in use by another instance", cfg.Path)
+	}
+
 	// Remove existing socket if it exists
-	if err := os.RemoveAll(cfg.Socket.Path); err != nil {
+	if err := os.RemoveAll(cfg.Path); err != nil {
 		return nil, fmt.Errorf("failed to remove existing socket: %w", err)
 	}
 
 	// Create Unix socket listener
-	listener, err := net.Listen("unix", cfg.Socket.Path)
+	listener, err := net.Listen("unix", cfg.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create socket: %w", err)
 	}
 
 	// Set socket permissions (group-based: root:input 0660)
-	if err := os.Chmod(cfg.Socket.Path, os.FileMode(cfg.Socket.Permissions)); err != nil {
+	if err := os.Chmod(cfg.Path, os.FileMode(cfg.Permissions)); err != nil {
 		listener.Close()
 		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
 	}
 
 	// Try to set group ownership to 'input' (GID typically 104 or similar)
 	// This allows users in the input group to connect
-	if err := setSocketGroup(cfg.Socket.Path); err != nil {
-		log.Warn("failed to set socket group ownership", "error", err, "hint", "run 'chgrp input "+cfg.Socket.Path+"' manually if needed")
+	if err := setSocketGroup(cfg.Path); err != nil {
+		log.Warn("failed to set socket group ownership", "error", err, "hint", "run 'chgrp input "+cfg.Path+"' manually if needed")
 	}
 
-	log.Info("unix socket created", "path", cfg.Socket.Path, "permissions", fmt.Sprintf("%o", cfg.Socket.Permissions))
+	log.Info("unix socket created", "path", cfg.Path, "permissions", fmt.Sprintf("%o", cfg.Permissions))
+	return listener, nil
+}
+
+// newRegistry creates the layout registry with the user-defined layouts and
+// the preloaded XKB layouts. Layouts that fail to parse are logged and
+// skipped.
+func newRegistry(ctx context.Context, cfg *config.Config) *layouts.Registry {
+	log := logger.LogFromCtx(ctx)
+
+	registry := layouts.NewRegistry()
+	if cfg.XKB.SymbolsDir != "" {
+		registry.SetXKBDir(cfg.XKB.SymbolsDir)
+	}
 
-	return &Server{
-		cfg:      cfg,
-		device:   device,
-		registry: layouts.NewRegistry(),
-		listener: listener,
-	}, nil
+	if cfg.LayoutsDir != "" {
+		names, err := registry.LoadDir(cfg.LayoutsDir)
+		if err != nil {
+			log.Warn("failed to load layout files", "dir", cfg.LayoutsDir, "error", err)
+		}
+		if len(names) > 0 {
+			log.Info("layout files loaded", "dir", cfg.LayoutsDir, "layouts", names)
+		}
+	}
+
+	for _, spec := range cfg.XKB.Preload {
+		if _, err := registry.LoadXKB(spec); err != nil {
+			log.Warn("failed to load xkb layout", "layout", spec, "error", err)
+			continue
+		}
+		log.Info("xkb layout loaded", "layout", layouts.XKBPrefix+spec)
+	}
+
+	return registry
 }
 
 // Start begins accepting client connections.
-// This blocks until ctx is cancelled or an error occurs.
+// This blocks until ctx is cancelled, an error occurs, or a socket activated
+// server has been idle for socket.idle_exit_ms.
 func (s *Server) Start(ctx context.Context) error {
 	log := logger.LogFromCtx(ctx)
-	log.Info("server starting", "socket", s.cfg.Socket.Path)
+	log.Info("server starting", "socket", s.listener.Addr())
+
+	// Connections wait in the listen backlog until the devices created at
+	// startup are opened, so the first command of the client that had
+	// systemd start the daemon is not lost
+	if err := s.settle(ctx); err != nil {
+		return nil
+	}
+	s.clients.touch()
 
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 	g, ctx := errgroup.WithContext(ctx)
 
+	if idle := time.Duration(s.cfg.Socket.IdleExitMs) * time.Millisecond; s.activated && idle > 0 {
+		g.Go(func() error {
+			s.exitWhenIdle(ctx, stop, idle)
+			return nil
+		})
+	}
+
 	// Goroutine to accept connections
 	g.Go(func() error {
 		for {
@@ -84,8 +249,14 @@ func (s *Server) Start(ctx context.Context) error {
 				}
 			}
 
+			if !s.admit(ctx, conn) {
+				continue
+			}
+
 			// Handle connection in separate goroutine
+			s.clients.touch()
 			g.Go(func() error {
+				defer s.leave()
 				return s.handleConnection(ctx, conn)
 			})
 		}
@@ -101,46 +272,133 @@ func (s *Server) Start(ctx context.Context) error {
 	return g.Wait()
 }
 
-// handleConnection processes a single client connection.
+// handleConnection processes a client connection.
+// The connection stays open and serves newline-delimited commands until the
+// client disconnects, a command cannot be decoded, or ctx is cancelled.
 func (s *Server) handleConnection(ctx context.Context, conn net.Conn) error {
 	defer conn.Close()
 
+	// Unblock the decoder on shutdown
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Every log line of the connection identifies the client
+	cc := &clientConn{connectedAt: time.Now()}
+	ctx = s.identify(ctx, conn, cc)
+	defer s.closeConn(ctx, cc)
+
+	s.clients.add(cc)
+	defer s.clients.remove(cc)
+
 	log := logger.LogFromCtx(ctx)
-	log.Debug("client connected", "remote", conn.RemoteAddr())
 
-	// Read command from client
-	decoder := json.NewDecoder(conn)
-	var cmd protocol.Command
-	if err := decoder.Decode(&cmd); err != nil {
-		if err == io.EOF {
-			return nil // Client disconnected
+	perf := s.cfg.Performance
+	readTimeout := time.Duration(s.cfg.Socket.ReadTimeoutMs) * time.Millisecond
+	reader := newMessageReader(conn, perf.BufferSize, perf.MaxMessageSize)
+	writer := newResponseWriter(conn, perf.BufferSize, time.Duration(s.cfg.Socket.WriteTimeoutMs)*time.Millisecond)
+	decoder := json.NewDecoder(reader)
+
+	for {
+		if readTimeout > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+				log.Debug("failed to set read deadline", "error", err)
+				return nil
+			}
+		}
+		reader.next()
+
+		var cmd protocol.Command
+		if err := decoder.Decode(&cmd); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				log.Debug("client disconnected")
+				return nil
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				log.Debug("client idle, closing connection", "timeout", readTimeout)
+				return nil
+			}
+			// The stream cannot be resynchronised after a decode error
+			if werr := s.sendError(writer, "", fmt.Errorf("failed to decode command: %w", err)); werr != nil {
+				log.Debug("failed to write response", "error", werr)
+			}
+			return nil
 		}
-		return s.sendError(conn, fmt.Errorf("failed to decode command: %w", err))
-	}
 
-	// Enrich context with command info
-	cmdLogger := log.With("cmd_type", cmd.Type)
-	ctx = logger.WithLogger(ctx, cmdLogger)
+		// Enrich context with command info
+		cmdLogger := log.With("cmd_type", cmd.Type)
+		if cmd.ID != "" {
+			cmdLogger = cmdLogger.With("cmd_id", cmd.ID)
+		}
+		if cmd.Device != "" {
+			cmdLogger = cmdLogger.With("device", cmd.Device)
+		}
+		cmdCtx := logger.WithLogger(ctx, cmdLogger)
+		s.served.Add(1)
+		cc.commands.Add(1)
+
+		// Handle command; handlers may add details to the response
+		resp := &protocol.Response{ID: cmd.ID}
+		if herr := s.handleCommand(withResponse(cmdCtx, resp), cc, &cmd); herr != nil {
+			resp.Error = herr.Error()
+			switch {
+			case errors.Is(herr, errJobCancelled):
+				resp.Code = protocol.ErrorCode_Cancelled
+			case errors.Is(herr, errQueueFull):
+				resp.Code = protocol.ErrorCode_Busy
+			case errors.Is(herr, errPermissionDenied):
+				resp.Code = protocol.ErrorCode_PermissionDenied
+			case errors.Is(herr, errRateLimited):
+				resp.Code = protocol.ErrorCode_RateLimited
+			}
+		} else {
+			resp.Success = true
+			resp.Message = "command executed successfully"
+		}
+		s.recordAudit(cmdCtx, cc, &cmd, resp)
+		if err := writer.send(resp); err != nil {
+			log.Debug("failed to write response", "error", err)
+			return nil
+		}
+	}
+}
 
-	// Handle command
-	if err := s.handleCommand(ctx, &cmd); err != nil {
-		return s.sendError(conn, err)
+// admit takes a connection slot for conn. Without a free slot, conn is
+// sent a "busy" error and closed.
+func (s *Server) admit(ctx context.Context, conn net.Conn) bool {
+	if s.connSlots == nil {
+		return true
+	}
+	select {
+	case s.connSlots <- struct{}{}:
+		return true
+	default:
 	}
 
-	// Send success response
-	return s.sendSuccess(conn, "command executed successfully")
+	logger.LogFromCtx(ctx).Warn("too many connections, refusing client", "max_connections", cap(s.connSlots))
+	w := newResponseWriter(conn, 0, 100*time.Millisecond)
+	w.send(&protocol.Response{
+		Error: fmt.Sprintf("too many connections (max %d)", cap(s.connSlots)),
+		Code:  protocol.ErrorCode_Busy,
+	})
+	conn.Close()
+	return false
 }
 
-// sendSuccess sends a success response to the client.
-func (s *Server) sendSuccess(conn net.Conn, message string) error {
-	resp := protocol.NewSuccessResponse(message)
-	return json.NewEncoder(conn).Encode(resp)
+// leave frees the connection slot taken by admit.
+func (s *Server) leave() {
+	if s.connSlots != nil {
+		<-s.connSlots
+	}
 }
 
 // sendError sends an error response to the client.
-func (s *Server) sendError(conn net.Conn, err error) error {
+func (s *Server) sendError(w *responseWriter, id string, err error) error {
 	resp := protocol.NewErrorResponse(err)
-	return json.NewEncoder(conn).Encode(resp)
+	resp.ID = id
+	if errors.Is(err, errMessageTooLarge) {
+		resp.Code = protocol.ErrorCode_MessageTooLarge
+	}
+	return w.send(resp)
 }
 
 // setSocketGroup attempts to set the socket's group to 'input'.
@@ -165,10 +423,27 @@ func setSocketGroup(path string) error {
 	return nil
 }
 
-// Close cleanly shuts down the server.
+// gamepadState holds the on-demand gamepad device.
+type gamepadState struct {
+	mu      sync.Mutex
+	factory GamepadFactory
+	device  uinput.DeviceInterface
+}
+
+// Close cleanly shuts down the server, destroys the gamepad if one was
+// created and closes the audit log.
 func (s *Server) Close() error {
+	var err error
 	if s.listener != nil {
-		return s.listener.Close()
+		err = s.listener.Close()
 	}
-	return nil
+	if gerr := s.closeGamepad(); gerr != nil && err == nil {
+		err = gerr
+	}
+	if s.audit != nil {
+		if aerr := s.audit.Close(); aerr != nil && err == nil {
+			err = aerr
+		}
+	}
+	return err
 }
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/policy"
+)
+
+// streamQueueSize is the number of chunks a stream session buffers before
+// stream_chunk blocks the connection (backpressure on fast producers).
+const streamQueueSize = 256
+
+// clientConn holds per-connection state shared by the commands of one client.
+type clientConn struct {
+	peer        *peer.Cred   // Nil if the client could not be identified
+	rule        *policy.Rule // Policy rule matching the client
+	connectedAt time.Time
+	commands    atomic.Uint64 // Commands sent on the connection
+
+	stream *streamSession
+	axes   []movedAxis // Gamepad axes moved off rest
+
+	// Keys and buttons held down, in press order. Guarded by mu since
+	// cancel commands release them from other connections.
+	mu   sync.Mutex
+	held []heldKey
+}
+
+// closeConn releases everything the connection still owns: any stream
+// session is aborted, held keys and buttons are released and gamepad axes
+// return to rest. A paused stream session is kept until it is resumed and
+// has typed its queued chunks, or until it is cancelled or times out.
+// Releases and resets take a turn in the command queue, so that they never
+// land in the middle of another client's command.
+func (s *Server) closeConn(ctx context.Context, cc *clientConn) {
+	log := logger.LogFromCtx(ctx)
+
+	if ss := cc.stream; ss != nil {
+		if ss.job != nil && ss.job.isPaused() {
+			log.Info("keeping paused stream session", "job", ss.job.id)
+			go ss.close()
+		} else {
+			ss.abort()
+		}
+		cc.stream = nil
+	}
+
+	codes := cc.heldCodes()
+	if len(codes) == 0 && len(cc.axes) == 0 {
+		return
+	}
+	leave := s.queue.enterCleanup(ctx)
+	defer leave()
+
+	if len(codes) > 0 {
+		log.Info("releasing held keys", "keys", codes)
+		if err := s.releaseAll(cc); err != nil {
+			log.Error("failed to release held keys", "error", err)
+		}
+	}
+
+	if len(cc.axes) > 0 {
+		log.Info("resetting gamepad axes", "axes", len(cc.axes))
+		if err := s.resetAxes(cc); err != nil {
+			log.Error("failed to reset gamepad axes", "error", err)
+		}
+	}
+}
+
+// streamSession types text chunks as they arrive on a connection.
+// Chunks are queued and typed in order by a dedicated goroutine, so the
+// client is acknowledged immediately and can keep producing text.
+type streamSession struct {
+	typer     *charTyper // Only used by run until the queue is drained
+	queue     *cmdQueue
+	charDelay time.Duration
+	wordDelay time.Duration
+
+	chunks  chan string
+	pending sync.WaitGroup
+	cancel  context.CancelFunc
+	done    chan struct{}
+	job     *job // Ended once the session is over, if set
+
+	mu  sync.Mutex
+	err error
+}
+
+// newStreamSession starts a session typing with the given typer and delays.
+func (s *Server) newStreamSession(ctx context.Context, typer *charTyper, charDelay, wordDelay time.Duration) *streamSession {
+	ctx, cancel := context.WithCancel(ctx)
+
+	ss := &streamSession{
+		typer:     typer,
+		queue:     &s.queue,
+		charDelay: charDelay,
+		wordDelay: wordDelay,
+		chunks:    make(chan string, streamQueueSize),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	go ss.run(ctx)
+	return ss
+}
+
+// run types queued chunks until the queue is closed.
+// After the first failure, remaining chunks are discarded.
+func (ss *streamSession) run(ctx context.Context) {
+	defer close(ss.done)
+
+	for chunk := range ss.chunks {
+		if ss.Err() == nil {
+			if err := ss.typeChunk(ctx, chunk); err != nil {
+				ss.mu.Lock()
+				ss.err = err
+				ss.mu.Unlock()
+			}
+		}
+		ss.pending.Done()
+	}
+}
+
+// typeChunk types a single chunk, pausing after each character.
+// Whitespace is followed by the word delay, anything else by the char delay.
+// Other commands wait until the whole chunk is typed.
+func (ss *streamSession) typeChunk(ctx context.Context, chunk string) error {
+	ctx, leave, err := ss.queue.enter(ctx)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	for _, char := range chunk {
+		if err := ss.typer.typeChar(ctx, char); err != nil {
+			return err
+		}
+
+		delay := ss.charDelay
+		if unicode.IsSpace(char) {
+			delay = ss.wordDelay
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueue queues a chunk for typing.
+func (ss *streamSession) enqueue(chunk string) {
+	if ss.job != nil {
+		ss.job.total.Add(int64(utf8.RuneCountInString(chunk)))
+	}
+	ss.pending.Add(1)
+	ss.chunks <- chunk
+}
+
+// flush waits until every queued chunk has been typed.
+func (ss *streamSession) flush() error {
+	ss.pending.Wait()
+	return ss.Err()
+}
+
+// close flushes the queue and stops the session.
+func (ss *streamSession) close() error {
+	close(ss.chunks)
+	<-ss.done
+	ss.stop()
+	return ss.Err()
+}
+
+// abort stops typing as soon as possible and discards queued chunks.
+func (ss *streamSession) abort() {
+	ss.cancel()
+	close(ss.chunks)
+	<-ss.done
+	ss.stop()
+}
+
+// stop releases the session's context once typing is over.
+func (ss *streamSession) stop() {
+	ss.cancel()
+	if ss.job != nil {
+		ss.job.end()
+	}
+}
+
+// Err returns the first typing error of the session, if any.
+func (ss *streamSession) Err() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.err
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/logger"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+)
+
+// commandTypes lists the commands handleCommand serves, as reported by
+// capabilities commands.
+var commandTypes = []protocol.CommandType{
+	protocol.CommandType_Type,
+	protocol.CommandType_Stream,
+	protocol.CommandType_Key,
+	protocol.CommandType_Ping,
+	protocol.CommandType_Plan,
+	protocol.CommandType_Status,
+	protocol.CommandType_Capabilities,
+	protocol.CommandType_Jobs,
+	protocol.CommandType_Cancel,
+	protocol.CommandType_Pause,
+	protocol.CommandType_Resume,
+	protocol.CommandType_KeyDown,
+	protocol.CommandType_KeyUp,
+	protocol.CommandType_StreamOpen,
+	protocol.CommandType_StreamChunk,
+	protocol.CommandType_StreamFlush,
+	protocol.CommandType_StreamClose,
+	protocol.CommandType_MouseMove,
+	protocol.CommandType_MouseClick,
+	protocol.CommandType_MouseDown,
+	protocol.CommandType_MouseUp,
+	protocol.CommandType_MouseScroll,
+	protocol.CommandType_AbsPointer,
+	protocol.CommandType_GamepadButton,
+	protocol.CommandType_GamepadAxis,
+}
+
+// healthChecker is implemented by devices that can tell whether they
+// still work, like *uinput.Device.
+type healthChecker interface {
+	Healthy() error
+}
+
+// deviceEntry is a virtual device of the daemon.
+type deviceEntry struct {
+	info   protocol.DeviceInfo
+	device uinput.DeviceInterface // Nil until created
+}
+
+// SetVersion sets the daemon version reported to clients.
+func (s *Server) SetVersion(version string) {
+	s.version = version
+}
+
+// devices lists the built-in devices, then the named devices by name.
+func (s *Server) devices() []deviceEntry {
+	builtin := func(name string, profile uinput.Profile, device uinput.DeviceInterface) deviceEntry {
+		return deviceEntry{
+			info:   protocol.DeviceInfo{Name: name, Profile: string(profile), Builtin: true},
+			device: device,
+		}
+	}
+
+	entries := []deviceEntry{builtin(uinput.DeviceName, uinput.ProfileKeyboard, s.device)}
+	if s.pointer != nil {
+		entries = append(entries, builtin(uinput.PointerDeviceName, uinput.ProfilePointer, s.pointer))
+	}
+	if s.absolute != nil {
+		name := uinput.TabletDeviceName
+		if uinput.Profile(s.cfg.Devices.Absolute.Mode) == uinput.ProfileTouchscreen {
+			name = uinput.TouchscreenDeviceName
+		}
+		entries = append(entries, builtin(name, uinput.Profile(s.cfg.Devices.Absolute.Mode), s.absolute))
+	}
+	if s.gamepad.factory != nil {
+		entries = append(entries, builtin(uinput.GamepadDeviceName, uinput.ProfileGamepad, s.currentGamepad()))
+	}
+
+	names := make([]string, 0, len(s.named))
+	for name := range s.named {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		d := s.named[name]
+		entries = append(entries, deviceEntry{
+			info:   protocol.DeviceInfo{Name: d.Name, Profile: string(d.Profile)},
+			device: d.Device,
+		})
+	}
+
+	return entries
+}
+
+// availableCommands returns the commands some device can serve.
+func availableCommands(entries []deviceEntry) []protocol.CommandType {
+	var commands []protocol.CommandType
+	for _, cmd := range commandTypes {
+		profiles, ok := commandProfiles[cmd]
+		if !ok || slices.ContainsFunc(entries, func(e deviceEntry) bool {
+			return slices.Contains(profiles, uinput.Profile(e.info.Profile))
+		}) {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// handleStatus reports uptime, counters, the health of each device and the
+// progress of running jobs.
+func (s *Server) handleStatus(ctx context.Context) error {
+	log := logger.LogFromCtx(ctx)
+
+	status := &protocol.Status{
+		Version:        s.version,
+		StartedAt:      s.started,
+		UptimeMs:       time.Since(s.started).Milliseconds(),
+		CommandsServed: s.served.Load(),
+		Connections:    s.clients.len(),
+		Healthy:        true,
+	}
+
+	for _, entry := range s.devices() {
+		ds := protocol.DeviceStatus{DeviceInfo: entry.info}
+		if entry.device != nil {
+			ds.Created, ds.Healthy = true, true
+			if hc, ok := entry.device.(healthChecker); ok {
+				if err := hc.Healthy(); err != nil {
+					ds.Healthy, ds.Error = false, err.Error()
+					status.Healthy = false
+					log.Warn("device unhealthy", "name", ds.Name, "error", err)
+				}
+			}
+		}
+		status.Devices = append(status.Devices, ds)
+	}
+
+	for _, j := range s.runningJobs() {
+		status.Jobs = append(status.Jobs, j.info())
+	}
+	status.Clients = s.clients.info()
+	status.RateLimits = s.limiter.status()
+
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Status = status
+	}
+	return nil
+}
+
+// handleCapabilities reports the version, commands, layouts, devices and
+// limits of the daemon.
+func (s *Server) handleCapabilities(ctx context.Context) error {
+	entries := s.devices()
+
+	defaultFallback := s.cfg.Fallback
+	if defaultFallback == "" {
+		defaultFallback = protocol.Fallback_None
+	}
+
+	caps := &protocol.Capabilities{
+		Version:         s.version,
+		Commands:        availableCommands(entries),
+		DefaultLayout:   s.cfg.Layout,
+		LayoutDetect:    s.detector != nil,
+		Layouts:         s.registry.Available(),
+		Fallbacks:       []string{protocol.Fallback_None, protocol.Fallback_UnicodeHex},
+		DefaultFallback: defaultFallback,
+		Limits: protocol.Limits{
+			MaxMessageSize:    s.cfg.Performance.MaxMessageSize,
+			BufferSize:        s.cfg.Performance.BufferSize,
+			MaxConcurrentCmds: s.cfg.Performance.MaxConcurrentCmds,
+			StreamDelayMs:     s.cfg.Performance.StreamDelayMs,
+			CharDelayMs:       s.cfg.Performance.CharDelayMs,
+		},
+	}
+	slices.Sort(caps.Layouts)
+	for _, entry := range entries {
+		caps.Devices = append(caps.Devices, entry.info)
+	}
+
+	if resp := responseFromCtx(ctx); resp != nil {
+		resp.Capabilities = caps
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/layouts"
+	"github.com/bnema/uinputd-go/internal/peer"
+	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/uinput"
+	uinputMocks "github.com/bnema/uinputd-go/internal/uinput/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenDevice is a device whose health check fails.
+type brokenDevice struct {
+	*uinputMocks.MockDeviceInterface
+}
+
+func (brokenDevice) Healthy() error {
+	return errors.New("write event: no such device")
+}
+
+func TestHandleStatus(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.SetVersion("1.2.3")
+	server.SetGamepadFactory(func(context.Context) (uinput.DeviceInterface, error) {
+		return uinputMocks.NewMockDeviceInterface(t), nil
+	})
+	assert.NoError(t, server.AddDevice(NamedDevice{
+		Name:    "vm-pad",
+		Profile: uinput.ProfilePointer,
+		Device:  brokenDevice{uinputMocks.NewMockDeviceInterface(t)},
+	}))
+	server.served.Add(3)
+
+	resp := &protocol.Response{}
+	err := server.handleStatus(withResponse(context.Background(), resp))
+
+	assert.NoError(t, err)
+	if !assert.NotNil(t, resp.Status) {
+		return
+	}
+	assert.Equal(t, "1.2.3", resp.Status.Version)
+	assert.Equal(t, uint64(3), resp.Status.CommandsServed)
+	assert.False(t, resp.Status.Healthy)
+	assert.Equal(t, []protocol.DeviceStatus{
+		{
+			DeviceInfo: protocol.DeviceInfo{Name: uinput.DeviceName, Profile: "keyboard", Builtin: true},
+			Created:    true,
+			Healthy:    true,
+		},
+		{
+			// Created on the first gamepad command
+			DeviceInfo: protocol.DeviceInfo{Name: uinput.GamepadDeviceName, Profile: "gamepad", Builtin: true},
+		},
+		{
+			DeviceInfo: protocol.DeviceInfo{Name: "vm-pad", Profile: "pointer"},
+			Created:    true,
+			Error:      "write event: no such device",
+		},
+	}, resp.Status.Devices)
+}
+
+func TestHandleStatusClients(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+
+	connected := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	known := &clientConn{
+		peer:        &peer.Cred{PID: 42, UID: 1000, GID: 100, Exe: "/usr/bin/dictate", Cmdline: []string{"dictate", "-v"}},
+		connectedAt: connected,
+	}
+	known.commands.Add(2)
+	unknown := &clientConn{connectedAt: connected.Add(time.Second)}
+	gone := &clientConn{}
+
+	server.clients.add(known)
+	server.clients.add(gone)
+	server.clients.add(unknown)
+	server.clients.remove(gone)
+
+	resp := &protocol.Response{}
+	assert.NoError(t, server.handleStatus(withResponse(context.Background(), resp)))
+	if !assert.NotNil(t, resp.Status) {
+		return
+	}
+	assert.Equal(t, 2, resp.Status.Connections)
+	assert.Equal(t, []protocol.ClientInfo{
+		{
+			Peer:        &protocol.PeerInfo{PID: 42, UID: 1000, GID: 100, Exe: "/usr/bin/dictate", Cmdline: []string{"dictate", "-v"}},
+			ConnectedAt: connected,
+			Commands:    2,
+		},
+		{ConnectedAt: connected.Add(time.Second)},
+	}, resp.Status.Clients)
+}
+
+func TestHandleCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Server)
+		included []protocol.CommandType
+		excluded []protocol.CommandType
+	}{
+		{
+			name:     "keyboard only",
+			setup:    func(*Server) {},
+			included: []protocol.CommandType{protocol.CommandType_Type, protocol.CommandType_Ping, protocol.CommandType_Status},
+			excluded: []protocol.CommandType{protocol.CommandType_MouseMove, protocol.CommandType_AbsPointer, protocol.CommandType_GamepadAxis},
+		},
+		{
+			name: "pointer and gamepad",
+			setup: func(s *Server) {
+				s.SetPointer(uinputMocks.NewMockDeviceInterface(t))
+				s.SetGamepadFactory(func(context.Context) (uinput.DeviceInterface, error) { return nil, nil })
+			},
+			included: []protocol.CommandType{protocol.CommandType_MouseMove, protocol.CommandType_GamepadAxis},
+			excluded: []protocol.CommandType{protocol.CommandType_AbsPointer},
+		},
+		{
+			name: "named tablet",
+			setup: func(s *Server) {
+				_ = s.AddDevice(NamedDevice{Name: "tablet", Profile: uinput.ProfileTablet, Device: uinputMocks.NewMockDeviceInterface(t)})
+			},
+			included: []protocol.CommandType{protocol.CommandType_AbsPointer},
+			excluded: []protocol.CommandType{protocol.CommandType_MouseMove},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+			tt.setup(server)
+
+			resp := &protocol.Response{}
+			err := server.handleCapabilities(withResponse(context.Background(), resp))
+
+			assert.NoError(t, err)
+			if !assert.NotNil(t, resp.Capabilities) {
+				return
+			}
+			for _, cmd := range tt.included {
+				assert.Contains(t, resp.Capabilities.Commands, cmd)
+			}
+			for _, cmd := range tt.excluded {
+				assert.NotContains(t, resp.Capabilities.Commands, cmd)
+			}
+		})
+	}
+}
+
+func TestHandleCapabilitiesConfig(t *testing.T) {
+	server := newTestServer(uinputMocks.NewMockDeviceInterface(t), layouts.NewRegistry())
+	server.cfg.Performance.MaxMessageSize = 1024
+
+	resp := &protocol.Response{}
+	err := server.handleCapabilities(withResponse(context.Background(), resp))
+
+	assert.NoError(t, err)
+	caps := resp.Capabilities
+	if !assert.NotNil(t, caps) {
+		return
+	}
+	assert.Equal(t, "us", caps.DefaultLayout)
+	assert.Equal(t, []string{"de", "es", "fr", "it", "uk", "us"}, caps.Layouts)
+	assert.Equal(t, protocol.Fallback_None, caps.DefaultFallback)
+	assert.Equal(t, 1024, caps.Limits.MaxMessageSize)
+	assert.Equal(t, 10, caps.Limits.CharDelayMs)
+	assert.Equal(t, []protocol.DeviceInfo{{Name: uinput.DeviceName, Profile: "keyboard", Builtin: true}}, caps.Devices)
+}
@@ -15,6 +15,37 @@ const (
 	SynReport = 0 // Marks end of event batch
 )
 
+// Relative axis codes
+const (
+	RelX           = 0x00
+	RelY           = 0x01
+	RelHWheel      = 0x06
+	RelWheel       = 0x08
+	RelWheelHiRes  = 0x0b // 1/120 of a wheel detent
+	RelHWheelHiRes = 0x0c
+)
+
+// Absolute axis codes
+const (
+	AbsX     = 0x00
+	AbsY     = 0x01
+	AbsZ     = 0x02
+	AbsRX    = 0x03
+	AbsRY    = 0x04
+	AbsRZ    = 0x05
+	AbsHat0X = 0x10
+	AbsHat0Y = 0x11
+)
+
+// Input device properties
+const (
+	InputPropPointer = 0x00 // Needs a pointer on screen (tablet)
+	InputPropDirect  = 0x01 // Direct input device (touchscreen)
+)
+
+// WheelHiResUnit is the number of high-resolution wheel units per detent.
+const WheelHiResUnit = 120
+
 // Key event values
 const (
 	KeyRelease = 0 // Key released
@@ -79,27 +110,145 @@ const (
 	KeyDot        = 52
 	KeySlash      = 53
 	KeyRightShift = 54
+	KeyKPAsterisk = 55
 	KeyLeftAlt    = 56
 	KeySpace      = 57
 	KeyCapsLock   = 58
-	Key102ND      = 86  // Extra key on non-US keyboards (< > |)
-	KeyRightAlt   = 100 // AltGr
+	KeyF1         = 59
+	KeyF2         = 60
+	KeyF3         = 61
+	KeyF4         = 62
+	KeyF5         = 63
+	KeyF6         = 64
+	KeyF7         = 65
+	KeyF8         = 66
+	KeyF9         = 67
+	KeyF10        = 68
+	KeyNumLock    = 69
+	KeyScrollLock = 70
+	Key102ND      = 86 // Extra key on non-US keyboards (< > |)
+	KeyF11        = 87
+	KeyF12        = 88
+	KeyKPEnter    = 96
 	KeyRightCtrl  = 97
+	KeySysRq      = 99  // Print Screen
+	KeyRightAlt   = 100 // AltGr
+	KeyHome       = 102
+	KeyUp         = 103
+	KeyPageUp     = 104
+	KeyLeft       = 105
+	KeyRight      = 106
+	KeyEnd        = 107
+	KeyDown       = 108
+	KeyPageDown   = 109
+	KeyInsert     = 110
+	KeyDelete     = 111
+	KeyMute       = 113
+	KeyVolumeDown = 114
+	KeyVolumeUp   = 115
+	KeyPause      = 119
+	KeyLeftMeta   = 125 // Super / Windows key
+	KeyRightMeta  = 126
+	KeyCompose    = 127 // Menu key
+	KeyNextSong   = 163
+	KeyPlayPause  = 164
+	KeyPrevSong   = 165
+	KeyF13        = 183
+	KeyF14        = 184
+	KeyF15        = 185
+	KeyF16        = 186
+	KeyF17        = 187
+	KeyF18        = 188
+	KeyF19        = 189
+	KeyF20        = 190
+	KeyF21        = 191
+	KeyF22        = 192
+	KeyF23        = 193
+	KeyF24        = 194
+)
+
+// Mouse button codes
+const (
+	BtnLeft    = 0x110
+	BtnRight   = 0x111
+	BtnMiddle  = 0x112
+	BtnSide    = 0x113 // Back
+	BtnExtra   = 0x114 // Forward
+	BtnForward = 0x115
+	BtnBack    = 0x116
+	BtnTask    = 0x117
+	BtnTouch   = 0x14a
+)
+
+// Gamepad button codes
+// See: Documentation/input/gamepad.rst in the kernel tree
+const (
+	BtnSouth  = 0x130 // A on Xbox-style pads
+	BtnEast   = 0x131 // B
+	BtnNorth  = 0x133 // Top face button
+	BtnWest   = 0x134 // Left face button
+	BtnTL     = 0x136 // Left bumper
+	BtnTR     = 0x137 // Right bumper
+	BtnSelect = 0x13a
+	BtnStart  = 0x13b
+	BtnMode   = 0x13c // Guide/home
+	BtnThumbL = 0x13d // Left stick click
+	BtnThumbR = 0x13e // Right stick click
+)
+
+// Bus types
+const (
+	BusUSB       = 0x03 // BUS_USB
+	BusBluetooth = 0x05 // BUS_BLUETOOTH
+	BusVirtual   = 0x06 // BUS_VIRTUAL
 )
 
+// BusNames maps bus names accepted in configuration to bus types.
+var BusNames = map[string]uint16{
+	"usb":       BusUSB,
+	"bluetooth": BusBluetooth,
+	"virtual":   BusVirtual,
+}
+
+// UinputMaxNameSize is the size of the device name buffer, including the
+// terminating NUL (UINPUT_MAX_NAME_SIZE).
+const UinputMaxNameSize = 80
+
 // Device name and ID
 const (
 	DeviceName = "uinputd-virtual-keyboard"
-	BusVirtual = 0x06 // BUS_VIRTUAL
 	VendorID   = 0x1234
 	ProductID  = 0x5678
 	Version    = 1
 )
 
+// Pointer device name and ID
+const (
+	PointerDeviceName = "uinputd-virtual-pointer"
+	PointerProductID  = 0x5679
+)
+
+// Absolute pointer device names and ID
+const (
+	TabletDeviceName      = "uinputd-virtual-tablet"
+	TouchscreenDeviceName = "uinputd-virtual-touchscreen"
+	AbsoluteProductID     = 0x567a
+)
+
+// Gamepad device name and ID
+const (
+	GamepadDeviceName = "uinputd-virtual-gamepad"
+	GamepadProductID  = 0x567b
+)
+
 // uinput ioctl constants
 const (
 	UI_SET_EVBIT   = 0x40045564
 	UI_SET_KEYBIT  = 0x40045565
+	UI_SET_RELBIT  = 0x40045566
+	UI_SET_ABSBIT  = 0x40045567
+	UI_SET_PROPBIT = 0x4004556e
+	UI_ABS_SETUP   = 0x401c5504 // _IOW('U', 4, struct uinput_abs_setup)
 	UI_DEV_CREATE  = 0x5501
 	UI_DEV_DESTROY = 0x5502
 	UI_DEV_SETUP   = 0x405c5503
@@ -11,18 +11,129 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-// Device represents a virtual uinput keyboard device.
+// Device represents a virtual uinput device.
+// The same type backs every profile (keyboard, pointer, tablet, ...); they
+// only differ in the capabilities enabled at creation time.
 type Device struct {
-	fd   *os.File
-	mu   sync.Mutex
-	name string
+	fd      *os.File
+	mu      sync.Mutex
+	id      Identity
+	lastErr error // Error of the last write, if it failed
+}
+
+// Profile selects the capabilities of a virtual device.
+type Profile string
+
+const (
+	ProfileKeyboard Profile = "keyboard" // Every key
+	ProfilePointer  Profile = "pointer"  // Relative mouse with wheels
+
+	// ProfileTablet is an absolute mouse (like a VM tablet): the pointer
+	// follows the coordinates and buttons click where it is.
+	ProfileTablet Profile = "tablet"
+
+	// ProfileTouchscreen is a single-touch screen: coordinates are only
+	// reported while touching.
+	ProfileTouchscreen Profile = "touchscreen"
+
+	ProfileGamepad Profile = "gamepad" // Buttons, sticks, triggers and D-pad
+)
+
+// Identity is the name and IDs a virtual device reports to the system.
+// Zero fields fall back to the defaults of the device profile.
+type Identity struct {
+	Name    string
+	Bustype uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+// Options describes a virtual device to create.
+type Options struct {
+	Profile  Profile
+	Identity Identity
+
+	// Coordinate space of the tablet and touchscreen profiles: coordinates
+	// range from 0 to Width-1 and 0 to Height-1, and the compositor scales
+	// that range to the output(s) the device is mapped to.
+	Width  int32
+	Height int32
 }
 
 // New creates and initializes a new virtual keyboard device.
 // This opens /dev/uinput and configures it as a keyboard.
 func New(ctx context.Context) (*Device, error) {
+	return Create(ctx, Options{Profile: ProfileKeyboard})
+}
+
+// NewPointer creates and initializes a new virtual pointer (mouse) device
+// with relative axes, high-resolution wheels and mouse buttons.
+func NewPointer(ctx context.Context) (*Device, error) {
+	return Create(ctx, Options{Profile: ProfilePointer})
+}
+
+// Create creates and initializes a virtual device with the capabilities
+// of opts.Profile and the identity of opts.Identity.
+func Create(ctx context.Context, opts Options) (*Device, error) {
+	var (
+		name    string
+		product uint16
+		enable  func(ctx context.Context, d *Device) error
+	)
+
+	switch opts.Profile {
+	case ProfileKeyboard:
+		name, product, enable = DeviceName, ProductID, enableKeyboard
+	case ProfilePointer:
+		name, product, enable = PointerDeviceName, PointerProductID, enablePointer
+	case ProfileTablet, ProfileTouchscreen:
+		if opts.Width <= 1 || opts.Height <= 1 {
+			return nil, fmt.Errorf("invalid absolute range %dx%d", opts.Width, opts.Height)
+		}
+		name, product = TabletDeviceName, AbsoluteProductID
+		prop, buttons := uintptr(InputPropPointer), []uint16{BtnLeft, BtnRight, BtnMiddle}
+		if opts.Profile == ProfileTouchscreen {
+			name = TouchscreenDeviceName
+			prop, buttons = InputPropDirect, []uint16{BtnTouch}
+		}
+		enable = func(ctx context.Context, d *Device) error {
+			return enableAbsolute(d, opts.Width, opts.Height, prop, buttons)
+		}
+	case ProfileGamepad:
+		name, product, enable = GamepadDeviceName, GamepadProductID, enableGamepad
+	default:
+		return nil, fmt.Errorf("unknown device profile: %s", opts.Profile)
+	}
+
+	id := opts.Identity
+	if id.Name == "" {
+		id.Name = name
+	}
+	if id.Bustype == 0 {
+		id.Bustype = BusVirtual
+	}
+	if id.Vendor == 0 {
+		id.Vendor = VendorID
+	}
+	if id.Product == 0 {
+		id.Product = product
+	}
+	if id.Version == 0 {
+		id.Version = Version
+	}
+
+	return create(ctx, id, enable)
+}
+
+// create opens /dev/uinput, enables capabilities and creates the device.
+func create(ctx context.Context, id Identity, enable func(ctx context.Context, d *Device) error) (*Device, error) {
 	log := logger.LogFromCtx(ctx)
-	log.Info("creating virtual keyboard device", "name", DeviceName)
+	log.Info("creating virtual device", "name", id.Name, "vendor", fmt.Sprintf("%04x", id.Vendor), "product", fmt.Sprintf("%04x", id.Product))
+
+	if len(id.Name) >= UinputMaxNameSize {
+		return nil, fmt.Errorf("device name too long: %q (max %d bytes)", id.Name, UinputMaxNameSize-1)
+	}
 
 	// Open /dev/uinput
 	fd, err := os.OpenFile("/dev/uinput", os.O_WRONLY|unix.O_NONBLOCK, 0)
@@ -31,22 +142,26 @@ func New(ctx context.Context) (*Device, error) {
 	}
 
 	d := &Device{
-		fd:   fd,
-		name: DeviceName,
+		fd: fd,
+		id: id,
 	}
 
 	// Setup device capabilities and create the virtual device
+	if err := enable(ctx, d); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("device setup failed: %w", err)
+	}
 	if err := d.setup(ctx); err != nil {
 		d.Close()
 		return nil, fmt.Errorf("device setup failed: %w", err)
 	}
 
-	log.Info("virtual keyboard device created successfully")
+	log.Info("virtual device created successfully", "name", id.Name)
 	return d, nil
 }
 
-// setup configures the uinput device with keyboard capabilities.
-func (d *Device) setup(ctx context.Context) error {
+// enableKeyboard enables keyboard capabilities.
+func enableKeyboard(ctx context.Context, d *Device) error {
 	log := logger.LogFromCtx(ctx)
 
 	// Enable key events (EV_KEY)
@@ -54,11 +169,6 @@ func (d *Device) setup(ctx context.Context) error {
 		return fmt.Errorf("set EV_KEY: %w", err)
 	}
 
-	// Enable synchronization events (EV_SYN)
-	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvSyn)); err != nil {
-		return fmt.Errorf("set EV_SYN: %w", err)
-	}
-
 	// Enable all keyboard keys (KEY_RESERVED to KEY_MAX)
 	// Note: KEY_MAX is typically 0x2ff (767)
 	for key := uint16(KeyReserved); key <= 767; key++ {
@@ -68,18 +178,105 @@ func (d *Device) setup(ctx context.Context) error {
 		}
 	}
 
+	return nil
+}
+
+// enablePointer enables mouse buttons, relative axes and wheels.
+func enablePointer(ctx context.Context, d *Device) error {
+	// Enable key events (EV_KEY) for the buttons
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvKey)); err != nil {
+		return fmt.Errorf("set EV_KEY: %w", err)
+	}
+	for btn := uint16(BtnLeft); btn <= BtnTask; btn++ {
+		if err := d.ioctl(UI_SET_KEYBIT, uintptr(btn)); err != nil {
+			return fmt.Errorf("set button %#x: %w", btn, err)
+		}
+	}
+
+	// Enable relative events (EV_REL)
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvRel)); err != nil {
+		return fmt.Errorf("set EV_REL: %w", err)
+	}
+	for _, rel := range []uint16{RelX, RelY, RelWheel, RelHWheel, RelWheelHiRes, RelHWheelHiRes} {
+		if err := d.ioctl(UI_SET_RELBIT, uintptr(rel)); err != nil {
+			return fmt.Errorf("set axis %#x: %w", rel, err)
+		}
+	}
+
+	return nil
+}
+
+// enableAbsolute enables ABS_X/ABS_Y with the given ranges, buttons and
+// device property.
+func enableAbsolute(d *Device, width, height int32, prop uintptr, buttons []uint16) error {
+	// Enable key events (EV_KEY) for the buttons
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvKey)); err != nil {
+		return fmt.Errorf("set EV_KEY: %w", err)
+	}
+	for _, btn := range buttons {
+		if err := d.ioctl(UI_SET_KEYBIT, uintptr(btn)); err != nil {
+			return fmt.Errorf("set button %#x: %w", btn, err)
+		}
+	}
+
+	// Enable absolute events (EV_ABS) and their ranges
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvAbs)); err != nil {
+		return fmt.Errorf("set EV_ABS: %w", err)
+	}
+	axes := []struct {
+		code uint16
+		max  int32
+	}{
+		{AbsX, width - 1},
+		{AbsY, height - 1},
+	}
+	for _, axis := range axes {
+		if err := d.enableAbs(axis.code, absInfo{Maximum: axis.max}); err != nil {
+			return err
+		}
+	}
+
+	if err := d.ioctl(UI_SET_PROPBIT, prop); err != nil {
+		return fmt.Errorf("set property %#x: %w", prop, err)
+	}
+
+	return nil
+}
+
+// enableAbs enables an absolute axis and sets its range.
+func (d *Device) enableAbs(code uint16, info absInfo) error {
+	if err := d.ioctl(UI_SET_ABSBIT, uintptr(code)); err != nil {
+		return fmt.Errorf("set axis %#x: %w", code, err)
+	}
+	setup := absSetup{
+		Code: code,
+		Info: info,
+	}
+	if err := d.ioctlAbsSetup(&setup); err != nil {
+		return fmt.Errorf("UI_ABS_SETUP %#x: %w", code, err)
+	}
+	return nil
+}
+
+// setup registers the device identity and creates it.
+func (d *Device) setup(ctx context.Context) error {
+	// Enable synchronization events (EV_SYN)
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvSyn)); err != nil {
+		return fmt.Errorf("set EV_SYN: %w", err)
+	}
+
 	// Configure device setup structure
 	// This uses UI_DEV_SETUP ioctl (kernel >= 4.5)
 	setup := uiSetup{
 		ID: inputID{
-			Bustype: BusVirtual,
-			Vendor:  VendorID,
-			Product: ProductID,
-			Version: Version,
+			Bustype: d.id.Bustype,
+			Vendor:  d.id.Vendor,
+			Product: d.id.Product,
+			Version: d.id.Version,
 		},
 		FFEffectsMax: 0,
 	}
-	copy(setup.Name[:], d.name)
+	copy(setup.Name[:], d.id.Name)
 
 	// Write setup structure
 	if err := d.ioctlSetup(&setup); err != nil {
@@ -115,6 +312,26 @@ func (d *Device) Close() error {
 	return err
 }
 
+// Name returns the name the device reports to the system.
+func (d *Device) Name() string {
+	return d.id.Name
+}
+
+// Healthy returns an error if the device is closed or the last event
+// written to it failed.
+func (d *Device) Healthy() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.fd == nil {
+		return fmt.Errorf("device not open")
+	}
+	if d.lastErr != nil {
+		return fmt.Errorf("last write failed: %w", d.lastErr)
+	}
+	return nil
+}
+
 // ioctl performs an ioctl system call on the device.
 func (d *Device) ioctl(req, arg uintptr) error {
 	_, _, errno := unix.Syscall(unix.SYS_IOCTL, d.fd.Fd(), req, arg)
@@ -138,11 +355,25 @@ func (d *Device) ioctlSetup(setup *uiSetup) error {
 	return nil
 }
 
+// ioctlAbsSetup performs UI_ABS_SETUP ioctl with absSetup structure.
+func (d *Device) ioctlAbsSetup(setup *absSetup) error {
+	_, _, errno := unix.Syscall(
+		unix.SYS_IOCTL,
+		d.fd.Fd(),
+		uintptr(UI_ABS_SETUP),
+		uintptr(unsafe.Pointer(setup)),
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
 // uiSetup is the structure for UI_DEV_SETUP ioctl.
 // See: <linux/uinput.h> struct uinput_setup
 type uiSetup struct {
 	ID           inputID
-	Name         [80]byte
+	Name         [UinputMaxNameSize]byte
 	FFEffectsMax uint32
 }
 
@@ -154,3 +385,22 @@ type inputID struct {
 	Product uint16
 	Version uint16
 }
+
+// absSetup is the structure for UI_ABS_SETUP ioctl.
+// See: <linux/uinput.h> struct uinput_abs_setup
+type absSetup struct {
+	Code uint16
+	_    uint16 // Padding before the 4-byte aligned absinfo
+	Info absInfo
+}
+
+// absInfo describes an absolute axis.
+// See: <linux/input.h> struct input_absinfo
+type absInfo struct {
+	Value      int32
+	Minimum    int32
+	Maximum    int32
+	Fuzz       int32
+	Flat       int32
+	Resolution int32
+}
@@ -57,6 +57,16 @@ func NewKeyEvent(keycode uint16, pressed bool) *InputEvent {
 	return NewEvent(EvKey, keycode, value)
 }
 
+// NewRelEvent creates a relative axis event (pointer motion or wheel).
+func NewRelEvent(axis uint16, value int32) *InputEvent {
+	return NewEvent(EvRel, axis, value)
+}
+
+// NewAbsEvent creates an absolute axis event.
+func NewAbsEvent(axis uint16, value int32) *InputEvent {
+	return NewEvent(EvAbs, axis, value)
+}
+
 // NewSynEvent creates a synchronization event (SYN_REPORT).
 func NewSynEvent() *InputEvent {
 	return NewEvent(EvSyn, SynReport, 0)
@@ -91,6 +101,8 @@ func (d *Device) SendKey(ctx context.Context, keycode uint16) error {
 }
 
 // SendKeyWithModifier sends a key with a modifier (e.g., Shift+A).
+// The modifier is released even if a write fails after pressing it, so that
+// it does not stay stuck.
 func (d *Device) SendKeyWithModifier(ctx context.Context, modifier, keycode uint16) error {
 	select {
 	case <-ctx.Done():
@@ -102,35 +114,39 @@ func (d *Device) SendKeyWithModifier(ctx context.Context, modifier, keycode uint
 	if err := d.WriteEvent(NewKeyEvent(modifier, true)); err != nil {
 		return fmt.Errorf("modifier press: %w", err)
 	}
-	if err := d.WriteEvent(NewSynEvent()); err != nil {
-		return fmt.Errorf("syn after modifier: %w", err)
-	}
-
-	// Press key
-	if err := d.WriteEvent(NewKeyEvent(keycode, true)); err != nil {
-		return fmt.Errorf("key press: %w", err)
-	}
-	if err := d.WriteEvent(NewSynEvent()); err != nil {
-		return fmt.Errorf("syn after press: %w", err)
-	}
 
-	// Release key
-	if err := d.WriteEvent(NewKeyEvent(keycode, false)); err != nil {
-		return fmt.Errorf("key release: %w", err)
-	}
-	if err := d.WriteEvent(NewSynEvent()); err != nil {
-		return fmt.Errorf("syn after release: %w", err)
-	}
+	err := func() error {
+		if err := d.WriteEvent(NewSynEvent()); err != nil {
+			return fmt.Errorf("syn after modifier: %w", err)
+		}
+
+		// Press key
+		if err := d.WriteEvent(NewKeyEvent(keycode, true)); err != nil {
+			return fmt.Errorf("key press: %w", err)
+		}
+		if err := d.WriteEvent(NewSynEvent()); err != nil {
+			return fmt.Errorf("syn after press: %w", err)
+		}
+
+		// Release key
+		if err := d.WriteEvent(NewKeyEvent(keycode, false)); err != nil {
+			return fmt.Errorf("key release: %w", err)
+		}
+		if err := d.WriteEvent(NewSynEvent()); err != nil {
+			return fmt.Errorf("syn after release: %w", err)
+		}
+		return nil
+	}()
 
 	// Release modifier
-	if err := d.WriteEvent(NewKeyEvent(modifier, false)); err != nil {
-		return fmt.Errorf("modifier release: %w", err)
+	if rerr := d.WriteEvent(NewKeyEvent(modifier, false)); rerr != nil && err == nil {
+		err = fmt.Errorf("modifier release: %w", rerr)
 	}
-	if err := d.WriteEvent(NewSynEvent()); err != nil {
-		return fmt.Errorf("syn after modifier release: %w", err)
+	if rerr := d.WriteEvent(NewSynEvent()); rerr != nil && err == nil {
+		err = fmt.Errorf("syn after modifier release: %w", rerr)
 	}
 
-	return nil
+	return err
 }
 
 // WriteEvent writes a single InputEvent to the uinput device.
@@ -144,12 +160,14 @@ func (d *Device) WriteEvent(event *InputEvent) error {
 
 	data := event.Marshal()
 	n, err := d.fd.Write(data)
-	if err != nil {
-		return fmt.Errorf("write event: %w", err)
-	}
-	if n != len(data) {
-		return fmt.Errorf("incomplete write: %d/%d bytes", n, len(data))
+	switch {
+	case err != nil:
+		d.lastErr = fmt.Errorf("write event: %w", err)
+	case n != len(data):
+		d.lastErr = fmt.Errorf("incomplete write: %d/%d bytes", n, len(data))
+	default:
+		d.lastErr = nil
 	}
 
-	return nil
+	return d.lastErr
 }
@@ -0,0 +1,126 @@
+package uinput
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GamepadAxis describes an analog axis of the gamepad profile.
+// Rest is the value the axis returns to when released.
+type GamepadAxis struct {
+	Code uint16
+	Min  int32
+	Max  int32
+	Rest int32
+
+	fuzz int32
+	flat int32
+}
+
+// Stick and trigger ranges of the gamepad profile (as reported by xpad)
+const (
+	stickMin   = -32768
+	stickMax   = 32767
+	triggerMax = 255
+)
+
+// GamepadAxes maps axis names to the analog axes of the gamepad profile.
+// Sticks range from -32768 to 32767 (negative is left/up), triggers from
+// 0 to 255 and the D-pad hat from -1 to 1.
+var GamepadAxes = map[string]GamepadAxis{
+	"left_x":        {Code: AbsX, Min: stickMin, Max: stickMax, fuzz: 16, flat: 128},
+	"left_y":        {Code: AbsY, Min: stickMin, Max: stickMax, fuzz: 16, flat: 128},
+	"right_x":       {Code: AbsRX, Min: stickMin, Max: stickMax, fuzz: 16, flat: 128},
+	"right_y":       {Code: AbsRY, Min: stickMin, Max: stickMax, fuzz: 16, flat: 128},
+	"left_trigger":  {Code: AbsZ, Min: 0, Max: triggerMax},
+	"right_trigger": {Code: AbsRZ, Min: 0, Max: triggerMax},
+	"dpad_x":        {Code: AbsHat0X, Min: -1, Max: 1},
+	"dpad_y":        {Code: AbsHat0Y, Min: -1, Max: 1},
+}
+
+// GamepadButtons maps button names to the buttons of the gamepad profile.
+// Face buttons are named by position to avoid vendor-specific letters.
+var GamepadButtons = map[string]uint16{
+	"south":  BtnSouth,
+	"a":      BtnSouth,
+	"east":   BtnEast,
+	"b":      BtnEast,
+	"north":  BtnNorth,
+	"west":   BtnWest,
+	"tl":     BtnTL,
+	"lb":     BtnTL,
+	"tr":     BtnTR,
+	"rb":     BtnTR,
+	"select": BtnSelect,
+	"back":   BtnSelect,
+	"start":  BtnStart,
+	"mode":   BtnMode,
+	"guide":  BtnMode,
+	"thumbl": BtnThumbL,
+	"thumbr": BtnThumbR,
+}
+
+// LookupGamepadButton resolves a gamepad button name to its button code.
+// Names are case-insensitive and accept the "BTN_" prefix (e.g. "BTN_SOUTH").
+func LookupGamepadButton(name string) (uint16, bool) {
+	name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "btn_")
+	code, ok := GamepadButtons[name]
+	return code, ok
+}
+
+// LookupGamepadAxis resolves a gamepad axis name.
+func LookupGamepadAxis(name string) (GamepadAxis, bool) {
+	axis, ok := GamepadAxes[strings.ToLower(strings.TrimSpace(name))]
+	return axis, ok
+}
+
+// NewGamepad creates and initializes a new virtual gamepad device with
+// face buttons, bumpers, sticks, triggers and a D-pad hat.
+func NewGamepad(ctx context.Context) (*Device, error) {
+	return Create(ctx, Options{Profile: ProfileGamepad})
+}
+
+// enableGamepad enables the buttons and axes of the gamepad profile.
+func enableGamepad(ctx context.Context, d *Device) error {
+	// Enable key events (EV_KEY) for the buttons
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvKey)); err != nil {
+		return fmt.Errorf("set EV_KEY: %w", err)
+	}
+
+	buttons := make([]uint16, 0, len(GamepadButtons))
+	seen := make(map[uint16]bool)
+	for _, btn := range GamepadButtons {
+		if !seen[btn] {
+			seen[btn] = true
+			buttons = append(buttons, btn)
+		}
+	}
+	sort.Slice(buttons, func(i, j int) bool { return buttons[i] < buttons[j] })
+
+	for _, btn := range buttons {
+		if err := d.ioctl(UI_SET_KEYBIT, uintptr(btn)); err != nil {
+			return fmt.Errorf("set button %#x: %w", btn, err)
+		}
+	}
+
+	// Enable absolute events (EV_ABS) for sticks, triggers and the hat
+	if err := d.ioctl(UI_SET_EVBIT, uintptr(EvAbs)); err != nil {
+		return fmt.Errorf("set EV_ABS: %w", err)
+	}
+	for _, axis := range GamepadAxes {
+		info := absInfo{
+			Value:   axis.Rest,
+			Minimum: axis.Min,
+			Maximum: axis.Max,
+			Fuzz:    axis.fuzz,
+			Flat:    axis.flat,
+		}
+		if err := d.enableAbs(axis.Code, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
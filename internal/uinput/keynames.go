@@ -0,0 +1,215 @@
+package uinput
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModifierNames maps modifier names used in chords to their keycodes.
+var ModifierNames = map[string]uint16{
+	"shift":   KeyLeftShift,
+	"lshift":  KeyLeftShift,
+	"rshift":  KeyRightShift,
+	"ctrl":    KeyLeftCtrl,
+	"control": KeyLeftCtrl,
+	"lctrl":   KeyLeftCtrl,
+	"rctrl":   KeyRightCtrl,
+	"alt":     KeyLeftAlt,
+	"lalt":    KeyLeftAlt,
+	"altgr":   KeyRightAlt,
+	"ralt":    KeyRightAlt,
+	"super":   KeyLeftMeta,
+	"meta":    KeyLeftMeta,
+	"win":     KeyLeftMeta,
+	"lsuper":  KeyLeftMeta,
+	"rsuper":  KeyRightMeta,
+}
+
+// KeyNames maps key names used in chords to their keycodes.
+// Names are lowercase; LookupKey also accepts the "KEY_" prefix
+// from <linux/input-event-codes.h> (e.g. "KEY_ENTER").
+var KeyNames = map[string]uint16{
+	// Letters
+	"a": KeyA, "b": KeyB, "c": KeyC, "d": KeyD, "e": KeyE, "f": KeyF,
+	"g": KeyG, "h": KeyH, "i": KeyI, "j": KeyJ, "k": KeyK, "l": KeyL,
+	"m": KeyM, "n": KeyN, "o": KeyO, "p": KeyP, "q": KeyQ, "r": KeyR,
+	"s": KeyS, "t": KeyT, "u": KeyU, "v": KeyV, "w": KeyW, "x": KeyX,
+	"y": KeyY, "z": KeyZ,
+
+	// Number row
+	"1": Key1, "2": Key2, "3": Key3, "4": Key4, "5": Key5,
+	"6": Key6, "7": Key7, "8": Key8, "9": Key9, "0": Key0,
+
+	// Punctuation keys (named after their US QWERTY legend)
+	"minus":      KeyMinus,
+	"equal":      KeyEqual,
+	"leftbrace":  KeyLeftBrace,
+	"rightbrace": KeyRightBrace,
+	"semicolon":  KeySemicolon,
+	"apostrophe": KeyApostrophe,
+	"grave":      KeyGrave,
+	"backslash":  KeyBackslash,
+	"comma":      KeyComma,
+	"dot":        KeyDot,
+	"period":     KeyDot,
+	"slash":      KeySlash,
+	"102nd":      Key102ND,
+
+	// Editing and whitespace
+	"esc":       KeyEsc,
+	"escape":    KeyEsc,
+	"enter":     KeyEnter,
+	"return":    KeyEnter,
+	"tab":       KeyTab,
+	"space":     KeySpace,
+	"backspace": KeyBackspace,
+	"delete":    KeyDelete,
+	"del":       KeyDelete,
+	"insert":    KeyInsert,
+	"ins":       KeyInsert,
+
+	// Navigation
+	"up":       KeyUp,
+	"down":     KeyDown,
+	"left":     KeyLeft,
+	"right":    KeyRight,
+	"home":     KeyHome,
+	"end":      KeyEnd,
+	"pageup":   KeyPageUp,
+	"pgup":     KeyPageUp,
+	"pagedown": KeyPageDown,
+	"pgdn":     KeyPageDown,
+
+	// Locks and system keys
+	"capslock":   KeyCapsLock,
+	"numlock":    KeyNumLock,
+	"scrolllock": KeyScrollLock,
+	"sysrq":      KeySysRq,
+	"print":      KeySysRq,
+	"pause":      KeyPause,
+	"menu":       KeyCompose,
+	"compose":    KeyCompose,
+	"kpenter":    KeyKPEnter,
+	"kpasterisk": KeyKPAsterisk,
+
+	// Function keys
+	"f1": KeyF1, "f2": KeyF2, "f3": KeyF3, "f4": KeyF4,
+	"f5": KeyF5, "f6": KeyF6, "f7": KeyF7, "f8": KeyF8,
+	"f9": KeyF9, "f10": KeyF10, "f11": KeyF11, "f12": KeyF12,
+	"f13": KeyF13, "f14": KeyF14, "f15": KeyF15, "f16": KeyF16,
+	"f17": KeyF17, "f18": KeyF18, "f19": KeyF19, "f20": KeyF20,
+	"f21": KeyF21, "f22": KeyF22, "f23": KeyF23, "f24": KeyF24,
+
+	// Media
+	"mute":       KeyMute,
+	"volumedown": KeyVolumeDown,
+	"volumeup":   KeyVolumeUp,
+	"playpause":  KeyPlayPause,
+	"nextsong":   KeyNextSong,
+	"prevsong":   KeyPrevSong,
+}
+
+// ButtonNames maps mouse button names to their button codes.
+var ButtonNames = map[string]uint16{
+	"left":    BtnLeft,
+	"right":   BtnRight,
+	"middle":  BtnMiddle,
+	"side":    BtnSide,
+	"extra":   BtnExtra,
+	"forward": BtnForward,
+	"back":    BtnBack,
+	"task":    BtnTask,
+}
+
+// LookupButton resolves a mouse button name to its button code.
+// Names are case-insensitive and accept the "BTN_" prefix (e.g. "BTN_LEFT").
+func LookupButton(name string) (uint16, bool) {
+	name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "btn_")
+	code, ok := ButtonNames[name]
+	return code, ok
+}
+
+// Chord is a key combination such as Ctrl+Shift+T.
+// Modifiers are pressed first in order, then keys in order; everything is
+// released in reverse order so that presses and releases nest correctly.
+type Chord struct {
+	Modifiers []uint16
+	Keys      []uint16
+}
+
+// keyNamesByCode maps keycodes to their shortest name (the first one in
+// alphabetical order on ties), so that reverse lookups are stable.
+var keyNamesByCode = func() map[uint16]string {
+	names := make(map[uint16]string, len(KeyNames)+len(ModifierNames))
+	for _, table := range []map[string]uint16{KeyNames, ModifierNames} {
+		for name, code := range table {
+			cur, ok := names[code]
+			if !ok || len(name) < len(cur) || (len(name) == len(cur) && name < cur) {
+				names[code] = name
+			}
+		}
+	}
+	return names
+}()
+
+// KeyName returns the name of a key or modifier keycode, as accepted by
+// LookupKey, or "" if the keycode has no name.
+func KeyName(code uint16) string {
+	return keyNamesByCode[code]
+}
+
+// normalizeKeyName lowercases a key name and strips the "KEY_" prefix.
+func normalizeKeyName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.TrimPrefix(name, "key_")
+}
+
+// LookupKey resolves a key or modifier name to its keycode.
+func LookupKey(name string) (uint16, bool) {
+	name = normalizeKeyName(name)
+	if code, ok := ModifierNames[name]; ok {
+		return code, true
+	}
+	code, ok := KeyNames[name]
+	return code, ok
+}
+
+// ParseChord parses a chord such as "ctrl+shift+t" or "super+enter".
+// Names are case-insensitive. A chord made only of modifiers (e.g. "super")
+// is valid and taps them.
+func ParseChord(chord string) (*Chord, error) {
+	if strings.TrimSpace(chord) == "" {
+		return nil, fmt.Errorf("empty chord")
+	}
+
+	c := &Chord{}
+	for _, part := range strings.Split(chord, "+") {
+		name := normalizeKeyName(part)
+		if name == "" {
+			return nil, fmt.Errorf("invalid chord %q: empty key name", chord)
+		}
+
+		if code, ok := ModifierNames[name]; ok {
+			if len(c.Keys) > 0 {
+				return nil, fmt.Errorf("invalid chord %q: modifier %q after key", chord, part)
+			}
+			c.Modifiers = append(c.Modifiers, code)
+			continue
+		}
+
+		code, ok := KeyNames[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid chord %q: unknown key %q", chord, strings.TrimSpace(part))
+		}
+		c.Keys = append(c.Keys, code)
+	}
+
+	return c, nil
+}
+
+// Keycodes returns every keycode of the chord in press order.
+func (c *Chord) Keycodes() []uint16 {
+	codes := make([]uint16, 0, len(c.Modifiers)+len(c.Keys))
+	codes = append(codes, c.Modifiers...)
+	return append(codes, c.Keys...)
+}
@@ -0,0 +1,134 @@
+package uinput
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		chord         string
+		wantModifiers []uint16
+		wantKeys      []uint16
+		wantErr       bool
+	}{
+		{chord: "ctrl+shift+t", wantModifiers: []uint16{KeyLeftCtrl, KeyLeftShift}, wantKeys: []uint16{KeyT}},
+		{chord: "super+enter", wantModifiers: []uint16{KeyLeftMeta}, wantKeys: []uint16{KeyEnter}},
+		{chord: "ctrl+alt+delete", wantModifiers: []uint16{KeyLeftCtrl, KeyLeftAlt}, wantKeys: []uint16{KeyDelete}},
+		{chord: "Ctrl + Alt + F2", wantModifiers: []uint16{KeyLeftCtrl, KeyLeftAlt}, wantKeys: []uint16{KeyF2}},
+		{chord: "KEY_ENTER", wantKeys: []uint16{KeyEnter}},
+		{chord: "altgr+e", wantModifiers: []uint16{KeyRightAlt}, wantKeys: []uint16{KeyE}},
+		{chord: "super", wantModifiers: []uint16{KeyLeftMeta}},
+		{chord: "1", wantKeys: []uint16{Key1}},
+		{chord: "", wantErr: true},
+		{chord: "ctrl+", wantErr: true},
+		{chord: "ctrl+nosuchkey", wantErr: true},
+		{chord: "t+ctrl", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.chord, func(t *testing.T) {
+			chord, err := ParseChord(tt.chord)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChord(%q) error = %v, wantErr %v", tt.chord, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(chord.Modifiers, tt.wantModifiers) {
+				t.Errorf("Modifiers = %v, want %v", chord.Modifiers, tt.wantModifiers)
+			}
+			if !reflect.DeepEqual(chord.Keys, tt.wantKeys) {
+				t.Errorf("Keys = %v, want %v", chord.Keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want uint16
+		ok   bool
+	}{
+		{name: "enter", want: KeyEnter, ok: true},
+		{name: "KEY_ESC", want: KeyEsc, ok: true},
+		{name: "shift", want: KeyLeftShift, ok: true},
+		{name: "F24", want: KeyF24, ok: true},
+		{name: "bogus", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LookupKey(tt.name)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("LookupKey(%q) = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	tests := []struct {
+		code uint16
+		want string
+	}{
+		{code: KeyA, want: "a"},
+		{code: KeyEnter, want: "enter"}, // Not "return"
+		{code: KeyEsc, want: "esc"},     // Not "escape"
+		{code: KeyDot, want: "dot"},     // Not "period"
+		{code: KeyLeftShift, want: "shift"},
+		{code: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := KeyName(tt.code); got != tt.want {
+			t.Errorf("KeyName(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestLookupButton(t *testing.T) {
+	tests := []struct {
+		name string
+		want uint16
+		ok   bool
+	}{
+		{name: "left", want: BtnLeft, ok: true},
+		{name: "Right", want: BtnRight, ok: true},
+		{name: "BTN_MIDDLE", want: BtnMiddle, ok: true},
+		{name: "wheel", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LookupButton(tt.name)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("LookupButton(%q) = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGamepadProfile(t *testing.T) {
+	if code, ok := LookupGamepadButton("BTN_SOUTH"); !ok || code != BtnSouth {
+		t.Errorf("LookupGamepadButton(BTN_SOUTH) = %d, %v", code, ok)
+	}
+	if _, ok := LookupGamepadButton("x"); ok {
+		t.Error("Expected ambiguous letter x to be rejected")
+	}
+
+	// Every axis needs its own code and a rest value within range
+	codes := make(map[uint16]string)
+	for name, axis := range GamepadAxes {
+		if other, dup := codes[axis.Code]; dup {
+			t.Errorf("Axes %s and %s share code %#x", name, other, axis.Code)
+		}
+		codes[axis.Code] = name
+
+		if axis.Rest < axis.Min || axis.Rest > axis.Max {
+			t.Errorf("Axis %s rest %d outside %d..%d", name, axis.Rest, axis.Min, axis.Max)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/bnema/uinputd-go/internal/protocol"
@@ -23,11 +25,27 @@ import (
 //	defer client.Close()
 //
 //	err = client.TypeText(ctx, "Hello, World!", nil)
+//
+// A Client keeps a single connection to the daemon open for its whole
+// lifetime and sends every command over it. If the daemon closed the
+// connection since the last command (e.g. after its idle timeout), the
+// next command transparently dials a new one. A command whose connection
+// breaks while it runs fails, and the one after it dials again.
 type Client struct {
 	socketPath string
-	mu         sync.Mutex
-	conn       net.Conn
 	timeout    time.Duration
+	device     string // Target device name, empty for the daemon's built-in devices
+	priority   int    // Queue priority of the commands
+	*connState
+}
+
+// connState is the connection shared by a client and its device views.
+type connState struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	enc    *json.Encoder
+	dec    *json.Decoder
+	nextID uint64
 }
 
 // Options contains optional configuration for the client.
@@ -42,6 +60,12 @@ type TypeOptions struct {
 	// Use the constants from layouts package (layouts.NameUS, layouts.NameFR, etc.)
 	// If empty, uses the daemon's default layout
 	Layout string
+	// Fallback selects how characters missing from the layout are typed
+	// ("none" or "unicode_hex"). If empty, uses the daemon's default
+	Fallback string
+	// Strict makes the daemon type nothing, and return an
+	// *UnsupportedCharsError, if any character would be dropped
+	Strict bool
 }
 
 // StreamOptions contains options for streaming text.
@@ -49,6 +73,13 @@ type StreamOptions struct {
 	// Layout specifies the keyboard layout
 	// Use the constants from layouts package (layouts.NameUS, layouts.NameFR, etc.)
 	Layout string
+	// Fallback selects how characters missing from the layout are typed
+	// ("none" or "unicode_hex"). If empty, uses the daemon's default
+	Fallback string
+	// Strict makes the daemon type nothing, and return an
+	// *UnsupportedCharsError, if any character would be dropped.
+	// Ignored by OpenStream
+	Strict bool
 	// DelayMs is the delay between words in milliseconds
 	DelayMs int
 	// CharDelay is the delay between characters in milliseconds
@@ -80,23 +111,55 @@ func New(socketPath string, opts *Options) (*Client, error) {
 	c := &Client{
 		socketPath: socketPath,
 		timeout:    opts.Timeout,
+		connState:  &connState{},
 	}
 
 	return c, nil
 }
 
+// Device returns a view of the client whose commands target the named
+// device declared in the daemon's devices.extra config. The view shares
+// the client's connection: closing either closes both.
+//
+// Example:
+//
+//	macros := client.Device("macros")
+//	err := macros.SendChord(ctx, "ctrl+alt+m")
+func (c *Client) Device(name string) *Client {
+	view := *c
+	view.device = name
+	return &view
+}
+
+// WithPriority returns a view of the client whose commands jump ahead of
+// those with a lower priority (0 by default) while they wait for the
+// daemon's devices. Commands run one at a time, so an urgent chord would
+// otherwise wait for every text queued before it. The view shares the
+// client's connection: closing either closes both.
+//
+// Example:
+//
+//	urgent := client.WithPriority(10)
+//	err := urgent.SendChord(ctx, "ctrl+c")
+func (c *Client) WithPriority(priority int) *Client {
+	view := *c
+	view.priority = priority
+	return &view
+}
+
 // NewDefault creates a client with the default socket path.
 func NewDefault() (*Client, error) {
 	return New("/run/uinputd.sock", nil)
 }
 
 // connect establishes a connection to the daemon.
+// The caller must hold c.mu.
 func (c *Client) connect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.conn != nil {
-		return nil // Already connected
+		if !closedByPeer(c.conn) {
+			return nil // Already connected
+		}
+		c.disconnect() // Dropped by the daemon since the last command
 	}
 
 	conn, err := net.DialTimeout("unix", c.socketPath, c.timeout)
@@ -105,80 +168,137 @@ func (c *Client) connect() error {
 	}
 
 	c.conn = conn
+	c.enc = json.NewEncoder(conn)
+	c.dec = json.NewDecoder(conn)
 	return nil
 }
 
+// closedByPeer reports whether the other end closed conn, which is
+// otherwise only noticed once a command has been sent over it. The socket
+// is peeked at without blocking or consuming anything.
+func closedByPeer(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	raw.Read(func(fd uintptr) bool {
+		var b [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), b[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == nil:
+			closed = n == 0 // EOF
+		case err != syscall.EAGAIN && err != syscall.EINTR:
+			closed = true // e.g. ECONNRESET
+		}
+		return true
+	})
+	return closed
+}
+
 // disconnect closes the connection to the daemon.
+// The caller must hold c.mu.
 func (c *Client) disconnect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.conn != nil {
-		err := c.conn.Close()
-		c.conn = nil
-		return err
+	if c.conn == nil {
+		return nil
 	}
 
-	return nil
+	err := c.conn.Close()
+	c.conn = nil
+	c.enc = nil
+	c.dec = nil
+	return err
 }
 
-// sendCommand sends a command to the daemon and returns the response.
+// sendCommand sends a command to the daemon and waits for its response.
+// The client timeout applies unless ctx carries its own deadline.
 func (c *Client) sendCommand(ctx context.Context, cmdType protocol.CommandType, payload interface{}) error {
+	_, err := c.roundTrip(ctx, cmdType, payload, c.timeout)
+	return err
+}
+
+// roundTrip sends a command and waits for its response.
+// A zero timeout waits as long as ctx allows, for commands such as
+// stream_close that only answer once all queued text has been typed.
+// Failed commands return a *DaemonError, or a more specific error such as
+// *UnsupportedCharsError.
+func (c *Client) roundTrip(ctx context.Context, cmdType protocol.CommandType, payload interface{}, timeout time.Duration) (*protocol.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Connect if not already connected
 	if err := c.connect(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set deadline based on context or timeout
 	var deadline time.Time
 	if d, ok := ctx.Deadline(); ok {
 		deadline = d
-	} else {
-		deadline = time.Now().Add(c.timeout)
+	} else if timeout > 0 {
+		deadline = time.Now().Add(timeout)
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if err := c.conn.SetDeadline(deadline); err != nil {
-		return fmt.Errorf("failed to set deadline: %w", err)
+		c.disconnect()
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
 	}
 
+	// Abort blocking I/O when ctx is cancelled
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
 	// Marshal payload
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	// Create command
+	c.nextID++
 	cmd := protocol.Command{
-		Type:    cmdType,
-		Payload: payloadBytes,
+		ID:       strconv.FormatUint(c.nextID, 10),
+		Device:   c.device,
+		Priority: c.priority,
+		Type:     cmdType,
+		Payload:  payloadBytes,
 	}
 
 	// Send command
-	if err := json.NewEncoder(c.conn).Encode(&cmd); err != nil {
-		c.conn = nil // Connection broken, force reconnect next time
-		return fmt.Errorf("failed to send command: %w", err)
+	if err := c.enc.Encode(&cmd); err != nil {
+		c.disconnect() // Connection broken, force reconnect next time
+		return nil, fmt.Errorf("failed to send command: %w", err)
 	}
 
 	// Read response
 	var resp protocol.Response
-	if err := json.NewDecoder(c.conn).Decode(&resp); err != nil {
-		c.conn = nil // Connection broken
-		return fmt.Errorf("failed to read response: %w", err)
+	if err := c.dec.Decode(&resp); err != nil {
+		c.disconnect() // Connection broken
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.ID != "" && resp.ID != cmd.ID {
+		c.disconnect() // Out of sync with the daemon
+		return nil, fmt.Errorf("response id mismatch: sent %s, got %s", cmd.ID, resp.ID)
 	}
 
 	// Check for errors
 	if !resp.Success {
-		return fmt.Errorf("daemon error: %s", resp.Error)
+		return &resp, responseError(&resp)
 	}
 
-	return nil
+	return &resp, nil
 }
 
 // TypeText types the given text using the specified layout.
 // This is a batch operation - all text is sent at once.
+// Use Type to find out which characters were typed.
 //
 // Example:
 //
@@ -186,20 +306,68 @@ func (c *Client) sendCommand(ctx context.Context, cmdType protocol.CommandType,
 //	    Layout: "us",
 //	})
 func (c *Client) TypeText(ctx context.Context, text string, opts *TypeOptions) error {
+	_, err := c.Type(ctx, text, opts)
+	return err
+}
+
+// Type types the given text like TypeText and reports how it was typed.
+//
+// Example:
+//
+//	res, err := client.Type(ctx, "→ ok", &client.TypeOptions{Strict: true})
+//	var unsupported *client.UnsupportedCharsError
+//	if errors.As(err, &unsupported) {
+//	    log.Printf("nothing typed, %s cannot type %v", unsupported.Layout, unsupported.Chars)
+//	}
+func (c *Client) Type(ctx context.Context, text string, opts *TypeOptions) (*TypeResult, error) {
 	if opts == nil {
 		opts = &TypeOptions{}
 	}
 
 	payload := protocol.TypePayload{
-		Text:   text,
-		Layout: opts.Layout,
+		Text:     text,
+		Layout:   opts.Layout,
+		Fallback: opts.Fallback,
+		Strict:   opts.Strict,
+	}
+
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Type, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newTypeResult(resp), nil
+}
+
+// Plan asks the daemon which keystrokes it would send to type text with
+// the given options, without typing anything. Strict is ignored.
+//
+// Example:
+//
+//	plan, err := client.Plan(ctx, "Ôk", &client.TypeOptions{Layout: "fr"})
+//	for _, step := range plan.Steps {
+//	    fmt.Println(step.CharPosition, step.Method, step.Keys)
+//	}
+func (c *Client) Plan(ctx context.Context, text string, opts *TypeOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &TypeOptions{}
+	}
+
+	payload := protocol.PlanPayload{
+		Text:     text,
+		Layout:   opts.Layout,
+		Fallback: opts.Fallback,
 	}
 
-	return c.sendCommand(ctx, protocol.CommandType_Type, payload)
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Plan, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(resp), nil
 }
 
 // StreamText streams text with configurable delays.
 // This allows for more natural-looking typing with delays between words/characters.
+// Use Stream to find out which characters were typed.
 //
 // Example:
 //
@@ -209,6 +377,12 @@ func (c *Client) TypeText(ctx context.Context, text string, opts *TypeOptions) e
 //	    CharDelay: 10,  // 10ms between characters
 //	})
 func (c *Client) StreamText(ctx context.Context, text string, opts *StreamOptions) error {
+	_, err := c.Stream(ctx, text, opts)
+	return err
+}
+
+// Stream streams text like StreamText and reports how it was typed.
+func (c *Client) Stream(ctx context.Context, text string, opts *StreamOptions) (*TypeResult, error) {
 	if opts == nil {
 		opts = &StreamOptions{}
 	}
@@ -216,14 +390,21 @@ func (c *Client) StreamText(ctx context.Context, text string, opts *StreamOption
 	payload := protocol.StreamPayload{
 		Text:      text,
 		Layout:    opts.Layout,
+		Fallback:  opts.Fallback,
+		Strict:    opts.Strict,
 		DelayMs:   opts.DelayMs,
 		CharDelay: opts.CharDelay,
 	}
 
-	return c.sendCommand(ctx, protocol.CommandType_Stream, payload)
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Stream, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newTypeResult(resp), nil
 }
 
 // SendKey sends a single keypress with an optional modifier.
+// Use SendChord for named keys and combinations of several modifiers.
 //
 // Example:
 //
@@ -241,6 +422,52 @@ func (c *Client) SendKey(ctx context.Context, keycode uint16, modifier KeyModifi
 	return c.sendCommand(ctx, protocol.CommandType_Key, payload)
 }
 
+// SendChord sends a key combination using key names, such as
+// "ctrl+shift+t", "super+enter" or "ctrl+alt+delete".
+// Modifiers are pressed in order before the keys and released in reverse.
+//
+// Example:
+//
+//	// Reopen the last closed browser tab
+//	err := client.SendChord(ctx, "ctrl+shift+t")
+func (c *Client) SendChord(ctx context.Context, chord string) error {
+	payload := protocol.KeyPayload{
+		Chord: chord,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_Key, payload)
+}
+
+// KeyDown presses and holds keys until KeyUp is called.
+// keys is a key name or chord such as "shift" or "ctrl+a".
+//
+// Held keys belong to the client's connection: the daemon releases them
+// automatically when the client is closed or its connection drops.
+//
+// Example:
+//
+//	// Push-to-talk
+//	err := client.KeyDown(ctx, "f13")
+//	// ... record ...
+//	err = client.KeyUp(ctx, "f13")
+func (c *Client) KeyDown(ctx context.Context, keys string) error {
+	payload := protocol.KeyHoldPayload{
+		Chord: keys,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_KeyDown, payload)
+}
+
+// KeyUp releases keys previously held with KeyDown.
+// Chord keys are released in reverse order.
+func (c *Client) KeyUp(ctx context.Context, keys string) error {
+	payload := protocol.KeyHoldPayload{
+		Chord: keys,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_KeyUp, payload)
+}
+
 // Ping checks if the daemon is responsive.
 // Returns nil if the daemon responds successfully.
 //
@@ -256,6 +483,8 @@ func (c *Client) Ping(ctx context.Context) error {
 // Close closes the connection to the daemon.
 // Should be called when the client is no longer needed.
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.disconnect()
 }
 
@@ -3,7 +3,11 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 type mockServer struct {
 	listener net.Listener
 	handler  func(protocol.Command) protocol.Response
+	accepted atomic.Int32
 }
 
 func newMockServer(t *testing.T, handler func(protocol.Command) protocol.Response) *mockServer {
@@ -39,6 +44,7 @@ func (ms *mockServer) serve() {
 			return // Server closed
 		}
 
+		ms.accepted.Add(1)
 		go ms.handleConnection(conn)
 	}
 }
@@ -46,13 +52,21 @@ func (ms *mockServer) serve() {
 func (ms *mockServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
-	var cmd protocol.Command
-	if err := json.NewDecoder(conn).Decode(&cmd); err != nil {
-		return
-	}
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		var cmd protocol.Command
+		if err := decoder.Decode(&cmd); err != nil {
+			return
+		}
 
-	resp := ms.handler(cmd)
-	json.NewEncoder(conn).Encode(resp)
+		resp := ms.handler(cmd)
+		resp.ID = cmd.ID
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
 }
 
 func (ms *mockServer) close() {
@@ -310,6 +324,123 @@ func TestClient_DaemonError(t *testing.T) {
 	if err != nil && err.Error() != "daemon error: test error from daemon" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
+
+	var daemonErr *DaemonError
+	if !errors.As(err, &daemonErr) || daemonErr.Message != "test error from daemon" {
+		t.Errorf("Expected *DaemonError, got %T: %v", err, err)
+	}
+}
+
+func TestClient_PolicyErrors(t *testing.T) {
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		if cmd.Type == protocol.CommandType_Ping {
+			return protocol.Response{Success: false, Code: protocol.ErrorCode_RateLimited, Error: "rate limit exceeded for rule slow"}
+		}
+		return protocol.Response{Success: false, Code: protocol.ErrorCode_PermissionDenied, Error: "permission denied: rule typist does not allow type commands"}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.TypeText(ctx, "test", nil); !errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("TypeText() error = %v, want ErrPermissionDenied", err)
+	}
+	if err := client.Ping(ctx); !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Ping() error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestClient_TypeResult(t *testing.T) {
+	var received protocol.TypePayload
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		json.Unmarshal(cmd.Payload, &received)
+		return protocol.Response{
+			Success: true,
+			Layout:  "us",
+			Result: &protocol.TypeResult{
+				Typed:     3,
+				Fallback:  []protocol.CharReport{{Char: "→", Position: 1}},
+				Dropped:   []protocol.CharReport{{Char: "\x07", Position: 3}},
+				ElapsedMs: 12,
+			},
+		}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	res, err := client.Type(context.Background(), "a→b\x07", &TypeOptions{Fallback: "unicode_hex"})
+	if err != nil {
+		t.Fatalf("Type() error = %v", err)
+	}
+
+	if received.Fallback != "unicode_hex" {
+		t.Errorf("Expected fallback %q, got %q", "unicode_hex", received.Fallback)
+	}
+
+	want := &TypeResult{
+		Layout:   "us",
+		Typed:    3,
+		Fallback: []CharPosition{{Char: '→', Position: 1}},
+		Dropped:  []CharPosition{{Char: '\x07', Position: 3}},
+		Elapsed:  12 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Type() = %+v, want %+v", res, want)
+	}
+}
+
+func TestClient_StrictUnsupportedChars(t *testing.T) {
+	var received protocol.TypePayload
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		json.Unmarshal(cmd.Payload, &received)
+		return protocol.Response{
+			Success: false,
+			Error:   "strict mode: 1 characters not supported by layout us",
+			Code:    protocol.ErrorCode_UnsupportedChars,
+			Layout:  "us",
+			Result: &protocol.TypeResult{
+				Dropped: []protocol.CharReport{{Char: "é", Position: 2}},
+			},
+		}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	res, err := client.Type(context.Background(), "a é", &TypeOptions{Strict: true})
+	if res != nil {
+		t.Errorf("Expected no result, got %+v", res)
+	}
+	if !received.Strict {
+		t.Error("Expected strict payload")
+	}
+
+	var unsupported *UnsupportedCharsError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("Expected *UnsupportedCharsError, got %T: %v", err, err)
+	}
+
+	want := []CharPosition{{Char: 'é', Position: 2}}
+	if unsupported.Layout != "us" || !reflect.DeepEqual(unsupported.Chars, want) {
+		t.Errorf("Unexpected error %+v", unsupported)
+	}
+	if err.Error() != `layout us cannot type 'é'@2` {
+		t.Errorf("Unexpected error message: %v", err)
+	}
 }
 
 func TestClient_ContextTimeout(t *testing.T) {
@@ -366,3 +497,769 @@ func TestClient_IsConnected(t *testing.T) {
 		t.Error("Client should not be connected after Close")
 	}
 }
+
+func TestClient_ReusesConnection(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, cmd.ID)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := client.TypeText(ctx, "a", nil); err != nil {
+			t.Fatalf("TypeText() #%d error = %v", i, err)
+		}
+	}
+
+	if n := server.accepted.Load(); n != 1 {
+		t.Errorf("Expected 1 connection, got %d", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			t.Errorf("Expected unique non-empty command IDs, got %v", ids)
+			break
+		}
+		seen[id] = true
+	}
+}
+
+func TestClient_RedialsDroppedConnection(t *testing.T) {
+	listener, err := net.Listen("unix", t.TempDir()+"/test.sock")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer listener.Close()
+
+	// The daemon drops every connection after one command, like its idle
+	// timeout would
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var cmd protocol.Command
+			if err := json.NewDecoder(conn).Decode(&cmd); err == nil {
+				json.NewEncoder(conn).Encode(protocol.Response{ID: cmd.ID, Success: true})
+			}
+			conn.Close()
+		}
+	}()
+
+	client, err := New(listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping() #%d error = %v", i, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClient_ResponseIDMismatch(t *testing.T) {
+	listener, err := net.Listen("unix", t.TempDir()+"/test.sock")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var cmd protocol.Command
+		if err := json.NewDecoder(conn).Decode(&cmd); err != nil {
+			return
+		}
+		json.NewEncoder(conn).Encode(protocol.Response{ID: "bogus", Success: true})
+	}()
+
+	client, err := New(listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected error for mismatched response ID, got nil")
+	}
+
+	if client.IsConnected() {
+		t.Error("Client should drop the connection after an ID mismatch")
+	}
+}
+
+func TestClient_OpenStream(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		types  []protocol.CommandType
+		chunks []string
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+
+		types = append(types, cmd.Type)
+		if cmd.Type == protocol.CommandType_StreamChunk {
+			var p protocol.StreamChunkPayload
+			if err := json.Unmarshal(cmd.Payload, &p); err != nil {
+				return protocol.Response{Success: false, Error: err.Error()}
+			}
+			chunks = append(chunks, p.Text)
+		}
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	w, err := client.OpenStream(context.Background(), &StreamOptions{Layout: "fr"})
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+
+	// "é" is split across two writes and must arrive as one rune
+	for _, part := range [][]byte{[]byte("caf"), {0xc3}, {0xa9, ' '}} {
+		if _, err := w.Write(part); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := w.Write([]byte("late")); err != ErrStreamClosed {
+		t.Errorf("Write() after Close error = %v, want %v", err, ErrStreamClosed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	wantTypes := []protocol.CommandType{
+		protocol.CommandType_StreamOpen,
+		protocol.CommandType_StreamChunk,
+		protocol.CommandType_StreamChunk,
+		protocol.CommandType_StreamFlush,
+		protocol.CommandType_StreamClose,
+	}
+	if len(types) != len(wantTypes) {
+		t.Fatalf("Expected commands %v, got %v", wantTypes, types)
+	}
+	for i := range wantTypes {
+		if types[i] != wantTypes[i] {
+			t.Errorf("Command %d: expected %v, got %v", i, wantTypes[i], types[i])
+		}
+	}
+
+	if got := chunks[0] + chunks[1]; got != "café " {
+		t.Errorf("Expected streamed text %q, got %q (chunks %q)", "café ", got, chunks)
+	}
+}
+
+func TestClient_SendChord(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received protocol.KeyPayload
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cmd.Type != protocol.CommandType_Key {
+			return protocol.Response{Success: false, Error: "wrong command type"}
+		}
+		if err := json.Unmarshal(cmd.Payload, &received); err != nil {
+			return protocol.Response{Success: false, Error: err.Error()}
+		}
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SendChord(context.Background(), "ctrl+shift+t"); err != nil {
+		t.Fatalf("SendChord() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if received.Chord != "ctrl+shift+t" {
+		t.Errorf("Expected chord %q, got %q", "ctrl+shift+t", received.Chord)
+	}
+}
+
+func TestClient_KeyDownKeyUp(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		types    []protocol.CommandType
+		payloads []protocol.KeyHoldPayload
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var p protocol.KeyHoldPayload
+		if err := json.Unmarshal(cmd.Payload, &p); err != nil {
+			return protocol.Response{Success: false, Error: err.Error()}
+		}
+		types = append(types, cmd.Type)
+		payloads = append(payloads, p)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.KeyDown(ctx, "shift"); err != nil {
+		t.Fatalf("KeyDown() error = %v", err)
+	}
+	if err := client.KeyUp(ctx, "shift"); err != nil {
+		t.Fatalf("KeyUp() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	wantTypes := []protocol.CommandType{protocol.CommandType_KeyDown, protocol.CommandType_KeyUp}
+	if len(types) != 2 || types[0] != wantTypes[0] || types[1] != wantTypes[1] {
+		t.Fatalf("Expected commands %v, got %v", wantTypes, types)
+	}
+
+	for i, p := range payloads {
+		if p.Chord != "shift" {
+			t.Errorf("Command %d: expected chord %q, got %q", i, "shift", p.Chord)
+		}
+	}
+}
+
+func TestClient_Mouse(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []protocol.Command
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, cmd)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	calls := []func() error{
+		func() error { return client.MoveMouse(ctx, 10, -5) },
+		func() error { return client.MoveMouseTo(ctx, 800, 600) },
+		func() error { return client.Click(ctx, ButtonRight, 2) },
+		func() error { return client.MouseDown(ctx, ButtonLeft) },
+		func() error { return client.MouseUp(ctx, ButtonLeft) },
+		func() error { return client.ScrollHiRes(ctx, -60, 0) },
+	}
+	for i, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("Call %d error = %v", i, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(commands) != len(calls) {
+		t.Fatalf("Expected %d commands, got %d", len(calls), len(commands))
+	}
+
+	var move protocol.MouseMovePayload
+	json.Unmarshal(commands[1].Payload, &move)
+	if commands[1].Type != protocol.CommandType_MouseMove || !move.Absolute || move.X != 800 || move.Y != 600 {
+		t.Errorf("Unexpected absolute move: %s %+v", commands[1].Type, move)
+	}
+
+	var click protocol.MouseButtonPayload
+	json.Unmarshal(commands[2].Payload, &click)
+	if commands[2].Type != protocol.CommandType_MouseClick || click.Button != "right" || click.Count != 2 {
+		t.Errorf("Unexpected click: %s %+v", commands[2].Type, click)
+	}
+
+	if commands[3].Type != protocol.CommandType_MouseDown || commands[4].Type != protocol.CommandType_MouseUp {
+		t.Errorf("Expected mouse_down then mouse_up, got %s, %s", commands[3].Type, commands[4].Type)
+	}
+
+	var scroll protocol.MouseScrollPayload
+	json.Unmarshal(commands[5].Payload, &scroll)
+	if !scroll.HiRes || scroll.Vertical != -60 {
+		t.Errorf("Unexpected scroll: %+v", scroll)
+	}
+}
+
+func TestClient_Tap(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []protocol.AbsPointerPayload
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cmd.Type != protocol.CommandType_AbsPointer {
+			return protocol.Response{Success: false, Error: "unexpected command " + string(cmd.Type)}
+		}
+		var p protocol.AbsPointerPayload
+		if err := json.Unmarshal(cmd.Payload, &p); err != nil {
+			return protocol.Response{Success: false, Error: err.Error()}
+		}
+		payloads = append(payloads, p)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Tap(ctx, 0.25, 0.75, &AbsOptions{Normalized: true}); err != nil {
+		t.Fatalf("Tap() error = %v", err)
+	}
+	if err := client.MoveAbsolute(ctx, 100, 200, nil); err != nil {
+		t.Fatalf("MoveAbsolute() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(payloads) != 2 {
+		t.Fatalf("Expected 2 commands, got %d", len(payloads))
+	}
+	if p := payloads[0]; p.Action != "tap" || !p.Normalized || p.X != 0.25 || p.Y != 0.75 {
+		t.Errorf("Unexpected tap payload: %+v", p)
+	}
+	if p := payloads[1]; p.Action != "move" || p.Normalized || p.X != 100 || p.Y != 200 {
+		t.Errorf("Unexpected move payload: %+v", p)
+	}
+}
+
+func TestClient_Gamepad(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []protocol.Command
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, cmd)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.GamepadPress(ctx, "start"); err != nil {
+		t.Fatalf("GamepadPress() error = %v", err)
+	}
+	if err := client.GamepadAxes(ctx, map[string]int32{"left_x": 100}); err != nil {
+		t.Fatalf("GamepadAxes() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %d", len(commands))
+	}
+
+	var button protocol.GamepadButtonPayload
+	json.Unmarshal(commands[0].Payload, &button)
+	if commands[0].Type != protocol.CommandType_GamepadButton || button.Button != "start" || button.Action != "press" {
+		t.Errorf("Unexpected button command: %s %+v", commands[0].Type, button)
+	}
+
+	var axis protocol.GamepadAxisPayload
+	json.Unmarshal(commands[1].Payload, &axis)
+	if commands[1].Type != protocol.CommandType_GamepadAxis || axis.Axes["left_x"] != 100 {
+		t.Errorf("Unexpected axis command: %s %+v", commands[1].Type, axis)
+	}
+}
+
+func TestClient_Device(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		devices []string
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		devices = append(devices, cmd.Device)
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	macros := client.Device("macros")
+	if err := macros.SendChord(ctx, "ctrl+alt+m"); err != nil {
+		t.Fatalf("SendChord() error = %v", err)
+	}
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(devices) != 2 || devices[0] != "macros" || devices[1] != "" {
+		t.Errorf("Expected devices [macros \"\"], got %q", devices)
+	}
+
+	// The view shares its parent's connection
+	if got := server.accepted.Load(); got != 1 {
+		t.Errorf("Expected 1 connection, got %d", got)
+	}
+}
+
+func TestClient_WithPriority(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		priorities []int
+	)
+
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		priorities = append(priorities, cmd.Priority)
+		if cmd.Type == protocol.CommandType_Type {
+			return protocol.Response{Success: false, Code: protocol.ErrorCode_Busy, Error: "command queue full: 100 commands waiting"}
+		}
+		return protocol.Response{Success: true}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.WithPriority(10).SendChord(ctx, "ctrl+c"); err != nil {
+		t.Fatalf("SendChord() error = %v", err)
+	}
+	if err := client.TypeText(ctx, "hello", nil); !errors.Is(err, ErrBusy) || errors.Is(err, ErrCancelled) {
+		t.Errorf("TypeText() error = %v, want ErrBusy", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if want := []int{10, 0}; !reflect.DeepEqual(priorities, want) {
+		t.Errorf("Expected priorities %v, got %v", want, priorities)
+	}
+}
+
+func TestClient_Plan(t *testing.T) {
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		if cmd.Type != protocol.CommandType_Plan {
+			return protocol.Response{Success: false, Error: "wrong command type"}
+		}
+		return protocol.Response{
+			Success: true,
+			Layout:  "fr",
+			Plan: []protocol.PlanStep{
+				{Char: "ô", Position: 0, Method: protocol.PlanMethod_Layout, Keys: []protocol.PlannedKey{
+					{Keycode: 26, Chord: "leftbrace"},
+					{Keycode: 24, Chord: "o"},
+				}},
+				{Char: "\x07", Position: 1, Method: protocol.PlanMethod_Dropped, Error: "not supported"},
+			},
+		}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	plan, err := client.Plan(context.Background(), "ô\x07", &TypeOptions{Layout: "fr"})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	want := &Plan{
+		Layout: "fr",
+		Steps: []PlanStep{
+			{CharPosition: CharPosition{Char: 'ô', Position: 0}, Method: MethodLayout, Keys: []PlannedKey{
+				{Keycode: 26, Chord: "leftbrace"},
+				{Keycode: 24, Chord: "o"},
+			}},
+			{CharPosition: CharPosition{Char: '\x07', Position: 1}, Method: MethodDropped, Error: "not supported"},
+		},
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("Plan() = %+v, want %+v", plan, want)
+	}
+}
+
+func TestClient_StatusCapabilities(t *testing.T) {
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	uid := uint32(1000)
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		switch cmd.Type {
+		case protocol.CommandType_Status:
+			return protocol.Response{Success: true, Status: &protocol.Status{
+				Version:        "1.2.3",
+				StartedAt:      started,
+				UptimeMs:       1500,
+				CommandsServed: 7,
+				Connections:    1,
+				Clients: []protocol.ClientInfo{{
+					Peer:        &protocol.PeerInfo{PID: 42, UID: 1000, GID: 1000, Exe: "/usr/bin/dictate", Cmdline: []string{"dictate", "-v"}},
+					ConnectedAt: started,
+					Commands:    2,
+				}},
+				Devices: []protocol.DeviceStatus{{
+					DeviceInfo: protocol.DeviceInfo{Name: "kbd", Profile: "keyboard", Builtin: true},
+					Created:    true,
+					Error:      "device not open",
+				}},
+				RateLimits: []protocol.RateLimitStatus{
+					{CommandsPerSecond: 100, Commands: 7},
+					{UID: &uid, KeystrokesPerSecond: 50, Commands: 7, Keystrokes: 120, Delayed: 3, Rejected: 1},
+				},
+			}}
+		case protocol.CommandType_Capabilities:
+			return protocol.Response{Success: true, Capabilities: &protocol.Capabilities{
+				Version:       "1.2.3",
+				Commands:      []protocol.CommandType{protocol.CommandType_Type, protocol.CommandType_Plan},
+				DefaultLayout: "fr",
+				Layouts:       []string{"fr", "us"},
+				Limits:        protocol.Limits{MaxMessageSize: 1024},
+			}}
+		default:
+			return protocol.Response{Success: false, Error: "wrong command type"}
+		}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	wantStatus := &Status{
+		Version:        "1.2.3",
+		StartedAt:      started,
+		Uptime:         1500 * time.Millisecond,
+		CommandsServed: 7,
+		Connections:    1,
+		Clients: []ClientInfo{{
+			Peer:        &Peer{PID: 42, UID: 1000, GID: 1000, Exe: "/usr/bin/dictate", Cmdline: []string{"dictate", "-v"}},
+			ConnectedAt: started,
+			Commands:    2,
+		}},
+		Devices: []DeviceStatus{{
+			DeviceInfo: DeviceInfo{Name: "kbd", Profile: "keyboard", Builtin: true},
+			Created:    true,
+			Error:      "device not open",
+		}},
+		RateLimits: []RateLimitStatus{
+			{Global: true, CommandsPerSecond: 100, Commands: 7},
+			{UID: 1000, KeystrokesPerSecond: 50, Commands: 7, Keystrokes: 120, Delayed: 3, Rejected: 1},
+		},
+	}
+	if !reflect.DeepEqual(status, wantStatus) {
+		t.Errorf("Status() = %+v, want %+v", status, wantStatus)
+	}
+
+	caps, err := client.Capabilities(ctx)
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if caps.DefaultLayout != "fr" || caps.Limits.MaxMessageSize != 1024 {
+		t.Errorf("Capabilities() = %+v", caps)
+	}
+	if !caps.Supports("plan") || caps.Supports("mouse_move") {
+		t.Errorf("Supports() mismatch for commands %v", caps.Commands)
+	}
+}
+
+func TestClient_JobsCancel(t *testing.T) {
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		switch cmd.Type {
+		case protocol.CommandType_Jobs:
+			return protocol.Response{Success: true, Jobs: []protocol.JobInfo{
+				{ID: "3", Type: protocol.CommandType_Stream, StartedAt: started},
+			}}
+		case protocol.CommandType_Cancel:
+			var p protocol.CancelPayload
+			json.Unmarshal(cmd.Payload, &p)
+			if p.Job == "4" {
+				return protocol.Response{Success: false, Error: "unknown job: 4"}
+			}
+			return protocol.Response{Success: true, Jobs: []protocol.JobInfo{{ID: "3", Type: protocol.CommandType_Stream}}}
+		case protocol.CommandType_Stream:
+			return protocol.Response{Success: false, Code: protocol.ErrorCode_Cancelled, Error: "job cancelled"}
+		default:
+			return protocol.Response{Success: false, Error: "wrong command type"}
+		}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	jobs, err := client.Jobs(ctx)
+	if err != nil {
+		t.Fatalf("Jobs() error = %v", err)
+	}
+	if want := []Job{{ID: "3", Type: "stream", StartedAt: started}}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("Jobs() = %+v, want %+v", jobs, want)
+	}
+
+	if err := client.Cancel(ctx, "3"); err != nil {
+		t.Errorf("Cancel() error = %v", err)
+	}
+	if err := client.Cancel(ctx, "4"); err == nil || errors.Is(err, ErrCancelled) {
+		t.Errorf("Cancel() of unknown job error = %v", err)
+	}
+	if jobs, err := client.CancelAll(ctx); err != nil || len(jobs) != 1 {
+		t.Errorf("CancelAll() = %v, %v", jobs, err)
+	}
+
+	if err := client.StreamText(ctx, "hello", nil); !errors.Is(err, ErrCancelled) {
+		t.Errorf("StreamText() error = %v, want ErrCancelled", err)
+	}
+}
+
+func TestClient_PauseResume(t *testing.T) {
+	server := newMockServer(t, func(cmd protocol.Command) protocol.Response {
+		var p protocol.JobPayload
+		json.Unmarshal(cmd.Payload, &p)
+		if p.Job != "3" {
+			return protocol.Response{Success: false, Error: "unknown job: " + p.Job}
+		}
+
+		info := protocol.JobInfo{ID: "3", Type: protocol.CommandType_Type, Offset: 5, Total: 20, Percent: 25}
+		switch cmd.Type {
+		case protocol.CommandType_Pause:
+			info.State = protocol.JobState_Paused
+		case protocol.CommandType_Resume:
+			info.State = protocol.JobState_Running
+		default:
+			return protocol.Response{Success: false, Error: "wrong command type"}
+		}
+		return protocol.Response{Success: true, Jobs: []protocol.JobInfo{info}}
+	})
+	defer server.close()
+
+	client, err := New(server.addr(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	job, err := client.Pause(ctx, "3")
+	if err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	want := &Job{ID: "3", Type: "type", State: "paused", Offset: 5, Total: 20, Percent: 25}
+	if !reflect.DeepEqual(job, want) {
+		t.Errorf("Pause() = %+v, want %+v", job, want)
+	}
+	if !job.Paused() {
+		t.Error("Paused() = false, want true")
+	}
+
+	job, err = client.Resume(ctx, "3")
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if job.Paused() {
+		t.Error("Paused() after Resume() = true, want false")
+	}
+
+	if _, err := client.Pause(ctx, "4"); err == nil {
+		t.Error("Pause() of unknown job error = nil")
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// GamepadTap presses and releases a gamepad button such as "south",
+// "east", "start" or "tl". The daemon creates its virtual gamepad on the
+// first gamepad command.
+//
+// Example:
+//
+//	err := client.GamepadTap(ctx, "south")
+func (c *Client) GamepadTap(ctx context.Context, button string) error {
+	return c.gamepadButton(ctx, button, "tap")
+}
+
+// GamepadPress presses and holds a gamepad button until GamepadRelease.
+// Held buttons are released automatically when the client is closed.
+func (c *Client) GamepadPress(ctx context.Context, button string) error {
+	return c.gamepadButton(ctx, button, "press")
+}
+
+// GamepadRelease releases a button previously held with GamepadPress.
+func (c *Client) GamepadRelease(ctx context.Context, button string) error {
+	return c.gamepadButton(ctx, button, "release")
+}
+
+// GamepadAxes sets stick, trigger and D-pad values in a single report.
+// Sticks ("left_x", "left_y", "right_x", "right_y") range from -32768 to
+// 32767, triggers ("left_trigger", "right_trigger") from 0 to 255 and the
+// D-pad ("dpad_x", "dpad_y") from -1 to 1.
+//
+// Axes stay where they are set until changed; the daemon returns them to
+// rest when the client is closed.
+//
+// Example:
+//
+//	// Full throttle, steering right
+//	err := client.GamepadAxes(ctx, map[string]int32{"right_trigger": 255, "left_x": 20000})
+func (c *Client) GamepadAxes(ctx context.Context, axes map[string]int32) error {
+	payload := protocol.GamepadAxisPayload{
+		Axes: axes,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_GamepadAxis, payload)
+}
+
+// gamepadButton sends a gamepad_button command.
+func (c *Client) gamepadButton(ctx context.Context, button, action string) error {
+	payload := protocol.GamepadButtonPayload{
+		Button: button,
+		Action: action,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_GamepadButton, payload)
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// ErrCancelled matches, with errors.Is, the errors of typing commands and
+// stream sessions aborted by a cancel command.
+var ErrCancelled = errors.New("cancelled")
+
+// ErrBusy matches, with errors.Is, the errors of commands rejected because
+// too many commands already wait for the daemon's devices, or because the
+// daemon has too many open connections.
+var ErrBusy = errors.New("busy")
+
+// Job is a typing command running on the daemon: type, stream or an open
+// stream session.
+type Job struct {
+	ID string
+	// Type is the command that started the job, e.g. "stream"
+	Type      string
+	StartedAt time.Time
+	// State is "queued", "running" or "paused"
+	State string
+	// Offset is the number of characters of the text typed so far, out of
+	// Total; stream sessions count the chunks sent so far
+	Offset  int
+	Total   int
+	Percent float64
+	// Peer is the client that started the job, nil if the daemon could not
+	// identify it
+	Peer *Peer
+}
+
+// Peer identifies a client process of the daemon, from the credentials of
+// its socket.
+type Peer struct {
+	PID int32
+	UID uint32
+	GID uint32
+	// Exe is the path of the client's executable and Cmdline its arguments,
+	// empty if the daemon may not read them
+	Exe     string
+	Cmdline []string
+}
+
+// newPeer converts the client identity of a response.
+func newPeer(info *protocol.PeerInfo) *Peer {
+	if info == nil {
+		return nil
+	}
+	p := Peer(*info)
+	return &p
+}
+
+// Paused reports whether the job is paused.
+func (j *Job) Paused() bool {
+	return j.State == protocol.JobState_Paused
+}
+
+// Jobs lists the typing jobs running on the daemon, from every client, in
+// start order.
+func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Jobs, protocol.JobsPayload{}, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newJobs(resp.Jobs), nil
+}
+
+// Cancel aborts the job with the given ID, which may belong to another
+// client. Typing stops before the next character, without leaving a
+// modifier pressed; keys the job's client holds with KeyDown stay held. The
+// job's command fails with ErrCancelled.
+//
+// Example:
+//
+//	if err := client.Cancel(ctx, "3"); err != nil {
+//	    log.Println("job already done:", err)
+//	}
+func (c *Client) Cancel(ctx context.Context, id string) error {
+	return c.sendCommand(ctx, protocol.CommandType_Cancel, protocol.CancelPayload{Job: id})
+}
+
+// Pause pauses the job with the given ID, which may belong to another
+// client, before its next character. The job's command keeps waiting until
+// the job is resumed; a paused stream session even outlives its client.
+// Jobs paused for longer than the daemon's jobs.pause_timeout_ms fail with
+// ErrCancelled.
+//
+// Example:
+//
+//	job, err := client.Pause(ctx, "3")
+//	if err == nil {
+//	    log.Printf("paused at %.0f%%", job.Percent)
+//	}
+func (c *Client) Pause(ctx context.Context, id string) (*Job, error) {
+	return c.jobCommand(ctx, protocol.CommandType_Pause, id)
+}
+
+// Resume resumes the paused job with the given ID where it left off.
+func (c *Client) Resume(ctx context.Context, id string) (*Job, error) {
+	return c.jobCommand(ctx, protocol.CommandType_Resume, id)
+}
+
+// jobCommand sends a command addressing a job and returns the job.
+func (c *Client) jobCommand(ctx context.Context, cmdType protocol.CommandType, id string) (*Job, error) {
+	resp, err := c.roundTrip(ctx, cmdType, protocol.JobPayload{Job: id}, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	jobs := newJobs(resp.Jobs)
+	if len(jobs) != 1 {
+		return nil, fmt.Errorf("daemon did not report job %s", id)
+	}
+	return &jobs[0], nil
+}
+
+// CancelAll aborts every running job, like Cancel, and returns them.
+func (c *Client) CancelAll(ctx context.Context) ([]Job, error) {
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Cancel, protocol.CancelPayload{All: true}, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newJobs(resp.Jobs), nil
+}
+
+// newJobs converts the jobs of a response.
+func newJobs(infos []protocol.JobInfo) []Job {
+	jobs := make([]Job, len(infos))
+	for i, info := range infos {
+		jobs[i] = Job{
+			ID:        info.ID,
+			Type:      string(info.Type),
+			StartedAt: info.StartedAt,
+			State:     info.State,
+			Offset:    info.Offset,
+			Total:     info.Total,
+			Percent:   info.Percent,
+			Peer:      newPeer(info.Peer),
+		}
+	}
+	return jobs
+}
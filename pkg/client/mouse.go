@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// MouseButton identifies a button of the daemon's virtual mouse.
+type MouseButton string
+
+const (
+	ButtonLeft    MouseButton = "left"
+	ButtonRight   MouseButton = "right"
+	ButtonMiddle  MouseButton = "middle"
+	ButtonSide    MouseButton = "side"  // Usually "back" in browsers
+	ButtonExtra   MouseButton = "extra" // Usually "forward" in browsers
+	ButtonForward MouseButton = "forward"
+	ButtonBack    MouseButton = "back"
+)
+
+// MoveMouse moves the pointer by dx, dy.
+// Positive values move right and down. Pointer acceleration of the
+// compositor applies, as for a physical mouse.
+func (c *Client) MoveMouse(ctx context.Context, dx, dy int32) error {
+	payload := protocol.MouseMovePayload{
+		X: dx,
+		Y: dy,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseMove, payload)
+}
+
+// MoveMouseTo moves the pointer to screen coordinates x, y.
+// The daemon emulates this on its relative mouse by pushing the pointer
+// to the top-left corner first, so the result is only pixel-accurate
+// with a flat acceleration profile.
+//
+// Example:
+//
+//	err := client.MoveMouseTo(ctx, 960, 540)
+func (c *Client) MoveMouseTo(ctx context.Context, x, y int32) error {
+	payload := protocol.MouseMovePayload{
+		X:        x,
+		Y:        y,
+		Absolute: true,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseMove, payload)
+}
+
+// Click clicks a mouse button count times (count 2 double-clicks).
+//
+// Example:
+//
+//	err := client.Click(ctx, client.ButtonLeft, 2)
+func (c *Client) Click(ctx context.Context, button MouseButton, count int) error {
+	payload := protocol.MouseButtonPayload{
+		Button: string(button),
+		Count:  count,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseClick, payload)
+}
+
+// MouseDown presses and holds a mouse button until MouseUp is called.
+// Like held keys, held buttons are released automatically by the daemon
+// when the client is closed or its connection drops.
+//
+// Example:
+//
+//	// Drag and drop
+//	err := client.MoveMouseTo(ctx, 100, 100)
+//	err = client.MouseDown(ctx, client.ButtonLeft)
+//	err = client.MoveMouse(ctx, 300, 0)
+//	err = client.MouseUp(ctx, client.ButtonLeft)
+func (c *Client) MouseDown(ctx context.Context, button MouseButton) error {
+	payload := protocol.MouseButtonPayload{
+		Button: string(button),
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseDown, payload)
+}
+
+// MouseUp releases a mouse button previously held with MouseDown.
+func (c *Client) MouseUp(ctx context.Context, button MouseButton) error {
+	payload := protocol.MouseButtonPayload{
+		Button: string(button),
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseUp, payload)
+}
+
+// Scroll scrolls by whole wheel detents.
+// Positive vertical scrolls up, positive horizontal scrolls right.
+func (c *Client) Scroll(ctx context.Context, vertical, horizontal int32) error {
+	payload := protocol.MouseScrollPayload{
+		Vertical:   vertical,
+		Horizontal: horizontal,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseScroll, payload)
+}
+
+// ScrollHiRes scrolls in high-resolution units of 1/120 of a detent,
+// for smooth scrolling in applications that support it.
+func (c *Client) ScrollHiRes(ctx context.Context, vertical, horizontal int32) error {
+	payload := protocol.MouseScrollPayload{
+		Vertical:   vertical,
+		Horizontal: horizontal,
+		HiRes:      true,
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_MouseScroll, payload)
+}
+
+// AbsOptions contains options for the absolute pointer (tablet/touchscreen).
+type AbsOptions struct {
+	// Normalized interprets coordinates as fractions (0-1) of the daemon's
+	// coordinate space instead of pixels
+	Normalized bool
+	// Button to tap with in tablet mode (default: left)
+	Button MouseButton
+}
+
+// MoveAbsolute moves the absolute pointer to x, y.
+// Requires the daemon's absolute device in tablet mode.
+func (c *Client) MoveAbsolute(ctx context.Context, x, y float64, opts *AbsOptions) error {
+	return c.absPointer(ctx, "move", x, y, opts)
+}
+
+// Tap taps (touchscreen) or clicks (tablet) at x, y on the absolute pointer.
+// Unlike MoveMouseTo, the position is exact regardless of pointer
+// acceleration.
+//
+// Example:
+//
+//	// Click the center of the screen
+//	err := client.Tap(ctx, 0.5, 0.5, &client.AbsOptions{Normalized: true})
+func (c *Client) Tap(ctx context.Context, x, y float64, opts *AbsOptions) error {
+	return c.absPointer(ctx, "tap", x, y, opts)
+}
+
+// absPointer sends an abs_pointer command.
+func (c *Client) absPointer(ctx context.Context, action string, x, y float64, opts *AbsOptions) error {
+	if opts == nil {
+		opts = &AbsOptions{}
+	}
+
+	payload := protocol.AbsPointerPayload{
+		X:          x,
+		Y:          y,
+		Normalized: opts.Normalized,
+		Action:     action,
+		Button:     string(opts.Button),
+	}
+
+	return c.sendCommand(ctx, protocol.CommandType_AbsPointer, payload)
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// TypeResult reports how the text of a typing command was typed.
+type TypeResult struct {
+	// Layout is the layout the daemon typed with
+	Layout string
+	// Typed is the number of characters typed, including by fallback
+	Typed int
+	// Fallback lists the characters entered with the fallback strategy
+	Fallback []CharPosition
+	// Dropped lists the characters the layout could not type
+	Dropped []CharPosition
+	// Elapsed is the time the daemon spent typing
+	Elapsed time.Duration
+}
+
+// CharPosition is a character of the typed text and its index, counted in
+// characters (runes) rather than bytes.
+type CharPosition struct {
+	Char     rune
+	Position int
+}
+
+// String returns the character and its position, e.g. `'→'@3`.
+func (p CharPosition) String() string {
+	return fmt.Sprintf("%q@%d", p.Char, p.Position)
+}
+
+// Plan lists the keystrokes the daemon would send to type a text.
+type Plan struct {
+	// Layout is the layout the daemon would type with
+	Layout string
+	// Steps holds one step per character of the text
+	Steps []PlanStep
+}
+
+// How a character is typed, see PlanStep.Method.
+const (
+	MethodLayout   = protocol.PlanMethod_Layout   // With the layout's keys
+	MethodFallback = protocol.PlanMethod_Fallback // With the fallback strategy
+	MethodDropped  = protocol.PlanMethod_Dropped  // Not typed
+)
+
+// PlanStep lists the keystrokes typing one character.
+type PlanStep struct {
+	CharPosition
+	// Method is MethodLayout, MethodFallback or MethodDropped
+	Method string
+	// Keys are the keystrokes in order, e.g. a dead key then the base key
+	Keys []PlannedKey
+	// Error tells why the layout cannot type the character, if it cannot
+	Error string
+}
+
+// PlannedKey is a single keystroke of a plan.
+type PlannedKey struct {
+	Keycode   uint16
+	Modifiers []string // "ctrl", "alt", "shift", "altgr"
+	Chord     string   // e.g. "shift+a"
+}
+
+// newPlan converts the plan of a plan command response.
+func newPlan(resp *protocol.Response) *Plan {
+	plan := &Plan{Layout: resp.Layout, Steps: make([]PlanStep, len(resp.Plan))}
+	for i, step := range resp.Plan {
+		char, _ := utf8.DecodeRuneInString(step.Char)
+		plan.Steps[i] = PlanStep{
+			CharPosition: CharPosition{Char: char, Position: step.Position},
+			Method:       step.Method,
+			Error:        step.Error,
+		}
+		for _, key := range step.Keys {
+			plan.Steps[i].Keys = append(plan.Steps[i].Keys, PlannedKey(key))
+		}
+	}
+	return plan
+}
+
+// ErrPermissionDenied matches, with errors.Is, the errors of commands the
+// daemon's policy does not allow this client.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrRateLimited matches, with errors.Is, the errors of commands over the
+// daemon's rate limits or the one its policy sets for this client. Typing
+// commands may fail with it midway, see TypeResult.
+var ErrRateLimited = errors.New("rate limited")
+
+// DaemonError is returned when the daemon fails to execute a command.
+type DaemonError struct {
+	// Code identifies the failure for errors clients may handle, if any
+	Code    string
+	Message string
+}
+
+func (e *DaemonError) Error() string {
+	return "daemon error: " + e.Message
+}
+
+// Is reports whether the error is ErrCancelled, ErrBusy,
+// ErrPermissionDenied or ErrRateLimited.
+func (e *DaemonError) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return e.Code == protocol.ErrorCode_Cancelled
+	case ErrBusy:
+		return e.Code == protocol.ErrorCode_Busy
+	case ErrPermissionDenied:
+		return e.Code == protocol.ErrorCode_PermissionDenied
+	case ErrRateLimited:
+		return e.Code == protocol.ErrorCode_RateLimited
+	}
+	return false
+}
+
+// UnsupportedCharsError is returned by strict typing commands when the
+// layout cannot type some characters of the text. Nothing was typed.
+type UnsupportedCharsError struct {
+	Layout string
+	Chars  []CharPosition
+}
+
+func (e *UnsupportedCharsError) Error() string {
+	chars := make([]string, len(e.Chars))
+	for i, c := range e.Chars {
+		chars[i] = c.String()
+	}
+	return fmt.Sprintf("layout %s cannot type %s", e.Layout, strings.Join(chars, ", "))
+}
+
+// responseError converts a failure response into an error.
+func responseError(resp *protocol.Response) error {
+	if resp.Code == protocol.ErrorCode_UnsupportedChars && resp.Result != nil {
+		return &UnsupportedCharsError{
+			Layout: resp.Layout,
+			Chars:  charPositions(resp.Result.Dropped),
+		}
+	}
+	return &DaemonError{Code: resp.Code, Message: resp.Error}
+}
+
+// newTypeResult converts the result of a typing command response.
+func newTypeResult(resp *protocol.Response) *TypeResult {
+	res := &TypeResult{Layout: resp.Layout}
+	if resp.Result != nil {
+		res.Typed = resp.Result.Typed
+		res.Fallback = charPositions(resp.Result.Fallback)
+		res.Dropped = charPositions(resp.Result.Dropped)
+		res.Elapsed = time.Duration(resp.Result.ElapsedMs) * time.Millisecond
+	}
+	return res
+}
+
+// charPositions converts reported characters.
+func charPositions(reports []protocol.CharReport) []CharPosition {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	chars := make([]CharPosition, len(reports))
+	for i, r := range reports {
+		char, _ := utf8.DecodeRuneInString(r.Char)
+		chars[i] = CharPosition{Char: char, Position: r.Position}
+	}
+	return chars
+}
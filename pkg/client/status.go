@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// Status is the state of the daemon.
+type Status struct {
+	Version   string
+	StartedAt time.Time
+	Uptime    time.Duration
+	// CommandsServed counts the commands handled since startup, including
+	// the status command itself
+	CommandsServed uint64
+	// Connections is the number of open client connections, described in
+	// Clients
+	Connections int
+	Clients     []ClientInfo
+	// Healthy is false if any created device stopped working
+	Healthy bool
+	Devices []DeviceStatus
+	// Jobs lists the running typing jobs and their progress
+	Jobs []Job
+	// RateLimits reports the daemon's rate limits, if any: the daemon-wide
+	// limits first, then those of each policy rule and each client UID
+	// seen
+	RateLimits []RateLimitStatus
+}
+
+// ClientInfo describes a client connected to the daemon.
+type ClientInfo struct {
+	// Peer is nil if the daemon could not identify the client
+	Peer        *Peer
+	ConnectedAt time.Time
+	// Commands counts the commands sent on the connection
+	Commands uint64
+}
+
+// RateLimitStatus is what a rate limit let through and held back since
+// the daemon started.
+type RateLimitStatus struct {
+	// Global is true for the limits shared by every client, else Rule is
+	// the policy rule or UID the client user they apply to
+	Global bool
+	Rule   string
+	UID    uint32
+	// Limits per second, 0 for no limit
+	CommandsPerSecond   float64
+	KeystrokesPerSecond float64
+	// Commands and keystrokes let through
+	Commands   uint64
+	Keystrokes uint64
+	// Delayed counts commands and keystrokes held back, Rejected the
+	// commands rejected
+	Delayed  uint64
+	Rejected uint64
+}
+
+// DeviceStatus is the health of a virtual device.
+type DeviceStatus struct {
+	DeviceInfo
+	// Created is false for devices created on first use, like the gamepad
+	Created bool
+	Healthy bool
+	// Error tells why the device is unhealthy
+	Error string
+}
+
+// DeviceInfo describes a virtual device of the daemon.
+type DeviceInfo struct {
+	// Name is the name reported to the system; named devices are
+	// targeted with it, see Client.Device
+	Name string
+	// Profile is "keyboard", "pointer", "tablet", "touchscreen" or "gamepad"
+	Profile string
+	// Builtin devices are used by commands that don't target a device
+	Builtin bool
+}
+
+// Capabilities describes what the daemon supports.
+type Capabilities struct {
+	Version string
+	// Commands lists the command types the daemon serves with its devices
+	Commands      []string
+	DefaultLayout string
+	// LayoutDetect is true if the daemon types with the desktop session's
+	// layout when none is given
+	LayoutDetect bool
+	// Layouts lists the loaded layouts; "xkb:..." layouts are also loaded
+	// on demand
+	Layouts         []string
+	Fallbacks       []string
+	DefaultFallback string
+	Devices         []DeviceInfo
+	Limits          Limits
+}
+
+// Limits are the daemon's performance settings.
+type Limits struct {
+	MaxMessageSize    int
+	BufferSize        int
+	MaxConcurrentCmds int
+	StreamDelayMs     int
+	CharDelayMs       int
+}
+
+// Supports reports whether the daemon serves the command type, e.g.
+// "mouse_move" or "plan".
+func (c *Capabilities) Supports(command string) bool {
+	return slices.Contains(c.Commands, command)
+}
+
+// Status returns the state of the daemon: version, uptime, counters and
+// the health of each device.
+//
+// Example:
+//
+//	status, err := client.Status(ctx)
+//	if err == nil && !status.Healthy {
+//	    log.Println("daemon devices unhealthy")
+//	}
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Status, protocol.StatusPayload{}, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == nil {
+		return nil, fmt.Errorf("daemon does not report its status")
+	}
+
+	s := resp.Status
+	status := &Status{
+		Version:        s.Version,
+		StartedAt:      s.StartedAt,
+		Uptime:         time.Duration(s.UptimeMs) * time.Millisecond,
+		CommandsServed: s.CommandsServed,
+		Connections:    s.Connections,
+		Healthy:        s.Healthy,
+	}
+	for _, d := range s.Devices {
+		status.Devices = append(status.Devices, DeviceStatus{
+			DeviceInfo: DeviceInfo(d.DeviceInfo),
+			Created:    d.Created,
+			Healthy:    d.Healthy,
+			Error:      d.Error,
+		})
+	}
+	for _, cl := range s.Clients {
+		status.Clients = append(status.Clients, ClientInfo{
+			Peer:        newPeer(cl.Peer),
+			ConnectedAt: cl.ConnectedAt,
+			Commands:    cl.Commands,
+		})
+	}
+	if len(s.Jobs) > 0 {
+		status.Jobs = newJobs(s.Jobs)
+	}
+	for _, rl := range s.RateLimits {
+		rs := RateLimitStatus{
+			Global:              rl.UID == nil && rl.Rule == "",
+			Rule:                rl.Rule,
+			CommandsPerSecond:   rl.CommandsPerSecond,
+			KeystrokesPerSecond: rl.KeystrokesPerSecond,
+			Commands:            rl.Commands,
+			Keystrokes:          rl.Keystrokes,
+			Delayed:             rl.Delayed,
+			Rejected:            rl.Rejected,
+		}
+		if rl.UID != nil {
+			rs.UID = *rl.UID
+		}
+		status.RateLimits = append(status.RateLimits, rs)
+	}
+	return status, nil
+}
+
+// Capabilities returns the version, commands, layouts, devices and limits
+// of the daemon, so that features can be checked before use.
+//
+// Example:
+//
+//	caps, err := client.Capabilities(ctx)
+//	if err == nil && caps.Supports("mouse_move") {
+//	    err = client.MoveMouse(ctx, 10, 0)
+//	}
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	resp, err := c.roundTrip(ctx, protocol.CommandType_Capabilities, protocol.CapabilitiesPayload{}, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Capabilities == nil {
+		return nil, fmt.Errorf("daemon does not report its capabilities")
+	}
+
+	rc := resp.Capabilities
+	caps := &Capabilities{
+		Version:         rc.Version,
+		DefaultLayout:   rc.DefaultLayout,
+		LayoutDetect:    rc.LayoutDetect,
+		Layouts:         rc.Layouts,
+		Fallbacks:       rc.Fallbacks,
+		DefaultFallback: rc.DefaultFallback,
+		Limits:          Limits(rc.Limits),
+	}
+	for _, cmd := range rc.Commands {
+		caps.Commands = append(caps.Commands, string(cmd))
+	}
+	for _, d := range rc.Devices {
+		caps.Devices = append(caps.Devices, DeviceInfo(d))
+	}
+	return caps, nil
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+// StreamWriter is an incremental streaming session on the daemon.
+// Every Write is typed as soon as the daemon receives it, using the layout
+// and delays given to OpenStream. It implements io.WriteCloser.
+//
+// Example:
+//
+//	w, err := c.OpenStream(ctx, &client.StreamOptions{Layout: "fr"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for token := range tokens {
+//	    io.WriteString(w, token)
+//	}
+//	err = w.Close() // Returns once everything has been typed
+type StreamWriter struct {
+	c      *Client
+	ctx    context.Context
+	tail   []byte // Incomplete UTF-8 sequence carried over to the next Write
+	closed bool
+	result *TypeResult
+	job    string
+}
+
+// Compile-time check to ensure StreamWriter implements io.WriteCloser
+var _ io.WriteCloser = (*StreamWriter)(nil)
+
+// ErrStreamClosed is returned when writing to a closed StreamWriter.
+var ErrStreamClosed = errors.New("stream closed")
+
+// OpenStream opens an incremental streaming session.
+// Only one session can be open per client at a time.
+// The context is used for every subsequent operation on the session.
+func (c *Client) OpenStream(ctx context.Context, opts *StreamOptions) (*StreamWriter, error) {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+
+	payload := protocol.StreamOpenPayload{
+		Layout:    opts.Layout,
+		Fallback:  opts.Fallback,
+		DelayMs:   opts.DelayMs,
+		CharDelay: opts.CharDelay,
+	}
+
+	resp, err := c.roundTrip(ctx, protocol.CommandType_StreamOpen, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StreamWriter{c: c, ctx: ctx, job: resp.Job}, nil
+}
+
+// Job returns the ID of the session's job on the daemon, which Cancel
+// accepts. It is empty with daemons that don't support cancellation.
+func (w *StreamWriter) Job() string {
+	return w.job
+}
+
+// Write sends p to the daemon for typing.
+// A multi-byte character split across writes is held back until complete.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrStreamClosed
+	}
+
+	data := append(w.tail, p...)
+
+	// Hold back a trailing incomplete rune
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	w.tail = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return len(p), nil
+	}
+
+	if err := w.sendChunk(string(data[:cut])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush blocks until everything written so far has been typed.
+func (w *StreamWriter) Flush() error {
+	if w.closed {
+		return ErrStreamClosed
+	}
+
+	if len(w.tail) > 0 {
+		tail := w.tail
+		w.tail = nil
+		if err := w.sendChunk(string(tail)); err != nil {
+			return err
+		}
+	}
+
+	return w.roundTrip(protocol.CommandType_StreamFlush, protocol.StreamFlushPayload{})
+}
+
+// Close flushes pending text and ends the session.
+// It blocks until everything written has been typed.
+func (w *StreamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	var err error
+	if len(w.tail) > 0 {
+		err = w.sendChunk(string(w.tail))
+		w.tail = nil
+	}
+	w.closed = true
+
+	if cerr := w.roundTrip(protocol.CommandType_StreamClose, protocol.StreamClosePayload{}); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Result reports how the text written so far was typed, as of the last
+// successful Flush or Close. It returns nil before then.
+func (w *StreamWriter) Result() *TypeResult {
+	return w.result
+}
+
+// roundTrip sends a flush or close command, which only answers once all
+// queued text has been typed, and keeps the session's result.
+func (w *StreamWriter) roundTrip(cmdType protocol.CommandType, payload interface{}) error {
+	resp, err := w.c.roundTrip(w.ctx, cmdType, payload, 0)
+	if err != nil {
+		return err
+	}
+	w.result = newTypeResult(resp)
+	return nil
+}
+
+// sendChunk sends a chunk of text to the open session.
+func (w *StreamWriter) sendChunk(text string) error {
+	return w.c.sendCommand(w.ctx, protocol.CommandType_StreamChunk, protocol.StreamChunkPayload{Text: text})
+}
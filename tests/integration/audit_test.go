@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnema/uinputd-go/internal/audit"
+	"github.com/bnema/uinputd-go/internal/config"
+	"github.com/bnema/uinputd-go/internal/protocol"
+)
+
+func TestAudit_RecordsCommands(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Audit = config.AuditConfig{Enabled: true, Path: auditPath, Text: audit.TextRedacted}
+	})
+
+	typePayload, _ := json.Marshal(protocol.TypePayload{Text: "Secret 123"})
+	keyPayload, _ := json.Marshal(protocol.KeyPayload{Keycode: 30, Modifier: "ctrl"})
+	badPayload, _ := json.Marshal(protocol.TypePayload{Text: "x", Layout: "nonexistent"})
+
+	for _, cmd := range []*protocol.Command{
+		{Type: protocol.CommandType_Type, ID: "1", Payload: typePayload},
+		{Type: protocol.CommandType_Key, Payload: keyPayload},
+		{Type: protocol.CommandType_Type, Payload: badPayload},
+	} {
+		ts.sendCommand(t, cmd)
+	}
+	ts.close()
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if strings.Contains(string(data), "Secret") {
+		t.Errorf("Typed text recorded in clear: %s", data)
+	}
+
+	var entries []audit.Entry
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var e audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d:\n%s", len(entries), data)
+	}
+
+	// The kernel reports this test process as the client
+	exe, _ := os.Executable()
+	for _, e := range entries {
+		if e.UID == nil || int(*e.UID) != os.Getuid() || int(e.PID) != os.Getpid() || e.Exe != exe {
+			t.Errorf("Expected this process as the client, got uid %v pid %d exe %q", e.UID, e.PID, e.Exe)
+		}
+	}
+
+	typed := entries[0]
+	if typed.Command != "type" || typed.ID != "1" || typed.Layout != "us" || !typed.Success {
+		t.Errorf("Unexpected type entry %+v", typed)
+	}
+	if typed.TextLen != 10 || len(typed.TextHMAC) != 64 || typed.Preview != "Xxxxxx 000" {
+		t.Errorf("Unexpected text record: len %d hash %q preview %q", typed.TextLen, typed.TextHMAC, typed.Preview)
+	}
+
+	if key := entries[1]; key.Command != "key" || key.Keys != "ctrl+30" || key.TextLen != 0 {
+		t.Errorf("Unexpected key entry %+v", key)
+	}
+
+	if failed := entries[2]; failed.Success || failed.Layout != "nonexistent" || !strings.Contains(failed.Error, "layout") {
+		t.Errorf("Unexpected failed entry %+v", failed)
+	}
+}
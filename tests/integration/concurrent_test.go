@@ -2,6 +2,7 @@ package integration
 
 import (
 	"encoding/json"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -457,3 +458,58 @@ func TestConcurrent_NoEventInterleaving(t *testing.T) {
 		t.Errorf("Expected %d key presses, got %d", clientCount, presses)
 	}
 }
+
+func TestConcurrent_TextsDoNotInterleave(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	// Each client types a word of a single repeated letter; commands run one
+	// at a time, so each word must come out in one piece.
+	clientCount := 5
+	wordLen := 8
+	var wg sync.WaitGroup
+	wg.Add(clientCount)
+
+	for i := 0; i < clientCount; i++ {
+		go func(clientID int) {
+			defer wg.Done()
+
+			payload := protocol.StreamPayload{
+				Text:      strings.Repeat(string(rune('a'+clientID)), wordLen),
+				Layout:    "us",
+				CharDelay: 1,
+			}
+			payloadBytes, _ := json.Marshal(payload)
+			cmd := &protocol.Command{
+				Type:    protocol.CommandType_Stream,
+				Payload: payloadBytes,
+			}
+
+			resp := ts.sendCommand(t, cmd)
+
+			if !resp.Success {
+				t.Errorf("Client %d: command failed: %s", clientID, resp.Error)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	var presses []string
+	for _, key := range ts.mockDevice.GetKeyPressSequence() {
+		if strings.HasPrefix(key, "press") {
+			presses = append(presses, key)
+		}
+	}
+	if len(presses) != clientCount*wordLen {
+		t.Fatalf("Expected %d key presses, got %d", clientCount*wordLen, len(presses))
+	}
+
+	for i := 0; i < len(presses); i += wordLen {
+		for j := i + 1; j < i+wordLen; j++ {
+			if presses[j] != presses[i] {
+				t.Fatalf("Texts interleaved: %v", presses)
+			}
+		}
+	}
+}
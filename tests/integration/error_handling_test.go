@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/bnema/uinputd-go/internal/config"
 	"github.com/bnema/uinputd-go/internal/protocol"
+	"github.com/bnema/uinputd-go/internal/server"
 	"github.com/bnema/uinputd-go/internal/uinput"
 )
 
@@ -568,3 +573,363 @@ func TestErrorHandling_MixedValidInvalidCommands(t *testing.T) {
 		t.Error("No events generated from valid commands")
 	}
 }
+
+// dialServer connects to the test server, failing the test on error.
+func dialServer(t *testing.T, ts *testServer) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("unix", ts.socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	return conn
+}
+
+func TestErrorHandling_MessageTooLarge(t *testing.T) {
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Performance.MaxMessageSize = 1024
+		cfg.Performance.BufferSize = 256
+	})
+	defer ts.close()
+
+	conn := dialServer(t, ts)
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	// Commands within the limit are served, one after the other
+	for i := 0; i < 3; i++ {
+		payload, _ := json.Marshal(protocol.TypePayload{Text: strings.Repeat("a", 500), Layout: "us"})
+		if err := enc.Encode(&protocol.Command{Type: protocol.CommandType_Type, Payload: payload}); err != nil {
+			t.Fatalf("Failed to send command: %v", err)
+		}
+		var resp protocol.Response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("Command within the limit failed: %s", resp.Error)
+		}
+	}
+	eventsBefore := ts.mockDevice.GetEventCount()
+
+	// A larger command is rejected without being read in full
+	go func() {
+		payload, _ := json.Marshal(protocol.TypePayload{Text: strings.Repeat("b", 1<<20), Layout: "us"})
+		enc.Encode(&protocol.Command{Type: protocol.CommandType_Type, Payload: payload})
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var resp protocol.Response
+	if err := dec.Decode(&resp); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrorCode_MessageTooLarge {
+		t.Errorf("Expected %s error, got success=%v code=%q error=%q", protocol.ErrorCode_MessageTooLarge, resp.Success, resp.Code, resp.Error)
+	}
+	if !strings.Contains(resp.Error, "max 1024 bytes") {
+		t.Errorf("Expected the limit in the error, got %q", resp.Error)
+	}
+
+	// The connection is closed and nothing was typed
+	if err := dec.Decode(&resp); err == nil {
+		t.Error("Expected the connection to be closed")
+	}
+	if got := ts.mockDevice.GetEventCount(); got != eventsBefore {
+		t.Errorf("Expected no events from the rejected command, got %d", got-eventsBefore)
+	}
+}
+
+func TestErrorHandling_MaxConcurrentCmds(t *testing.T) {
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Performance.MaxConcurrentCmds = 1
+	})
+	defer ts.close()
+
+	// The first stream types while the second waits for the devices. The
+	// streams are long enough to still run when cancelled below.
+	payload, _ := json.Marshal(protocol.StreamPayload{Text: strings.Repeat("a", 1000), Layout: "us", CharDelay: 20})
+	stream := &protocol.Command{Type: protocol.CommandType_Stream, Payload: payload}
+
+	waitJobs := func(want func([]protocol.JobInfo) bool, what string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Jobs, Payload: json.RawMessage("{}")})
+			if want(resp.Jobs) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s, got %+v", what, resp.Jobs)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	var streams []net.Conn
+	for i := 0; i < 2; i++ {
+		conn := dialServer(t, ts)
+		defer conn.Close()
+		if err := json.NewEncoder(conn).Encode(stream); err != nil {
+			t.Fatalf("Failed to send command: %v", err)
+		}
+		streams = append(streams, conn)
+
+		// Send the second stream once the first one runs
+		if i == 0 {
+			waitJobs(func(jobs []protocol.JobInfo) bool {
+				return len(jobs) == 1 && jobs[0].State == protocol.JobState_Running
+			}, "a running job")
+		}
+	}
+	waitJobs(func(jobs []protocol.JobInfo) bool {
+		return len(jobs) == 2 && jobs[1].State == protocol.JobState_Queued
+	}, "a running and a queued job")
+
+	// The queue is full: device commands are rejected, others still served
+	keyPayload, _ := json.Marshal(protocol.KeyPayload{Chord: "enter"})
+	resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Key, Payload: keyPayload})
+	if resp.Success || resp.Code != protocol.ErrorCode_Busy {
+		t.Errorf("Expected %s error, got success=%v code=%q error=%q", protocol.ErrorCode_Busy, resp.Success, resp.Code, resp.Error)
+	}
+
+	resp = ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Cancel, Payload: json.RawMessage(`{"all":true}`)})
+	if !resp.Success || len(resp.Jobs) != 2 {
+		t.Fatalf("Cancel failed: %s (%d jobs)", resp.Error, len(resp.Jobs))
+	}
+	for _, conn := range streams {
+		var resp protocol.Response
+		if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		if resp.Code != protocol.ErrorCode_Cancelled {
+			t.Errorf("Expected cancelled stream, got code=%q error=%q", resp.Code, resp.Error)
+		}
+	}
+}
+
+func TestErrorHandling_ReadTimeout(t *testing.T) {
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Socket.ReadTimeoutMs = 50
+	})
+	defer ts.close()
+
+	conn := dialServer(t, ts)
+	defer conn.Close()
+
+	// Active connections stay open
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+	for i := 0; i < 3; i++ {
+		time.Sleep(25 * time.Millisecond)
+		if err := enc.Encode(&protocol.Command{Type: protocol.CommandType_Ping, Payload: json.RawMessage("{}")}); err != nil {
+			t.Fatalf("Failed to send command: %v", err)
+		}
+		var resp protocol.Response
+		if err := dec.Decode(&resp); err != nil || !resp.Success {
+			t.Fatalf("Ping failed: %v %s", err, resp.Error)
+		}
+	}
+
+	// Idle ones are closed, even in the middle of a command
+	conn.Write([]byte(`{"type":"ping",`))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	var resp protocol.Response
+	if err := dec.Decode(&resp); err == nil {
+		t.Fatal("Expected the idle connection to be closed")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Idle connection closed after %v", elapsed)
+	}
+}
+
+func TestErrorHandling_WriteTimeout(t *testing.T) {
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Socket.WriteTimeoutMs = 50
+	})
+	defer ts.close()
+
+	// A client that never reads its responses fills the socket buffer
+	conn := dialServer(t, ts)
+	defer conn.Close()
+	go func() {
+		enc := json.NewEncoder(conn)
+		for {
+			if err := enc.Encode(&protocol.Command{Type: protocol.CommandType_Ping, Payload: json.RawMessage("{}")}); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The daemon gives up on it instead of blocking forever
+	waitConnections(t, ts, 2)
+	waitConnections(t, ts, 1)
+}
+
+// waitConnections waits until the daemon counts n open connections,
+// including the one asking.
+func waitConnections(t *testing.T, ts *testServer, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Status, Payload: json.RawMessage("{}")})
+		if resp.Status != nil && resp.Status.Connections == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d open connections", n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestErrorHandling_PolicyDenied(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Failed to find test executable: %v", err)
+	}
+
+	// The kernel reports this test process as the client
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := fmt.Sprintf(`rules:
+  - name: other
+    match: { uid: %d }
+  - name: tests
+    match: { uid: %d, exe: %q }
+    commands: [ping, type]
+    layouts: [us]
+`, os.Getuid()+1, os.Getuid(), exe)
+	if err := os.WriteFile(policyFile, []byte(policy), 0o644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.PolicyFile = policyFile
+	})
+	defer ts.close()
+
+	typeUS, _ := json.Marshal(protocol.TypePayload{Text: "a", Layout: "us"})
+	typeFR, _ := json.Marshal(protocol.TypePayload{Text: "a", Layout: "fr"})
+	key, _ := json.Marshal(protocol.KeyPayload{Chord: "enter"})
+
+	tests := []struct {
+		name   string
+		cmd    protocol.Command
+		denied bool
+	}{
+		{"allowed command", protocol.Command{Type: protocol.CommandType_Ping}, false},
+		{"allowed layout", protocol.Command{Type: protocol.CommandType_Type, Payload: typeUS}, false},
+		{"command not allowed", protocol.Command{Type: protocol.CommandType_Key, Payload: key}, true},
+		{"layout not allowed", protocol.Command{Type: protocol.CommandType_Type, Payload: typeFR}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.mockDevice.Reset()
+			resp := ts.sendCommand(t, &tt.cmd)
+
+			if !tt.denied {
+				if !resp.Success {
+					t.Errorf("Expected success, got error: %s", resp.Error)
+				}
+				return
+			}
+			if resp.Success || resp.Code != protocol.ErrorCode_PermissionDenied {
+				t.Errorf("Expected %s error, got success=%v code=%q error=%q", protocol.ErrorCode_PermissionDenied, resp.Success, resp.Code, resp.Error)
+			}
+			if !strings.Contains(resp.Error, "rule tests") {
+				t.Errorf("Expected the rule in the error, got %q", resp.Error)
+			}
+			if got := ts.mockDevice.GetEventCount(); got != 0 {
+				t.Errorf("Expected no events from the denied command, got %d", got)
+			}
+		})
+	}
+}
+
+func TestErrorHandling_PolicyNoRule(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := fmt.Sprintf("default: deny\nrules:\n  - match: { uid: %d }\n", os.Getuid()+1)
+	if err := os.WriteFile(policyFile, []byte(policy), 0o644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.PolicyFile = policyFile
+	})
+	defer ts.close()
+
+	resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Ping})
+	if resp.Success || resp.Code != protocol.ErrorCode_PermissionDenied {
+		t.Errorf("Expected %s error, got success=%v code=%q error=%q", protocol.ErrorCode_PermissionDenied, resp.Success, resp.Code, resp.Error)
+	}
+}
+
+func TestErrorHandling_SocketInUse(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	// A second daemon must not take over the socket of a running one
+	cfg := &config.Config{
+		Socket: config.SocketConfig{Path: ts.socketPath, Permissions: 0600},
+		Layout: "us",
+	}
+	if srv, err := server.New(context.Background(), cfg, NewMockUinputDevice()); err == nil {
+		srv.Close()
+		t.Fatal("Expected an error for a socket in use")
+	}
+
+	resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Ping, Payload: json.RawMessage(`{}`)})
+	if !resp.Success {
+		t.Errorf("Expected the first daemon to keep serving, got %s", resp.Error)
+	}
+}
+
+func TestErrorHandling_MaxConnections(t *testing.T) {
+	ts := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Socket.MaxConnections = 1
+	})
+	defer ts.close()
+
+	ping := &protocol.Command{Type: protocol.CommandType_Ping, Payload: json.RawMessage("{}")}
+
+	// The first connection is served
+	first := dialServer(t, ts)
+	if err := json.NewEncoder(first).Encode(ping); err != nil {
+		t.Fatalf("Failed to send command: %v", err)
+	}
+	var resp protocol.Response
+	if err := json.NewDecoder(first).Decode(&resp); err != nil || !resp.Success {
+		t.Fatalf("Ping failed: %v %s", err, resp.Error)
+	}
+
+	// The second one is refused while the first stays open
+	second := dialServer(t, ts)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	dec := json.NewDecoder(second)
+	resp = protocol.Response{}
+	if err := dec.Decode(&resp); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrorCode_Busy {
+		t.Errorf("Expected %s error, got success=%v code=%q error=%q", protocol.ErrorCode_Busy, resp.Success, resp.Code, resp.Error)
+	}
+	if err := dec.Decode(&resp); err == nil {
+		t.Error("Expected the refused connection to be closed")
+	}
+
+	// Closing the first connection frees its slot
+	first.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if resp := ts.sendCommand(t, ping); resp.Success {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a connection once the first one closed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
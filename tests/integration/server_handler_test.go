@@ -3,7 +3,9 @@ package integration
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -16,18 +18,27 @@ import (
 
 // testServer wraps server and mock device for testing.
 type testServer struct {
-	server     *server.Server
-	mockDevice *MockUinputDevice
-	ctx        context.Context
-	cancel     context.CancelFunc
-	socketPath string
+	server      *server.Server
+	mockDevice  *MockUinputDevice
+	mockPointer *MockUinputDevice
+	ctx         context.Context
+	cancel      context.CancelFunc
+	socketPath  string
 }
 
 // newTestServer creates a new test server with mock device.
 func newTestServer(t *testing.T) *testServer {
 	t.Helper()
+	return newTestServerWithConfig(t, nil)
+}
+
+// newTestServerWithConfig creates a test server whose minimal config is
+// adjusted by configure, if set.
+func newTestServerWithConfig(t *testing.T, configure func(*config.Config)) *testServer {
+	t.Helper()
 
 	mockDevice := NewMockUinputDevice()
+	mockPointer := NewMockUinputDevice()
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create test socket path
@@ -41,12 +52,16 @@ func newTestServer(t *testing.T) *testServer {
 		},
 		Layout: "us",
 	}
+	if configure != nil {
+		configure(cfg)
+	}
 
 	// Create server with mock device
 	srv, err := server.New(ctx, cfg, mockDevice)
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
+	srv.SetPointer(mockPointer)
 
 	// Start server in background
 	go func() {
@@ -59,11 +74,12 @@ func newTestServer(t *testing.T) *testServer {
 	time.Sleep(50 * time.Millisecond)
 
 	return &testServer{
-		server:     srv,
-		mockDevice: mockDevice,
-		ctx:        ctx,
-		cancel:     cancel,
-		socketPath: socketPath,
+		server:      srv,
+		mockDevice:  mockDevice,
+		mockPointer: mockPointer,
+		ctx:         ctx,
+		cancel:      cancel,
+		socketPath:  socketPath,
 	}
 }
 
@@ -418,6 +434,50 @@ func TestServerHandler_PingCommand(t *testing.T) {
 	}
 }
 
+func TestServerHandler_StatusCommand(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	ping := &protocol.Command{Type: protocol.CommandType_Ping, Payload: json.RawMessage(`{}`)}
+	if resp := ts.sendCommand(t, ping); !resp.Success {
+		t.Fatalf("Ping failed: %s", resp.Error)
+	}
+
+	resp := ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Status, Payload: json.RawMessage(`{}`)})
+	if !resp.Success || resp.Status == nil {
+		t.Fatalf("Status failed: %s", resp.Error)
+	}
+
+	// The ping and the status command itself
+	if resp.Status.CommandsServed != 2 {
+		t.Errorf("Expected 2 commands served, got %d", resp.Status.CommandsServed)
+	}
+	if resp.Status.Version != "dev" {
+		t.Errorf("Expected version dev, got %s", resp.Status.Version)
+	}
+	if !resp.Status.Healthy || len(resp.Status.Devices) != 2 {
+		t.Errorf("Expected 2 healthy devices, got %+v", resp.Status.Devices)
+	}
+
+	// The daemon identifies this process from the socket credentials; the
+	// ping's connection may not be closed yet, the status one comes last
+	clients := resp.Status.Clients
+	if len(clients) == 0 || clients[len(clients)-1].Peer == nil {
+		t.Fatalf("Expected an identified client, got %+v", clients)
+	}
+	if p := clients[len(clients)-1].Peer; p.PID != int32(os.Getpid()) || p.UID != uint32(os.Getuid()) {
+		t.Errorf("Expected client pid %d uid %d, got pid %d uid %d", os.Getpid(), os.Getuid(), p.PID, p.UID)
+	}
+
+	resp = ts.sendCommand(t, &protocol.Command{Type: protocol.CommandType_Capabilities, Payload: json.RawMessage(`{}`)})
+	if !resp.Success || resp.Capabilities == nil {
+		t.Fatalf("Capabilities failed: %s", resp.Error)
+	}
+	if resp.Capabilities.DefaultLayout != "us" {
+		t.Errorf("Expected default layout us, got %s", resp.Capabilities.DefaultLayout)
+	}
+}
+
 func TestServerHandler_InvalidCommand(t *testing.T) {
 	ts := newTestServer(t)
 	defer ts.close()
@@ -485,3 +545,336 @@ func TestServerHandler_EmptyText(t *testing.T) {
 		t.Errorf("Empty text should not generate events, got %d", ts.mockDevice.GetEventCount())
 	}
 }
+
+func TestServerHandler_PersistentConnection(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	conn, err := net.Dial("unix", ts.socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	texts := []string{"one", "two", "three"}
+
+	// Pipeline all commands before reading any response
+	encoder := json.NewEncoder(conn)
+	for i, text := range texts {
+		payloadBytes, err := json.Marshal(protocol.TypePayload{Text: text, Layout: "us"})
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+
+		cmd := &protocol.Command{
+			ID:      fmt.Sprintf("req-%d", i),
+			Type:    protocol.CommandType_Type,
+			Payload: payloadBytes,
+		}
+		if err := encoder.Encode(cmd); err != nil {
+			t.Fatalf("Failed to send command %d: %v", i, err)
+		}
+	}
+
+	decoder := json.NewDecoder(conn)
+	for i := range texts {
+		var resp protocol.Response
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("Failed to read response %d: %v", i, err)
+		}
+
+		if !resp.Success {
+			t.Errorf("Command %d failed: %s", i, resp.Error)
+		}
+
+		if want := fmt.Sprintf("req-%d", i); resp.ID != want {
+			t.Errorf("Response %d: expected id %q, got %q", i, want, resp.ID)
+		}
+	}
+
+	// 3+3+5 lowercase chars * 4 events
+	if got := ts.mockDevice.GetEventCount(); got != 44 {
+		t.Errorf("Expected 44 events, got %d", got)
+	}
+}
+
+func TestServerHandler_StreamSession(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	conn, err := net.Dial("unix", ts.socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+
+	send := func(cmdType protocol.CommandType, payload interface{}) *protocol.Response {
+		t.Helper()
+
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		if err := encoder.Encode(&protocol.Command{Type: cmdType, Payload: payloadBytes}); err != nil {
+			t.Fatalf("Failed to send %s: %v", cmdType, err)
+		}
+
+		var resp protocol.Response
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("Failed to read %s response: %v", cmdType, err)
+		}
+		return &resp
+	}
+
+	if resp := send(protocol.CommandType_StreamChunk, protocol.StreamChunkPayload{Text: "x"}); resp.Success {
+		t.Error("Expected stream_chunk without an open session to fail")
+	}
+
+	if resp := send(protocol.CommandType_StreamOpen, protocol.StreamOpenPayload{Layout: "us", CharDelay: 1, DelayMs: 1}); !resp.Success {
+		t.Fatalf("stream_open failed: %s", resp.Error)
+	}
+
+	if resp := send(protocol.CommandType_StreamOpen, protocol.StreamOpenPayload{}); resp.Success {
+		t.Error("Expected a second stream_open to fail")
+	}
+
+	for _, chunk := range []string{"hel", "lo ", "you"} {
+		if resp := send(protocol.CommandType_StreamChunk, protocol.StreamChunkPayload{Text: chunk}); !resp.Success {
+			t.Fatalf("stream_chunk %q failed: %s", chunk, resp.Error)
+		}
+	}
+
+	if resp := send(protocol.CommandType_StreamFlush, protocol.StreamFlushPayload{}); !resp.Success {
+		t.Fatalf("stream_flush failed: %s", resp.Error)
+	}
+
+	// "hello you" = 9 keys * 4 events, all typed once flush returns
+	if got := ts.mockDevice.GetEventCount(); got != 36 {
+		t.Errorf("Expected 36 events after flush, got %d", got)
+	}
+
+	if resp := send(protocol.CommandType_StreamClose, protocol.StreamClosePayload{}); !resp.Success {
+		t.Fatalf("stream_close failed: %s", resp.Error)
+	}
+
+	if resp := send(protocol.CommandType_StreamClose, protocol.StreamClosePayload{}); resp.Success {
+		t.Error("Expected stream_close without an open session to fail")
+	}
+}
+
+func TestServerHandler_KeyChord(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	payloadBytes, err := json.Marshal(protocol.KeyPayload{Chord: "ctrl+alt+delete"})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	resp := ts.sendCommand(t, &protocol.Command{
+		Type:    protocol.CommandType_Key,
+		Payload: payloadBytes,
+	})
+	if !resp.Success {
+		t.Fatalf("Command failed: %s", resp.Error)
+	}
+
+	// Presses in order, releases nested in reverse order
+	expected := []EventSequence{
+		{Keycode: uinput.KeyLeftCtrl, Pressed: true, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftAlt, Pressed: true, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyDelete, Pressed: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyDelete, Pressed: false},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftAlt, Pressed: false, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftCtrl, Pressed: false, Modifier: true},
+		{IsSyn: true},
+	}
+
+	if err := ts.mockDevice.VerifyEventSequence(expected); err != nil {
+		t.Errorf("Event sequence verification failed: %v", err)
+		t.Logf("Got sequence: %v", ts.mockDevice.GetKeyPressSequence())
+	}
+}
+
+func TestServerHandler_HeldKeysReleasedOnDisconnect(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	dial := func() (net.Conn, *json.Encoder, *json.Decoder) {
+		t.Helper()
+		conn, err := net.Dial("unix", ts.socketPath)
+		if err != nil {
+			t.Fatalf("Failed to connect to server: %v", err)
+		}
+		return conn, json.NewEncoder(conn), json.NewDecoder(conn)
+	}
+
+	send := func(enc *json.Encoder, dec *json.Decoder, cmdType protocol.CommandType, chord string) *protocol.Response {
+		t.Helper()
+
+		payloadBytes, err := json.Marshal(protocol.KeyHoldPayload{Chord: chord})
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		if err := enc.Encode(&protocol.Command{Type: cmdType, Payload: payloadBytes}); err != nil {
+			t.Fatalf("Failed to send %s: %v", cmdType, err)
+		}
+
+		var resp protocol.Response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("Failed to read %s response: %v", cmdType, err)
+		}
+		return &resp
+	}
+
+	connA, encA, decA := dial()
+	connB, encB, decB := dial()
+	defer connB.Close()
+
+	// Both clients hold shift; A also holds ctrl
+	if resp := send(encA, decA, protocol.CommandType_KeyDown, "ctrl+shift"); !resp.Success {
+		t.Fatalf("keydown failed: %s", resp.Error)
+	}
+	if resp := send(encB, decB, protocol.CommandType_KeyDown, "shift"); !resp.Success {
+		t.Fatalf("keydown failed: %s", resp.Error)
+	}
+
+	// Releasing a key this client never pressed is an error
+	if resp := send(encB, decB, protocol.CommandType_KeyUp, "ctrl"); resp.Success {
+		t.Error("Expected keyup of a key not held by the client to fail")
+	}
+
+	// Client A goes away without releasing anything
+	connA.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// Ctrl is released, shift stays down for client B
+	expected := []EventSequence{
+		{Keycode: uinput.KeyLeftCtrl, Pressed: true, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftShift, Pressed: true, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftCtrl, Pressed: false, Modifier: true},
+		{IsSyn: true},
+	}
+	if err := ts.mockDevice.VerifyEventSequence(expected); err != nil {
+		t.Errorf("Event sequence verification failed: %v", err)
+		t.Logf("Got sequence: %v", ts.mockDevice.GetKeyPressSequence())
+	}
+
+	// Client B releases shift explicitly
+	if resp := send(encB, decB, protocol.CommandType_KeyUp, "shift"); !resp.Success {
+		t.Fatalf("keyup failed: %s", resp.Error)
+	}
+
+	expected = append(expected,
+		EventSequence{Keycode: uinput.KeyLeftShift, Pressed: false, Modifier: true},
+		EventSequence{IsSyn: true},
+	)
+	if err := ts.mockDevice.VerifyEventSequence(expected); err != nil {
+		t.Errorf("Event sequence verification failed: %v", err)
+		t.Logf("Got sequence: %v", ts.mockDevice.GetKeyPressSequence())
+	}
+}
+
+func TestServerHandler_HeldKeysReleasedOnShutdown(t *testing.T) {
+	ts := newTestServer(t)
+
+	conn, err := net.Dial("unix", ts.socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	payloadBytes, _ := json.Marshal(protocol.KeyHoldPayload{Keycode: uinput.KeyLeftShift})
+	if err := json.NewEncoder(conn).Encode(&protocol.Command{Type: protocol.CommandType_KeyDown, Payload: payloadBytes}); err != nil {
+		t.Fatalf("Failed to send keydown: %v", err)
+	}
+
+	var resp protocol.Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil || !resp.Success {
+		t.Fatalf("keydown failed: %v %s", err, resp.Error)
+	}
+
+	// Stop the daemon while the client still holds shift
+	ts.cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	expected := []EventSequence{
+		{Keycode: uinput.KeyLeftShift, Pressed: true, Modifier: true},
+		{IsSyn: true},
+		{Keycode: uinput.KeyLeftShift, Pressed: false, Modifier: true},
+		{IsSyn: true},
+	}
+	if err := ts.mockDevice.VerifyEventSequence(expected); err != nil {
+		t.Errorf("Event sequence verification failed: %v", err)
+		t.Logf("Got sequence: %v", ts.mockDevice.GetKeyPressSequence())
+	}
+
+	ts.close()
+}
+
+func TestServerHandler_MouseDragReleasedOnDisconnect(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.close()
+
+	conn, err := net.Dial("unix", ts.socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+
+	send := func(cmdType protocol.CommandType, payload interface{}) {
+		t.Helper()
+
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		if err := enc.Encode(&protocol.Command{Type: cmdType, Payload: payloadBytes}); err != nil {
+			t.Fatalf("Failed to send %s: %v", cmdType, err)
+		}
+
+		var resp protocol.Response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("Failed to read %s response: %v", cmdType, err)
+		}
+		if !resp.Success {
+			t.Fatalf("%s failed: %s", cmdType, resp.Error)
+		}
+	}
+
+	// Start a drag and disconnect midway
+	send(protocol.CommandType_MouseDown, protocol.MouseButtonPayload{Button: "left"})
+	send(protocol.CommandType_MouseMove, protocol.MouseMovePayload{X: 40, Y: 0})
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	events := ts.mockPointer.GetEvents()
+	// press, syn, REL_X, syn, release, syn
+	if len(events) != 6 {
+		t.Fatalf("Expected 6 pointer events, got %d", len(events))
+	}
+
+	if events[2].Type != uinput.EvRel || events[2].Code != uinput.RelX || events[2].Value != 40 {
+		t.Errorf("Expected REL_X 40, got type=%d code=%d value=%d", events[2].Type, events[2].Code, events[2].Value)
+	}
+
+	release := events[4]
+	if release.Type != uinput.EvKey || release.Code != uinput.BtnLeft || release.Value != int32(uinput.KeyRelease) {
+		t.Errorf("Expected BTN_LEFT release on disconnect, got type=%d code=%d value=%d", release.Type, release.Code, release.Value)
+	}
+
+	// Nothing reached the keyboard
+	if n := ts.mockDevice.GetEventCount(); n != 0 {
+		t.Errorf("Expected no keyboard events, got %d", n)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 // mockDaemon simulates the uinputd daemon for integration testing
 type mockDaemon struct {
 	listener     net.Listener
+	mu           sync.Mutex
 	receivedCmds []protocol.Command
 	t            *testing.T
 }
@@ -52,17 +55,24 @@ func (md *mockDaemon) serve() {
 func (md *mockDaemon) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
-	var cmd protocol.Command
-	if err := json.NewDecoder(conn).Decode(&cmd); err != nil {
-		md.t.Logf("Failed to decode command: %v", err)
-		return
-	}
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		var cmd protocol.Command
+		if err := decoder.Decode(&cmd); err != nil {
+			return // Client disconnected
+		}
 
-	md.receivedCmds = append(md.receivedCmds, cmd)
+		md.mu.Lock()
+		md.receivedCmds = append(md.receivedCmds, cmd)
+		md.mu.Unlock()
 
-	resp := protocol.Response{Success: true, Message: "command executed successfully"}
-	if err := json.NewEncoder(conn).Encode(resp); err != nil {
-		md.t.Logf("Failed to send response: %v", err)
+		resp := protocol.Response{ID: cmd.ID, Success: true, Message: "command executed successfully"}
+		if err := encoder.Encode(resp); err != nil {
+			md.t.Logf("Failed to send response: %v", err)
+			return
+		}
 	}
 }
 
@@ -74,15 +84,58 @@ func (md *mockDaemon) addr() string {
 	return md.listener.Addr().String()
 }
 
-func (md *mockDaemon) getLastCommand() *protocol.Command {
-	if len(md.receivedCmds) == 0 {
-		return nil
-	}
-	return &md.receivedCmds[len(md.receivedCmds)-1]
+func (md *mockDaemon) reset() {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	md.receivedCmds = make([]protocol.Command, 0)
 }
 
 func (md *mockDaemon) getCommands() []protocol.Command {
-	return md.receivedCmds
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	cmds := make([]protocol.Command, len(md.receivedCmds))
+	copy(cmds, md.receivedCmds)
+	return cmds
+}
+
+// getStreamSession reassembles the streaming session sent by the client.
+// It returns the stream_open payload and the concatenated chunk text.
+func (md *mockDaemon) getStreamSession(t *testing.T) (protocol.StreamOpenPayload, string) {
+	t.Helper()
+
+	var open protocol.StreamOpenPayload
+
+	cmds := md.getCommands()
+	if len(cmds) < 2 {
+		t.Fatalf("Expected a stream session, got %d commands", len(cmds))
+	}
+
+	if cmds[0].Type != protocol.CommandType_StreamOpen {
+		t.Fatalf("Expected first command %v, got %v", protocol.CommandType_StreamOpen, cmds[0].Type)
+	}
+	if err := json.Unmarshal(cmds[0].Payload, &open); err != nil {
+		t.Fatalf("Failed to unmarshal stream_open payload: %v", err)
+	}
+
+	if last := cmds[len(cmds)-1]; last.Type != protocol.CommandType_StreamClose {
+		t.Fatalf("Expected last command %v, got %v", protocol.CommandType_StreamClose, last.Type)
+	}
+
+	var text strings.Builder
+	for _, cmd := range cmds[1 : len(cmds)-1] {
+		if cmd.Type != protocol.CommandType_StreamChunk {
+			t.Fatalf("Expected %v inside session, got %v", protocol.CommandType_StreamChunk, cmd.Type)
+		}
+
+		var chunk protocol.StreamChunkPayload
+		if err := json.Unmarshal(cmd.Payload, &chunk); err != nil {
+			t.Fatalf("Failed to unmarshal stream_chunk payload: %v", err)
+		}
+		text.WriteString(chunk.Text)
+	}
+
+	return open, text.String()
 }
 
 // getClientBinary returns the path to the uinput-client binary
@@ -137,7 +190,7 @@ func TestStreamCommand_StdinIntegration(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset received commands
-			daemon.receivedCmds = make([]protocol.Command, 0)
+			daemon.reset()
 
 			// Run uinput-client stream command
 			cmd := exec.Command(clientBin, "stream", "--socket", daemon.addr())
@@ -151,25 +204,12 @@ func TestStreamCommand_StdinIntegration(t *testing.T) {
 				t.Fatalf("Command failed: %v\nStdout: %s\nStderr: %s", err, stdout.String(), stderr.String())
 			}
 
-			// Verify command was received
+			// Verify the session was received
 			time.Sleep(10 * time.Millisecond) // Give server time to process
-			receivedCmd := daemon.getLastCommand()
-			if receivedCmd == nil {
-				t.Fatal("No command received by daemon")
-			}
-
-			if receivedCmd.Type != protocol.CommandType_Stream {
-				t.Errorf("Expected command type %v, got %v", protocol.CommandType_Stream, receivedCmd.Type)
-			}
+			_, text := daemon.getStreamSession(t)
 
-			// Unmarshal payload
-			var payload protocol.StreamPayload
-			if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
-				t.Fatalf("Failed to unmarshal payload: %v", err)
-			}
-
-			if payload.Text != tt.expectedText {
-				t.Errorf("Expected text %q, got %q", tt.expectedText, payload.Text)
+			if text != tt.expectedText {
+				t.Errorf("Expected text %q, got %q", tt.expectedText, text)
 			}
 		})
 	}
@@ -201,7 +241,7 @@ func TestStreamCommand_LayoutFlagIntegration(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset received commands
-			daemon.receivedCmds = make([]protocol.Command, 0)
+			daemon.reset()
 
 			// Run uinput-client stream command with layout flag
 			cmd := exec.Command(clientBin, "stream", "--socket", daemon.addr(), "--layout", tt.layout)
@@ -211,18 +251,9 @@ func TestStreamCommand_LayoutFlagIntegration(t *testing.T) {
 				t.Fatalf("Command failed: %v", err)
 			}
 
-			// Verify command
+			// Verify session options
 			time.Sleep(10 * time.Millisecond)
-			receivedCmd := daemon.getLastCommand()
-			if receivedCmd == nil {
-				t.Fatal("No command received by daemon")
-			}
-
-			// Unmarshal payload
-			var payload protocol.StreamPayload
-			if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
-				t.Fatalf("Failed to unmarshal payload: %v", err)
-			}
+			payload, _ := daemon.getStreamSession(t)
 
 			if payload.Layout != tt.expectedLayout {
 				t.Errorf("Expected layout %q, got %q", tt.expectedLayout, payload.Layout)
@@ -263,7 +294,7 @@ func TestStreamCommand_DelayFlagsIntegration(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset received commands
-			daemon.receivedCmds = make([]protocol.Command, 0)
+			daemon.reset()
 
 			// Build command arguments
 			args := []string{"stream", "--socket", daemon.addr()}
@@ -282,18 +313,9 @@ func TestStreamCommand_DelayFlagsIntegration(t *testing.T) {
 				t.Fatalf("Command failed: %v", err)
 			}
 
-			// Verify command
+			// Verify session options
 			time.Sleep(10 * time.Millisecond)
-			receivedCmd := daemon.getLastCommand()
-			if receivedCmd == nil {
-				t.Fatal("No command received by daemon")
-			}
-
-			// Unmarshal payload
-			var payload protocol.StreamPayload
-			if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
-				t.Fatalf("Failed to unmarshal payload: %v", err)
-			}
+			payload, _ := daemon.getStreamSession(t)
 
 			if payload.CharDelay != tt.expectedCharDelay {
 				t.Errorf("Expected CharDelay %d, got %d", tt.expectedCharDelay, payload.CharDelay)
@@ -320,7 +342,7 @@ what your country
 	expectedText := "my fellow Americans ask not what your country"
 
 	// Reset received commands
-	daemon.receivedCmds = make([]protocol.Command, 0)
+	daemon.reset()
 
 	// Run command
 	cmd := exec.Command(clientBin, "stream", "--socket", daemon.addr(), "--layout", "fr")
@@ -332,18 +354,10 @@ what your country
 
 	// Verify
 	time.Sleep(10 * time.Millisecond)
-	receivedCmd := daemon.getLastCommand()
-	if receivedCmd == nil {
-		t.Fatal("No command received by daemon")
-	}
-
-	var payload protocol.StreamPayload
-	if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
-		t.Fatalf("Failed to unmarshal payload: %v", err)
-	}
+	payload, text := daemon.getStreamSession(t)
 
-	if payload.Text != expectedText {
-		t.Errorf("Expected text %q, got %q", expectedText, payload.Text)
+	if text != expectedText {
+		t.Errorf("Expected text %q, got %q", expectedText, text)
 	}
 
 	if payload.Layout != "fr" {
@@ -419,22 +433,18 @@ func TestStreamCommand_LargeInput(t *testing.T) {
 		t.Fatalf("Command failed: %v", err)
 	}
 
-	// Verify command was received
+	// Verify the session was received
 	time.Sleep(20 * time.Millisecond)
-	receivedCmd := daemon.getLastCommand()
-	if receivedCmd == nil {
-		t.Fatal("No command received by daemon")
-	}
-
-	var payload protocol.StreamPayload
-	if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
-		t.Fatalf("Failed to unmarshal payload: %v", err)
-	}
+	_, text := daemon.getStreamSession(t)
 
 	// Verify text is accumulated correctly
-	if len(payload.Text) == 0 {
+	if len(text) == 0 {
 		t.Error("Expected non-empty text for large input")
 	}
+
+	if !strings.HasPrefix(text, "Line 0 with some text Line 1") || !strings.HasSuffix(text, "Line 999 with some text") {
+		t.Errorf("Unexpected accumulated text: %.60q...", text)
+	}
 }
 
 // TestStreamCommand_PipeIntegration tests piping from another command
@@ -473,18 +483,68 @@ func TestStreamCommand_PipeIntegration(t *testing.T) {
 
 	// Verify
 	time.Sleep(10 * time.Millisecond)
-	receivedCmd := daemon.getLastCommand()
-	if receivedCmd == nil {
-		t.Fatal("No command received by daemon")
+	_, text := daemon.getStreamSession(t)
+
+	expectedText := "Hello from pipe"
+	if text != expectedText {
+		t.Errorf("Expected text %q, got %q", expectedText, text)
+	}
+}
+
+func TestStreamCommand_ByteModeIntegration(t *testing.T) {
+	daemon := newMockDaemon(t)
+	defer daemon.close()
+
+	clientBin := getClientBinary(t)
+
+	input := "line one\nline two\n"
+
+	cmd := exec.Command(clientBin, "stream", "--socket", daemon.addr(), "--mode", "byte")
+	cmd.Stdin = bytes.NewBufferString(input)
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+
+	// Byte mode forwards stdin verbatim, newlines included
+	time.Sleep(10 * time.Millisecond)
+	_, text := daemon.getStreamSession(t)
+
+	if text != input {
+		t.Errorf("Expected text %q, got %q", input, text)
+	}
+}
+
+func TestStreamCommand_BatchModeIntegration(t *testing.T) {
+	daemon := newMockDaemon(t)
+	defer daemon.close()
+
+	clientBin := getClientBinary(t)
+
+	cmd := exec.Command(clientBin, "stream", "--socket", daemon.addr(), "--mode", "batch")
+	cmd.Stdin = bytes.NewBufferString("Hello\nworld\n")
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+
+	// Batch mode sends a single stream command with the joined text
+	time.Sleep(10 * time.Millisecond)
+	cmds := daemon.getCommands()
+	if len(cmds) != 1 {
+		t.Fatalf("Expected 1 command, got %d", len(cmds))
+	}
+
+	if cmds[0].Type != protocol.CommandType_Stream {
+		t.Errorf("Expected command type %v, got %v", protocol.CommandType_Stream, cmds[0].Type)
 	}
 
 	var payload protocol.StreamPayload
-	if err := json.Unmarshal(receivedCmd.Payload, &payload); err != nil {
+	if err := json.Unmarshal(cmds[0].Payload, &payload); err != nil {
 		t.Fatalf("Failed to unmarshal payload: %v", err)
 	}
 
-	expectedText := "Hello from pipe"
-	if payload.Text != expectedText {
-		t.Errorf("Expected text %q, got %q", expectedText, payload.Text)
+	if payload.Text != "Hello world" {
+		t.Errorf("Expected text %q, got %q", "Hello world", payload.Text)
 	}
 }